@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 )
@@ -41,7 +40,6 @@ func (api *API) createBaseRequest(ctx context.Context, method, apiUrl string, bo
 	if err != nil {
 		return nil, err
 	}
-	fmt.Println("api key", api.getSecret())
 	req.Header.Set("Authorization", "Bearer "+api.getSecret())
 	req.Header.Set("Cache-Control", "no-cache")
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
@@ -2,7 +2,11 @@ package dify
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+
+	"github.com/taadis/dify-sdk-go/client"
 )
 
 type ChatMessageRequest struct {
@@ -11,6 +15,10 @@ type ChatMessageRequest struct {
 	ResponseMode   string                 `json:"response_mode"`
 	ConversationID string                 `json:"conversation_id,omitempty"`
 	User           string                 `json:"user"`
+	// ParentMessageID answers the query again as a sibling branch of the messages
+	// following this one, e.g. to regenerate an answer. Empty to follow the last
+	// message of the conversation.
+	ParentMessageID string `json:"parent_message_id,omitempty"`
 }
 
 type ChatMessageResponse struct {
@@ -33,3 +41,30 @@ func (api *API) ChatMessages(ctx context.Context, req *ChatMessageRequest) (resp
 	err = api.c.sendJSONRequest(httpReq, &resp)
 	return
 }
+
+type ChatMessagesStopRequest struct {
+	TaskID string `json:"-"`
+	User   string `json:"user"`
+}
+
+type ChatMessagesStopResponse struct {
+	Result string `json:"result"`
+}
+
+/* Stop response
+ * Stop the generation of a streaming chat message, TaskID comes from the stream events.
+ */
+func (api *API) ChatMessagesStop(ctx context.Context, req *ChatMessagesStopRequest) (resp *ChatMessagesStopResponse, err error) {
+	if req.TaskID == "" {
+		err = errors.New("ChatMessagesStopRequest.TaskID Illegal")
+		return
+	}
+
+	url := fmt.Sprintf("/v1/chat-messages/%s/stop", req.TaskID)
+	httpReq, err := api.createBaseRequest(client.WithIdempotent(ctx), http.MethodPost, url, req)
+	if err != nil {
+		return
+	}
+	err = api.c.sendJSONRequest(httpReq, &resp)
+	return
+}
@@ -0,0 +1,230 @@
+package dify
+
+import (
+	"encoding/json"
+
+	"github.com/taadis/dify-sdk-go/client"
+)
+
+// Chat stream event types, see https://docs.dify.ai/en/openapi-api-access-readme
+const (
+	ChatEventMessage        = "message"
+	ChatEventAgentMessage   = "agent_message"
+	ChatEventAgentThought   = "agent_thought"
+	ChatEventMessageFile    = "message_file"
+	ChatEventMessageEnd     = "message_end"
+	ChatEventMessageReplace = "message_replace"
+	ChatEventTTSMessage     = EventTTSMessage
+	ChatEventTTSMessageEnd  = EventTTSMessageEnd
+	ChatEventError          = "error"
+)
+
+// Usage is the model usage of a message, see client.Usage.
+type Usage = client.Usage
+
+// RetrieverResource is a knowledge base citation, see client.RetrieverResource.
+type RetrieverResource = client.RetrieverResource
+
+// ChatStreamEvent is an event of a chat-messages stream. Its dynamic type is one of
+// *ChatMessageEvent, *ChatAgentMessageEvent, *ChatAgentThoughtEvent, *ChatMessageFileEvent,
+// *ChatMessageEndEvent, *ChatMessageReplaceEvent, *TTSMessage, *ChatErrorEvent
+// or *ChatUnknownEvent for events this SDK does not know yet.
+type ChatStreamEvent interface {
+	EventType() string
+}
+
+// ChatEventBase holds the fields shared by the chat stream events.
+type ChatEventBase struct {
+	Event          string `json:"event"`
+	TaskID         string `json:"task_id"`
+	MessageID      string `json:"message_id"`
+	ConversationID string `json:"conversation_id"`
+	CreatedAt      int64  `json:"created_at"`
+}
+
+func (e *ChatEventBase) EventType() string {
+	return e.Event
+}
+
+// ChatMessageEvent is a chunk of the answer.
+type ChatMessageEvent struct {
+	ChatEventBase
+	ID     string `json:"id"`
+	Answer string `json:"answer"`
+}
+
+// ChatAgentMessageEvent is a chunk of the answer of an agent app.
+type ChatAgentMessageEvent struct {
+	ChatEventBase
+	ID     string `json:"id"`
+	Answer string `json:"answer"`
+}
+
+// ChatAgentThoughtEvent is a reasoning step of an agent, it is sent again as the step progresses.
+type ChatAgentThoughtEvent struct {
+	ChatEventBase
+	ID           string   `json:"id"`
+	Position     int      `json:"position"`
+	Thought      string   `json:"thought"`
+	Observation  string   `json:"observation"`
+	Tool         string   `json:"tool"`
+	ToolInput    string   `json:"tool_input"`
+	MessageFiles []string `json:"message_files"`
+}
+
+// ChatMessageFileEvent is a file produced by the assistant, e.g. a generated image.
+type ChatMessageFileEvent struct {
+	ChatEventBase
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	BelongsTo string `json:"belongs_to"`
+	URL       string `json:"url"`
+}
+
+// ChatMessageMetadata is the metadata of a finished message.
+type ChatMessageMetadata struct {
+	Usage              Usage               `json:"usage"`
+	RetrieverResources []RetrieverResource `json:"retriever_resources"`
+}
+
+// ChatMessageEndEvent ends the message stream.
+type ChatMessageEndEvent struct {
+	ChatEventBase
+	ID       string              `json:"id"`
+	Metadata ChatMessageMetadata `json:"metadata"`
+}
+
+// ChatMessageReplaceEvent replaces the whole answer, e.g. when content moderation kicks in.
+type ChatMessageReplaceEvent struct {
+	ChatEventBase
+	Answer string `json:"answer"`
+}
+
+// ChatErrorEvent reports an error that ended the stream.
+type ChatErrorEvent struct {
+	ChatEventBase
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ChatUnknownEvent is an event type this SDK does not decode, Raw holds its JSON.
+type ChatUnknownEvent struct {
+	ChatEventBase
+	Raw json.RawMessage `json:"-"`
+}
+
+// EventType implements ChatStreamEvent.
+func (m *TTSMessage) EventType() string {
+	return m.Event
+}
+
+// decodeChatStreamEvent decodes the JSON data of a chat stream event.
+func decodeChatStreamEvent(data []byte) (ChatStreamEvent, error) {
+	var base ChatEventBase
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, err
+	}
+
+	var e ChatStreamEvent
+	switch base.Event {
+	case ChatEventMessage:
+		e = new(ChatMessageEvent)
+	case ChatEventAgentMessage:
+		e = new(ChatAgentMessageEvent)
+	case ChatEventAgentThought:
+		e = new(ChatAgentThoughtEvent)
+	case ChatEventMessageFile:
+		e = new(ChatMessageFileEvent)
+	case ChatEventMessageEnd:
+		e = new(ChatMessageEndEvent)
+	case ChatEventMessageReplace:
+		e = new(ChatMessageReplaceEvent)
+	case ChatEventTTSMessage, ChatEventTTSMessageEnd:
+		e = new(TTSMessage)
+	case ChatEventError:
+		e = new(ChatErrorEvent)
+	default:
+		return &ChatUnknownEvent{ChatEventBase: base, Raw: append(json.RawMessage(nil), data...)}, nil
+	}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ChatStreamSummary accumulates the events of a chat stream into the final message.
+type ChatStreamSummary struct {
+	TaskID         string
+	MessageID      string
+	ConversationID string
+	// Answer is the full answer, message_replace events included.
+	Answer string
+	// Files produced by the assistant.
+	Files []*ChatMessageFileEvent
+	// AgentThoughts holds the latest state of each agent step, in order.
+	AgentThoughts      []*ChatAgentThoughtEvent
+	Usage              Usage
+	RetrieverResources []RetrieverResource
+	// Done reports whether message_end was received.
+	Done bool
+}
+
+// Add updates the summary with e.
+func (s *ChatStreamSummary) Add(e ChatStreamEvent) {
+	var (
+		base *ChatEventBase
+		// older Dify versions only send the message ID as "id"
+		id string
+	)
+	switch e := e.(type) {
+	case *ChatMessageEvent:
+		base, id = &e.ChatEventBase, e.ID
+		s.Answer += e.Answer
+	case *ChatAgentMessageEvent:
+		base, id = &e.ChatEventBase, e.ID
+		s.Answer += e.Answer
+	case *ChatMessageReplaceEvent:
+		base = &e.ChatEventBase
+		s.Answer = e.Answer
+	case *ChatAgentThoughtEvent:
+		base = &e.ChatEventBase
+		found := false
+		for i, t := range s.AgentThoughts {
+			if t.ID == e.ID {
+				s.AgentThoughts[i] = e
+				found = true
+				break
+			}
+		}
+		if !found {
+			s.AgentThoughts = append(s.AgentThoughts, e)
+		}
+	case *ChatMessageFileEvent:
+		base = &e.ChatEventBase
+		s.Files = append(s.Files, e)
+	case *ChatMessageEndEvent:
+		base, id = &e.ChatEventBase, e.ID
+		s.Usage = e.Metadata.Usage
+		s.RetrieverResources = e.Metadata.RetrieverResources
+		s.Done = true
+	case *ChatErrorEvent:
+		base = &e.ChatEventBase
+	case *ChatUnknownEvent:
+		base = &e.ChatEventBase
+	}
+	if base == nil {
+		return
+	}
+	if base.TaskID != "" {
+		s.TaskID = base.TaskID
+	}
+	if base.MessageID != "" {
+		s.MessageID = base.MessageID
+	} else if id != "" {
+		s.MessageID = id
+	}
+	if base.ConversationID != "" {
+		s.ConversationID = base.ConversationID
+	}
+}
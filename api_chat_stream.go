@@ -1,19 +1,19 @@
 package dify
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
+
+	"github.com/taadis/dify-sdk-go/client"
 )
 
 type ChatMessageStreamResponse struct {
 	Event          string `json:"event"`
 	TaskID         string `json:"task_id"`
 	ID             string `json:"id"`
+	MessageID      string `json:"message_id"`
 	Answer         string `json:"answer"`
 	CreatedAt      int64  `json:"created_at"`
 	ConversationID string `json:"conversation_id"`
@@ -21,68 +21,191 @@ type ChatMessageStreamResponse struct {
 
 type ChatMessageStreamChannelResponse struct {
 	ChatMessageStreamResponse
-	Err error `json:"-"`
+	// Payload is the typed event, see ChatStreamEvent.
+	Payload ChatStreamEvent `json:"-"`
+	// Summary is set on the message_end response, the last one of a successful stream.
+	Summary *ChatStreamSummary `json:"-"`
+	Err     error              `json:"-"`
 }
 
+// ChatMessagesStreamRaw sends a streaming chat message and returns the raw event stream
+// response. A non-200 response is returned as an *APIError.
 func (api *API) ChatMessagesStreamRaw(ctx context.Context, req *ChatMessageRequest) (*http.Response, error) {
+	httpReq, err := api.newChatMessagesStreamRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := api.c.sendRequest(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		defer httpResp.Body.Close()
+		return nil, client.NewAPIError(httpResp)
+	}
+	return httpResp, nil
+}
+
+func (api *API) newChatMessagesStreamRequest(ctx context.Context, req *ChatMessageRequest) (*http.Request, error) {
 	req.ResponseMode = "streaming"
+	return api.createBaseRequest(ctx, http.MethodPost, "/v1/chat-messages", req)
+}
 
-	httpReq, err := api.createBaseRequest(ctx, http.MethodPost, "/v1/chat-messages", req)
+// ChatStream iterates over the events of a streaming chat message:
+//
+//	stream, err := api.StreamChatMessages(ctx, req)
+//	if err != nil {
+//		return err
+//	}
+//	defer stream.Close()
+//	for stream.Next() {
+//		switch e := stream.Current().(type) {
+//		case *dify.ChatMessageEvent:
+//			fmt.Print(e.Answer)
+//		}
+//	}
+//	if err := stream.Err(); err != nil {
+//		return err
+//	}
+//	summary := stream.Summary()
+//
+// The stream ends after message_end, an error event or the end of the response.
+// The response body is closed at the end of the stream, by Close, or when ctx is done.
+type ChatStream struct {
+	stream  *client.EventStream
+	current ChatStreamEvent
+	summary ChatStreamSummary
+	err     error
+	done    bool
+}
+
+// StreamChatMessages sends a streaming chat message. Error responses are returned as an *APIError.
+func (api *API) StreamChatMessages(ctx context.Context, req *ChatMessageRequest) (*ChatStream, error) {
+	httpReq, err := api.newChatMessagesStreamRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := api.c.sendStoppableStreamRequest(httpReq, func(ctx context.Context, taskId string) error {
+		_, err := api.ChatMessagesStop(ctx, &ChatMessagesStopRequest{TaskID: taskId, User: req.User})
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	return api.c.sendRequest(httpReq)
+	return &ChatStream{stream: stream}, nil
+}
+
+// Next advances to the next event, it returns false when the stream is over.
+func (s *ChatStream) Next() bool {
+	if s.done {
+		return false
+	}
+	if !s.stream.Next() {
+		s.done = true
+		return false
+	}
+
+	e, err := decodeChatStreamEvent(s.stream.Data())
+	if err != nil {
+		s.err = fmt.Errorf("error unmarshalling %s event: %w", s.stream.Event(), err)
+		s.Close()
+		return false
+	}
+	s.current = e
+	s.summary.Add(e)
+	switch e.(type) {
+	case *ChatErrorEvent:
+		s.err = client.StreamEventError(s.stream.Data())
+		s.end()
+		return false
+	case *ChatMessageEndEvent:
+		// message_end is the last event, release the connection right away
+		s.end()
+	}
+	return true
 }
 
+// Current returns the current event.
+func (s *ChatStream) Current() ChatStreamEvent {
+	return s.current
+}
+
+// Err returns the error that ended the stream: an *APIError for error events,
+// a read, decoding or context error otherwise. It is nil for a complete stream.
+func (s *ChatStream) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.stream.Err()
+}
+
+// Summary returns the message accumulated from the events read so far.
+func (s *ChatStream) Summary() *ChatStreamSummary {
+	return &s.summary
+}
+
+// Close ends the stream and releases the connection.
+func (s *ChatStream) Close() error {
+	s.done = true
+	return s.stream.Close()
+}
+
+// end closes the stream after its last event.
+func (s *ChatStream) end() error {
+	s.done = true
+	return s.stream.End()
+}
+
+// ChatMessagesStream sends a streaming chat message. The channel receives every event
+// until message_end, an error event or the end of the stream, then it is closed.
+// The message_end response carries the Summary of the message; an error event is
+// reported in Err as an *APIError.
+//
+// The channel is fed by a goroutine that exits when the stream ends or ctx is done,
+// callers that stop reading early must cancel ctx. StreamChatMessages has no such caveat.
 func (api *API) ChatMessagesStream(ctx context.Context, req *ChatMessageRequest) (chan ChatMessageStreamChannelResponse, error) {
-	httpResp, err := api.ChatMessagesStreamRaw(ctx, req)
+	stream, err := api.StreamChatMessages(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
 	streamChannel := make(chan ChatMessageStreamChannelResponse)
-	go api.chatMessagesStreamHandle(ctx, httpResp, streamChannel)
+	go chatMessagesStreamHandle(ctx, stream, streamChannel)
 	return streamChannel, nil
 }
 
-func (api *API) chatMessagesStreamHandle(ctx context.Context, resp *http.Response, streamChannel chan ChatMessageStreamChannelResponse) {
-	defer resp.Body.Close()
+func chatMessagesStreamHandle(ctx context.Context, stream *ChatStream, streamChannel chan ChatMessageStreamChannelResponse) {
 	defer close(streamChannel)
+	defer stream.Close()
 
-	reader := bufio.NewReader(resp.Body)
-	for {
+	send := func(resp ChatMessageStreamChannelResponse) bool {
 		select {
+		case streamChannel <- resp:
+			return true
 		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for stream.Next() {
+		var resp ChatMessageStreamChannelResponse
+		// the event was decoded once already, the flat fields cannot fail
+		json.Unmarshal(stream.stream.Data(), &resp.ChatMessageStreamResponse)
+		resp.Payload = stream.Current()
+		if _, ok := resp.Payload.(*ChatMessageEndEvent); ok {
+			resp.Summary = stream.Summary()
+		}
+		if !send(resp) {
 			return
-		default:
-			line, err := reader.ReadBytes('\n')
-			if err != nil {
-				streamChannel <- ChatMessageStreamChannelResponse{
-					Err: fmt.Errorf("error reading line: %w", err),
-				}
-				return
-			}
-
-			if !bytes.HasPrefix(line, []byte("data:")) {
-				continue
-			}
-			line = bytes.TrimPrefix(line, []byte("data:"))
-
-			var resp ChatMessageStreamChannelResponse
-			if err = json.Unmarshal(line, &resp); err != nil {
-				streamChannel <- ChatMessageStreamChannelResponse{
-					Err: fmt.Errorf("error unmarshalling event: %w", err),
-				}
-				return
-			} else if resp.Event == "error" {
-				streamChannel <- ChatMessageStreamChannelResponse{
-					Err: errors.New("error streaming event: " + string(line)),
-				}
-				return
-			} else if resp.Answer == "" {
-				return
-			}
-			streamChannel <- resp
 		}
 	}
+
+	if err := stream.Err(); err != nil && ctx.Err() == nil {
+		resp := ChatMessageStreamChannelResponse{Err: err}
+		if e, ok := stream.Current().(*ChatErrorEvent); ok {
+			json.Unmarshal(stream.stream.Data(), &resp.ChatMessageStreamResponse)
+			resp.Payload = e
+		}
+		send(resp)
+	}
 }
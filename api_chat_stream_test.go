@@ -0,0 +1,313 @@
+package dify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taadis/dify-sdk-go/client"
+	"github.com/taadis/dify-sdk-go/difytest"
+)
+
+func chatEvent(event string, fields map[string]interface{}) map[string]interface{} {
+	e := map[string]interface{}{
+		"event":           event,
+		"task_id":         "task-1",
+		"message_id":      "msg-1",
+		"conversation_id": "conv-1",
+		"created_at":      1,
+	}
+	for k, v := range fields {
+		e[k] = v
+	}
+	return e
+}
+
+func streamChat(t *testing.T, events ...interface{}) []ChatMessageStreamChannelResponse {
+	t.Helper()
+	srv := difytest.NewServer()
+	defer srv.Close()
+	srv.Handle(http.MethodPost, "/chat-messages", difytest.Stream(events...))
+
+	ch, err := NewClient(srv.URL, apiSecretKey).API().ChatMessagesStream(context.Background(), &ChatMessageRequest{
+		Query: "hello",
+		User:  "test-user",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ret []ChatMessageStreamChannelResponse
+	for r := range ch {
+		ret = append(ret, r)
+	}
+	return ret
+}
+
+func TestChatMessagesStreamEvents(t *testing.T) {
+	rs := streamChat(t,
+		"event: ping\n\n",
+		chatEvent(ChatEventAgentThought, map[string]interface{}{"id": "th-1", "position": 1, "tool": "search"}),
+		chatEvent(ChatEventAgentMessage, map[string]interface{}{"id": "msg-1", "answer": "Hel"}),
+		chatEvent(ChatEventAgentThought, map[string]interface{}{"id": "th-1", "position": 1, "tool": "search", "observation": "found"}),
+		chatEvent(ChatEventMessage, map[string]interface{}{"id": "msg-1", "answer": ""}),
+		chatEvent(ChatEventMessage, map[string]interface{}{"id": "msg-1", "answer": "lo"}),
+		chatEvent(ChatEventMessageFile, map[string]interface{}{"id": "file-1", "type": "image", "belongs_to": "assistant", "url": "https://localhost/1.png"}),
+		chatEvent(ChatEventMessageReplace, map[string]interface{}{"answer": "Hello!"}),
+		chatEvent(ChatEventTTSMessage, map[string]interface{}{"audio": "SUQz"}),
+		chatEvent("workflow_started", nil),
+		chatEvent(ChatEventMessageEnd, map[string]interface{}{"id": "msg-1", "metadata": map[string]interface{}{
+			"usage":               map[string]interface{}{"prompt_tokens": 3, "completion_tokens": 2, "total_tokens": 5, "total_price": "0.0001", "currency": "USD"},
+			"retriever_resources": []interface{}{map[string]interface{}{"position": 1, "document_name": "faq.md", "score": 0.9}},
+		}}),
+		chatEvent(ChatEventMessage, map[string]interface{}{"id": "msg-1", "answer": "after end"}),
+	)
+
+	var types []string
+	for _, r := range rs {
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+		types = append(types, r.Payload.EventType())
+	}
+	want := []string{ChatEventAgentThought, ChatEventAgentMessage, ChatEventAgentThought, ChatEventMessage, ChatEventMessage,
+		ChatEventMessageFile, ChatEventMessageReplace, ChatEventTTSMessage, "workflow_started", ChatEventMessageEnd}
+	if len(types) != len(want) {
+		t.Fatalf("events = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("events = %v, want %v", types, want)
+		}
+	}
+	if _, ok := rs[8].Payload.(*ChatUnknownEvent); !ok {
+		t.Errorf("unknown event decoded as %T", rs[8].Payload)
+	}
+	if tts, ok := rs[7].Payload.(*TTSMessage); !ok || tts.Audio != "SUQz" {
+		t.Errorf("unexpected tts event %#v", rs[7].Payload)
+	}
+
+	last := rs[len(rs)-1]
+	end, ok := last.Payload.(*ChatMessageEndEvent)
+	if !ok || end.Metadata.Usage.TotalTokens != 5 {
+		t.Fatalf("unexpected message_end %#v", last.Payload)
+	}
+	s := last.Summary
+	if s == nil || !s.Done {
+		t.Fatalf("missing summary: %#v", s)
+	}
+	if s.Answer != "Hello!" || s.MessageID != "msg-1" || s.ConversationID != "conv-1" || s.TaskID != "task-1" {
+		t.Errorf("unexpected summary %+v", s)
+	}
+	if s.Usage.TotalTokens != 5 || len(s.RetrieverResources) != 1 || s.RetrieverResources[0].DocumentName != "faq.md" {
+		t.Errorf("unexpected usage or citations %+v", s)
+	}
+	if len(s.Files) != 1 || s.Files[0].URL != "https://localhost/1.png" {
+		t.Errorf("unexpected files %+v", s.Files)
+	}
+	if len(s.AgentThoughts) != 1 || s.AgentThoughts[0].Observation != "found" {
+		t.Errorf("unexpected agent thoughts %+v", s.AgentThoughts)
+	}
+}
+
+func TestChatMessagesStreamErrorEvent(t *testing.T) {
+	rs := streamChat(t,
+		chatEvent(ChatEventMessage, map[string]interface{}{"answer": "Hel"}),
+		chatEvent(ChatEventError, map[string]interface{}{"status": 400, "code": "provider_quota_exceeded", "message": "quota exceeded"}),
+		chatEvent(ChatEventMessage, map[string]interface{}{"answer": "lo"}),
+	)
+	if len(rs) != 2 {
+		t.Fatalf("responses = %d, want 2", len(rs))
+	}
+	if !IsQuotaExceeded(rs[1].Err) {
+		t.Errorf("expected a quota exceeded error, got %v", rs[1].Err)
+	}
+	if e, ok := AsAPIError(rs[1].Err); !ok || !strings.Contains(string(e.Body), "quota exceeded") {
+		t.Errorf("the error does not keep the event data: %v", rs[1].Err)
+	}
+	if rs[1].Summary != nil {
+		t.Error("summary set on a failed stream")
+	}
+}
+
+func TestStreamChatMessages(t *testing.T) {
+	api := NewClient(host, apiSecretKey).API()
+	stream, err := api.StreamChatMessages(context.Background(), &ChatMessageRequest{
+		Query: "hello stream",
+		User:  "test-user-iterator",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var answer string
+	for stream.Next() {
+		if e, ok := stream.Current().(*ChatMessageEvent); ok {
+			answer += e.Answer
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if answer != "echo: hello stream" {
+		t.Errorf("answer = %q", answer)
+	}
+	if s := stream.Summary(); !s.Done || s.Answer != answer || s.Usage.TotalTokens == 0 {
+		t.Errorf("unexpected summary %+v", s)
+	}
+}
+
+func TestStreamChatMessagesHTTPError(t *testing.T) {
+	api := NewClient(host, apiSecretKey).API()
+	_, err := api.StreamChatMessages(context.Background(), &ChatMessageRequest{
+		Query:          "hello",
+		User:           "test-user",
+		ConversationID: "unknown",
+	})
+	if !IsNotFound(err) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+
+	_, err = api.ChatMessagesStream(context.Background(), &ChatMessageRequest{
+		Query:          "hello",
+		User:           "test-user",
+		ConversationID: "unknown",
+	})
+	if !IsNotFound(err) {
+		t.Fatalf("expected a not found error from the channel API, got %v", err)
+	}
+}
+
+func TestChatMessagesStreamAbandoned(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithStreamDelay(10 * time.Millisecond))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := NewClient(srv.URL, apiSecretKey).API().ChatMessagesStream(ctx, &ChatMessageRequest{
+		Query: "a long answer that the caller stops reading",
+		User:  "test-user",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ch
+	cancel()
+
+	// the producer must give up instead of blocking on the unread channel
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("a response was sent after the context was canceled")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the channel was not closed after the context was canceled")
+	}
+}
+
+func TestChatMessagesStreamAutoStop(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithStreamDelay(10 * time.Millisecond))
+	defer srv.Close()
+
+	results := make(chan StopResult, 1)
+	c := NewClientWithConfig(&ClientConfig{Host: srv.URL, DefaultAPISecret: apiSecretKey, AutoStop: &AutoStop{
+		OnStop: func(r StopResult) { results <- r },
+	}})
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := c.API().ChatMessagesStream(ctx, &ChatMessageRequest{
+		Query: "a long answer that the caller cancels",
+		User:  "test-user",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := <-ch
+	cancel()
+
+	select {
+	case r := <-results:
+		if r.Err != nil || r.TaskId != first.TaskID {
+			t.Errorf("unexpected stop result %+v, want task %s", r, first.TaskID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the task was not stopped")
+	}
+	if stopped := srv.StoppedTasks(); len(stopped) != 1 || stopped[0] != first.TaskID {
+		t.Errorf("stopped tasks = %v", stopped)
+	}
+	if r := srv.AssertRequest(t, http.MethodPost, "/chat-messages/{task_id}/stop"); r.Map()["user"] != "test-user" {
+		t.Errorf("stop request body = %s", r.Body)
+	}
+}
+
+func TestStreamChatMessagesAutoStopDeferredClose(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithStreamDelay(10 * time.Millisecond))
+	defer srv.Close()
+
+	results := make(chan StopResult, 1)
+	c := NewClientWithConfig(&ClientConfig{Host: srv.URL, DefaultAPISecret: apiSecretKey, AutoStop: &AutoStop{
+		OnStop: func(r StopResult) { results <- r },
+	}})
+	ctx, cancel := context.WithCancel(context.Background())
+	var taskId string
+	func() {
+		stream, err := c.API().StreamChatMessages(ctx, &ChatMessageRequest{
+			Query: "a long answer that the caller cancels",
+			User:  "test-user",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stream.Close()
+		if !stream.Next() {
+			t.Fatal(stream.Err())
+		}
+		taskId = stream.stream.TaskId()
+		cancel()
+	}()
+
+	select {
+	case r := <-results:
+		if r.Err != nil || r.TaskId != taskId {
+			t.Errorf("unexpected stop result %+v, want task %s", r, taskId)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the task was not stopped")
+	}
+	srv.AssertRequest(t, http.MethodPost, "/chat-messages/{task_id}/stop")
+}
+
+func TestStreamChatMessagesBudget(t *testing.T) {
+	budget := &Budget{PerApp: BudgetLimit{Tokens: 1}}
+	c := NewClientWithConfig(&ClientConfig{Host: host, DefaultAPISecret: apiSecretKey, Budget: budget})
+	ctx := context.Background()
+	req := &ChatMessageRequest{Query: "how much does it cost", User: "test-user-budget"}
+
+	// the usage of message_end is counted, the message is complete anyway
+	stream, err := c.API().StreamChatMessages(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for stream.Next() {
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	total := budget.UserTotal("test-user-budget")
+	if total.Tokens != int64(stream.Summary().Usage.TotalTokens) || total.Price != client.Decimal(strings.TrimRight(stream.Summary().Usage.TotalPrice, "0")) {
+		t.Errorf("user total = %+v, usage = %+v", total, stream.Summary().Usage)
+	}
+
+	n := len(testServer.RequestsTo(http.MethodPost, "/chat-messages"))
+	_, err = c.API().StreamChatMessages(ctx, req)
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) || budgetErr.Scope != client.BudgetScopeApp {
+		t.Errorf("err = %v, want an app budget error", err)
+	}
+	if len(testServer.RequestsTo(http.MethodPost, "/chat-messages")) != n {
+		t.Error("the refused message was sent")
+	}
+}
@@ -0,0 +1,137 @@
+package dify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/taadis/dify-sdk-go/chatflow"
+)
+
+// ConversationVariable is a variable holding the state of a conversation, its Value is
+// decoded according to its ValueType, see chatflow.ConversationVariable.
+type ConversationVariable = chatflow.ConversationVariable
+
+// VariableTypeError reports a value that does not match the type of its variable.
+type VariableTypeError = chatflow.VariableTypeError
+
+// Value types of a ConversationVariable.
+const (
+	VariableTypeString      = chatflow.VariableTypeString
+	VariableTypeNumber      = chatflow.VariableTypeNumber
+	VariableTypeObject      = chatflow.VariableTypeObject
+	VariableTypeSecret      = chatflow.VariableTypeSecret
+	VariableTypeArrayString = chatflow.VariableTypeArrayString
+	VariableTypeArrayNumber = chatflow.VariableTypeArrayNumber
+	VariableTypeArrayObject = chatflow.VariableTypeArrayObject
+)
+
+type ConversationVariablesRequest struct {
+	ConversationID string `json:"conversation_id"`
+	User           string `json:"user"`
+	LastID         string `json:"last_id,omitempty"`
+	Limit          int    `json:"limit"`
+	// Only return the variable with this name.
+	VariableName string `json:"variable_name,omitempty"`
+}
+
+type ConversationVariablesResponse struct {
+	Limit   int                    `json:"limit"`
+	HasMore bool                   `json:"has_more"`
+	Data    []ConversationVariable `json:"data"`
+}
+
+type ConversationVariableUpdateRequest struct {
+	ConversationID string `json:"-"`
+	VariableID     string `json:"-"`
+	// Declared type of the variable, the value is checked against it before sending.
+	// When empty, it is read from the variables of the conversation first.
+	ValueType string      `json:"-"`
+	Value     interface{} `json:"value"`
+	User      string      `json:"user"`
+}
+
+/* Get conversation variables
+ * Gets the variables of a conversation, page by page, optionally filtered by name.
+ */
+func (api *API) ConversationVariables(ctx context.Context, req *ConversationVariablesRequest) (resp *ConversationVariablesResponse, err error) {
+	if req.ConversationID == "" {
+		err = errors.New("ConversationVariablesRequest.ConversationID Illegal")
+		return
+	}
+
+	httpReq, err := api.createBaseRequest(ctx, http.MethodGet, fmt.Sprintf("/v1/conversations/%s/variables", req.ConversationID), nil)
+	if err != nil {
+		return
+	}
+
+	query := httpReq.URL.Query()
+	query.Set("user", req.User)
+	if req.LastID != "" {
+		query.Set("last_id", req.LastID)
+	}
+	if req.Limit > 0 {
+		query.Set("limit", strconv.FormatInt(int64(req.Limit), 10))
+	}
+	if req.VariableName != "" {
+		query.Set("variable_name", req.VariableName)
+	}
+	httpReq.URL.RawQuery = query.Encode()
+
+	err = api.c.sendJSONRequest(httpReq, &resp)
+	return
+}
+
+/* Update conversation variable
+ * Sets the value of a conversation variable. A value not matching the type of the
+ * variable is reported as a *VariableTypeError without sending the update.
+ */
+func (api *API) UpdateConversationVariable(ctx context.Context, req *ConversationVariableUpdateRequest) (resp *ConversationVariable, err error) {
+	if req.ConversationID == "" || req.VariableID == "" {
+		err = errors.New("ConversationVariableUpdateRequest.ConversationID or VariableID Illegal")
+		return
+	}
+	valueType := req.ValueType
+	if valueType == "" {
+		var v *ConversationVariable
+		if v, err = api.findConversationVariable(ctx, req); err != nil {
+			return
+		}
+		valueType = v.ValueType
+	}
+	if err = chatflow.CheckVariableValue(valueType, req.Value); err != nil {
+		if e, ok := err.(*VariableTypeError); ok {
+			e.Variable = req.VariableID
+		}
+		return
+	}
+
+	url := fmt.Sprintf("/v1/conversations/%s/variables/%s", req.ConversationID, req.VariableID)
+	httpReq, err := api.createBaseRequest(ctx, http.MethodPut, url, req)
+	if err != nil {
+		return
+	}
+	err = api.c.sendJSONRequest(httpReq, &resp)
+	return
+}
+
+func (api *API) findConversationVariable(ctx context.Context, req *ConversationVariableUpdateRequest) (*ConversationVariable, error) {
+	list := &ConversationVariablesRequest{ConversationID: req.ConversationID, User: req.User, Limit: 100}
+	for {
+		resp, err := api.ConversationVariables(ctx, list)
+		if err != nil {
+			return nil, err
+		}
+		for i := range resp.Data {
+			if resp.Data[i].Id == req.VariableID {
+				return &resp.Data[i], nil
+			}
+		}
+		if !resp.HasMore || len(resp.Data) == 0 {
+			return nil, fmt.Errorf("conversation variable %s not found", req.VariableID)
+		}
+		list.LastID = resp.Data[len(resp.Data)-1].Id
+	}
+}
@@ -0,0 +1,65 @@
+package dify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/taadis/dify-sdk-go/difytest"
+)
+
+func TestConversationVariables(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(apiSecretKey), difytest.WithConversationVariables(
+		difytest.ConversationVariable{Name: "cart", ValueType: VariableTypeArrayObject, Value: []interface{}{}},
+		difytest.ConversationVariable{Name: "visits", ValueType: VariableTypeNumber, Value: 0},
+	))
+	defer srv.Close()
+	client := NewClient(srv.URL, apiSecretKey)
+	ctx := context.Background()
+
+	id := srv.AddConversation("test-user-variables", "variables")
+	res, err := client.API().ConversationVariables(ctx, &ConversationVariablesRequest{
+		ConversationID: id,
+		User:           "test-user-variables",
+		VariableName:   "cart",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Data) != 1 || res.Data[0].Name != "cart" || res.Data[0].ValueType != VariableTypeArrayObject {
+		t.Fatalf("unexpected variables: %+v", res)
+	}
+	cart := res.Data[0]
+
+	type item struct {
+		Sku string `json:"sku"`
+		Qty int    `json:"qty"`
+	}
+	v, err := client.API().UpdateConversationVariable(ctx, &ConversationVariableUpdateRequest{
+		ConversationID: id,
+		VariableID:     cart.Id,
+		Value:          []item{{Sku: "apple", Qty: 2}},
+		User:           "test-user-variables",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var items []item
+	if err := v.Decode(&items); err != nil || len(items) != 1 || items[0].Qty != 2 {
+		t.Errorf("items = %+v, err = %v", items, err)
+	}
+	srv.AssertRequest(t, http.MethodPut, "/conversations/"+id+"/variables/"+cart.Id)
+
+	_, err = client.API().UpdateConversationVariable(ctx, &ConversationVariableUpdateRequest{
+		ConversationID: id,
+		VariableID:     cart.Id,
+		ValueType:      cart.ValueType,
+		Value:          []string{"apple"},
+		User:           "test-user-variables",
+	})
+	var typeErr *VariableTypeError
+	if !errors.As(err, &typeErr) || typeErr.Value != "array of string" {
+		t.Errorf("err = %v, want a *VariableTypeError", err)
+	}
+}
@@ -49,13 +49,16 @@ type MessagesResponse struct {
 }
 
 type MessagesDataResponse struct {
-	ID             string                 `json:"id"`
-	ConversationID string                 `json:"conversation_id"`
-	Inputs         map[string]interface{} `json:"inputs"`
-	Query          string                 `json:"query"`
-	Answer         string                 `json:"answer"`
-	Feedback       interface{}            `json:"feedback"`
-	CreatedAt      int64                  `json:"created_at"`
+	ID             string `json:"id"`
+	ConversationID string `json:"conversation_id"`
+	// ParentMessageID links the messages of a conversation into a tree, a regenerated
+	// answer being a sibling of the original one. Empty for the first message.
+	ParentMessageID string                 `json:"parent_message_id"`
+	Inputs          map[string]interface{} `json:"inputs"`
+	Query           string                 `json:"query"`
+	Answer          string                 `json:"answer"`
+	Feedback        interface{}            `json:"feedback"`
+	CreatedAt       int64                  `json:"created_at"`
 }
 
 /* Get the chat history message
@@ -81,6 +84,38 @@ func (api *API) Messages(ctx context.Context, req *MessagesRequest) (resp *Messa
 	return
 }
 
+type MessagesSuggestedRequest struct {
+	MessageID string `json:"message_id"`
+	User      string `json:"user"`
+}
+
+type MessagesSuggestedResponse struct {
+	Result string   `json:"result"`
+	Data   []string `json:"data"`
+}
+
+/* Next suggested questions
+ * Gets the questions suggested after the answer of a message, when the app enables them,
+ * see ParametersResponse.SuggestedQuestionsAfterAnswer.
+ */
+func (api *API) MessagesSuggested(ctx context.Context, req *MessagesSuggestedRequest) (resp *MessagesSuggestedResponse, err error) {
+	if req.MessageID == "" {
+		err = errors.New("MessagesSuggestedRequest.MessageID Illegal")
+		return
+	}
+
+	httpReq, err := api.createBaseRequest(ctx, http.MethodGet, fmt.Sprintf("/v1/messages/%s/suggested", req.MessageID), nil)
+	if err != nil {
+		return
+	}
+	query := httpReq.URL.Query()
+	query.Set("user", req.User)
+	httpReq.URL.RawQuery = query.Encode()
+
+	err = api.c.sendJSONRequest(httpReq, &resp)
+	return
+}
+
 /* Message terminal user feedback, like
  * Rate received messages on behalf of end-users with likes or dislikes.
  * This data is visible in the Logs & Annotations page and used for future model fine-tuning.
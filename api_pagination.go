@@ -0,0 +1,170 @@
+package dify
+
+import (
+	"context"
+)
+
+// ConversationIterator walks the conversations of a user, most recent first, see
+// API.IterateConversations. Pages are fetched lazily, the next one when the current
+// one is consumed.
+type ConversationIterator struct {
+	ctx     context.Context
+	api     *API
+	req     ConversationsRequest
+	page    []ConversationsDataResponse
+	current *ConversationsDataResponse
+	last    bool
+	err     error
+}
+
+// IterateConversations returns an iterator over the conversations of req.User, starting
+// after req.LastID with req.Limit conversations per page (20 by default):
+//
+//	it := api.IterateConversations(ctx, &dify.ConversationsRequest{User: "user", Limit: 100})
+//	for it.Next() {
+//		log.Println(it.Current().Name)
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+func (api *API) IterateConversations(ctx context.Context, req *ConversationsRequest) *ConversationIterator {
+	return &ConversationIterator{ctx: ctx, api: api, req: *req}
+}
+
+// Next advances to the next conversation, it returns false after the last one or on error.
+func (it *ConversationIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.last || it.err != nil {
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		req := it.req
+		resp, err := it.api.Conversations(it.ctx, &req)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page = resp.Data
+		it.last = !resp.HasMore || len(resp.Data) == 0
+		if len(resp.Data) > 0 {
+			it.req.LastID = resp.Data[len(resp.Data)-1].ID
+		}
+	}
+	it.current, it.page = &it.page[0], it.page[1:]
+	return true
+}
+
+// Current returns the current conversation.
+func (it *ConversationIterator) Current() *ConversationsDataResponse {
+	return it.current
+}
+
+// Err returns the error that ended the iteration, nil when all the conversations were read.
+func (it *ConversationIterator) Err() error {
+	return it.err
+}
+
+// MessageOrder is the order in which a MessageIterator returns the history.
+type MessageOrder int
+
+const (
+	// MessagesNewestFirst walks the history from the latest message, one page at a time.
+	MessagesNewestFirst MessageOrder = iota
+	// MessagesChronological walks the history from the first message. The API pages
+	// from the latest message, so the whole history is read by the first call to Next.
+	MessagesChronological
+)
+
+// MessageIterator walks the messages of a conversation, see API.IterateMessages.
+type MessageIterator struct {
+	ctx     context.Context
+	api     *API
+	req     MessagesRequest
+	order   MessageOrder
+	page    []MessagesDataResponse
+	current *MessagesDataResponse
+	last    bool
+	err     error
+}
+
+// IterateMessages returns an iterator over the messages of req.ConversationID, starting
+// before req.FirstID with req.Limit messages per page (20 by default):
+//
+//	it := api.IterateMessages(ctx, &dify.MessagesRequest{ConversationID: id, User: "user", Limit: 100}, dify.MessagesChronological)
+//	for it.Next() {
+//		log.Println(it.Current().Query, it.Current().Answer)
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+func (api *API) IterateMessages(ctx context.Context, req *MessagesRequest, order MessageOrder) *MessageIterator {
+	return &MessageIterator{ctx: ctx, api: api, req: *req, order: order}
+}
+
+// Next advances to the next message, it returns false after the last one or on error.
+func (it *MessageIterator) Next() bool {
+	if it.order == MessagesChronological && !it.last && it.err == nil {
+		it.readAll()
+	}
+	for len(it.page) == 0 {
+		if it.last || it.err != nil {
+			return false
+		}
+		page, ok := it.fetch()
+		if !ok {
+			return false
+		}
+		// pages are in chronological order, walk them backwards
+		for i, j := 0, len(page)-1; i < j; i, j = i+1, j-1 {
+			page[i], page[j] = page[j], page[i]
+		}
+		it.page = page
+	}
+	it.current, it.page = &it.page[0], it.page[1:]
+	return true
+}
+
+// readAll reads the whole history in chronological order.
+func (it *MessageIterator) readAll() {
+	var all []MessagesDataResponse
+	for !it.last {
+		page, ok := it.fetch()
+		if !ok {
+			return
+		}
+		all = append(page, all...)
+	}
+	it.page = all
+}
+
+// fetch returns the page before it.req.FirstID, in chronological order.
+func (it *MessageIterator) fetch() ([]MessagesDataResponse, bool) {
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+		return nil, false
+	}
+	req := it.req
+	resp, err := it.api.Messages(it.ctx, &req)
+	if err != nil {
+		it.err = err
+		return nil, false
+	}
+	it.last = !resp.HasMore || len(resp.Data) == 0
+	if len(resp.Data) > 0 {
+		it.req.FirstID = resp.Data[0].ID
+	}
+	return resp.Data, true
+}
+
+// Current returns the current message.
+func (it *MessageIterator) Current() *MessagesDataResponse {
+	return it.current
+}
+
+// Err returns the error that ended the iteration, nil when all the messages were read.
+func (it *MessageIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,83 @@
+package dify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestIterateConversations(t *testing.T) {
+	var want []string
+	for i := 0; i < 5; i++ {
+		want = append([]string{testServer.AddConversation("test-user-iterate", fmt.Sprintf("conversation %d", i))}, want...)
+	}
+
+	it := NewClient(host, apiSecretKey).API().IterateConversations(context.Background(), &ConversationsRequest{User: "test-user-iterate", Limit: 2})
+	var got []string
+	for it.Next() {
+		got = append(got, it.Current().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("conversations = %v, want %v", got, want)
+	}
+}
+
+func TestIterateMessages(t *testing.T) {
+	cId := testServer.AddConversation("test-user-iterate", "messages")
+	var chronological []string
+	for i := 0; i < 5; i++ {
+		chronological = append(chronological, testServer.AddMessage(cId, fmt.Sprintf("question %d", i), "answer"))
+	}
+	var newestFirst []string
+	for i := len(chronological) - 1; i >= 0; i-- {
+		newestFirst = append(newestFirst, chronological[i])
+	}
+
+	api := NewClient(host, apiSecretKey).API()
+	for _, tt := range []struct {
+		order MessageOrder
+		want  []string
+	}{
+		{MessagesNewestFirst, newestFirst},
+		{MessagesChronological, chronological},
+	} {
+		it := api.IterateMessages(context.Background(), &MessagesRequest{ConversationID: cId, User: "test-user-iterate", Limit: 2}, tt.order)
+		var got []string
+		for it.Next() {
+			got = append(got, it.Current().ID)
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("order %d: messages = %v, want %v", tt.order, got, tt.want)
+		}
+	}
+}
+
+func TestIterateMessagesCanceled(t *testing.T) {
+	cId := testServer.AddConversation("test-user-iterate", "canceled")
+	for i := 0; i < 3; i++ {
+		testServer.AddMessage(cId, "question", "answer")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	it := NewClient(host, apiSecretKey).API().IterateMessages(ctx, &MessagesRequest{ConversationID: cId, User: "test-user-iterate", Limit: 2}, MessagesNewestFirst)
+	if !it.Next() {
+		t.Fatal(it.Err())
+	}
+	cancel()
+	n := len(testServer.RequestsTo(http.MethodGet, "/messages"))
+	for it.Next() {
+	}
+	if it.Err() != context.Canceled {
+		t.Errorf("err = %v, want context.Canceled", it.Err())
+	}
+	if len(testServer.RequestsTo(http.MethodGet, "/messages")) != n {
+		t.Error("a page was fetched after the context was canceled")
+	}
+}
@@ -4,20 +4,27 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"net/http"
 	"os"
 	"strings"
 	"sync"
 	"testing"
+
+	"github.com/taadis/dify-sdk-go/difytest"
 )
 
 var (
-	host         = os.Getenv("DIFY_API_HOST") // your dify api host
-	apiSecretKey = os.Getenv("DIFY_API_KEY")  // your dify api secret key e.g.app-xxx
+	host         = "" // fake dify api host, set by TestMain
+	apiSecretKey = "app-test"
+	testServer   *difytest.Server
 )
 
 func TestMain(m *testing.M) {
-	host = DifyCloud
-	os.Exit(m.Run())
+	testServer = difytest.NewServer(difytest.WithAPIKey(apiSecretKey))
+	host = testServer.URL
+	code := m.Run()
+	testServer.Close()
+	os.Exit(code)
 }
 
 func TestApi3(t *testing.T) {
@@ -36,7 +43,7 @@ func TestApi3(t *testing.T) {
 
 	ch, err = client.Api().ChatMessagesStream(ctx, &ChatMessageRequest{
 		Query: "你是谁?",
-		User:  "这里换成你创建的",
+		User:  "test-user-api3",
 	})
 	if err != nil {
 		t.Fatal(err.Error())
@@ -55,6 +62,9 @@ func TestApi3(t *testing.T) {
 			if !isOpen {
 				goto done
 			}
+			if r.Err != nil {
+				t.Fatal(r.Err)
+			}
 			strBuilder.WriteString(r.Answer)
 			cId = r.ConversationID
 			log.Println("Answer2", r.Answer, r.ConversationID, cId, r.ID, r.TaskID)
@@ -62,12 +72,18 @@ func TestApi3(t *testing.T) {
 	}
 
 done:
-	t.Log(strBuilder.String())
-	t.Log(cId)
+	if got := strBuilder.String(); got != "echo: 你是谁?" {
+		t.Errorf("answer = %q, want %q", got, "echo: 你是谁?")
+	}
+	if cId == "" {
+		t.Error("expected a conversation id")
+	}
 }
 
 func TestMessages(t *testing.T) {
-	var cId = "ec373942-2d17-4f11-89bb-f9bbf863ebcc"
+	var cId = testServer.AddConversation("test-user", "messages")
+	testServer.AddMessage(cId, "hello", "hi")
+	testServer.AddMessage(cId, "how are you?", "fine")
 	var err error
 	ctx := context.Background()
 
@@ -84,8 +100,67 @@ func TestMessages(t *testing.T) {
 		t.Fatal(err.Error())
 		return
 	}
-	j, _ := json.Marshal(msg)
-	t.Log(string(j))
+	if len(msg.Data) != 2 || msg.Data[0].Query != "hello" || msg.Data[1].Answer != "fine" {
+		j, _ := json.Marshal(msg)
+		t.Errorf("unexpected messages: %s", j)
+	}
+}
+
+func TestMessagesParent(t *testing.T) {
+	var client = NewClient(host, apiSecretKey)
+	ctx := context.Background()
+
+	first, err := client.API().ChatMessages(ctx, &ChatMessageRequest{Query: "hello", User: "test-user-parent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := client.API().ChatMessages(ctx, &ChatMessageRequest{Query: "hello again", User: "test-user-parent", ConversationID: first.ConversationID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// answer "hello again" again, as a sibling of the second message
+	branch, err := client.API().ChatMessages(ctx, &ChatMessageRequest{Query: "hello again", User: "test-user-parent", ConversationID: first.ConversationID, ParentMessageID: first.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := testServer.AssertRequest(t, http.MethodPost, "/chat-messages").Map(); body["parent_message_id"] != first.ID {
+		t.Errorf("unexpected body %v", body)
+	}
+
+	msg, err := client.API().Messages(ctx, &MessagesRequest{ConversationID: first.ConversationID, User: "test-user-parent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parents := make(map[string]string)
+	for _, m := range msg.Data {
+		parents[m.ID] = m.ParentMessageID
+	}
+	if len(parents) != 3 || parents[first.ID] != "" || parents[second.ID] != first.ID || parents[branch.ID] != first.ID {
+		t.Errorf("parents = %v", parents)
+	}
+}
+
+func TestMessagesSuggested(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(apiSecretKey), difytest.WithSuggestedQuestions("Why?"))
+	defer srv.Close()
+	var client = NewClient(srv.URL, apiSecretKey)
+	ctx := context.Background()
+
+	id := srv.AddMessage(srv.AddConversation("test-user", "suggested"), "hello", "hi")
+	res, err := client.API().MessagesSuggested(ctx, &MessagesSuggestedRequest{MessageID: id, User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Result != "success" || len(res.Data) != 1 || res.Data[0] != "Why?" {
+		t.Errorf("unexpected response: %+v", res)
+	}
+	params, err := client.API().Parameters(ctx, &ParametersRequest{User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !params.SuggestedQuestionsAfterAnswer.Enabled {
+		t.Error("suggested questions are disabled in the parameters")
+	}
 }
 
 func TestMessagesFeedbacks(t *testing.T) {
@@ -93,10 +168,9 @@ func TestMessagesFeedbacks(t *testing.T) {
 	var err error
 	ctx := context.Background()
 
-	var id = "72d3dc0f-a6d5-4b5e-8510-bec0611a6048"
+	var id = testServer.AddMessage(testServer.AddConversation("test-user", "feedbacks"), "hello", "hi")
 
-	var res *MessagesFeedbacksResponse
-	if res, err = client.Api().MessagesFeedbacks(ctx, &MessagesFeedbacksRequest{
+	if _, err = client.Api().MessagesFeedbacks(ctx, &MessagesFeedbacksRequest{
 		MessageID: id,
 		Rating:    FeedbackLike,
 		User:      "test-user",
@@ -104,9 +178,10 @@ func TestMessagesFeedbacks(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 
-	j, _ := json.Marshal(res)
-
-	log.Println(string(j))
+	req := testServer.AssertRequest(t, http.MethodPost, "/messages/"+id+"/feedbacks")
+	if body := req.Map(); body["rating"] != FeedbackLike || body["user"] != "test-user" {
+		t.Errorf("unexpected body: %s", req.Body)
+	}
 }
 
 func TestConversations(t *testing.T) {
@@ -114,16 +189,19 @@ func TestConversations(t *testing.T) {
 	var err error
 	ctx := context.Background()
 
+	id := testServer.AddConversation("test-user-conversations", "first")
+
 	var res *ConversationsResponse
 	if res, err = client.Api().Conversations(ctx, &ConversationsRequest{
-		User: "test-user",
+		User: "test-user-conversations",
 	}); err != nil {
 		t.Fatal(err.Error())
 	}
 
-	j, _ := json.Marshal(res)
-
-	log.Println(string(j))
+	if len(res.Data) != 1 || res.Data[0].ID != id || res.Data[0].Name != "first" {
+		j, _ := json.Marshal(res)
+		t.Errorf("unexpected conversations: %s", j)
+	}
 }
 
 func TestConversationsRename(t *testing.T) {
@@ -131,18 +209,24 @@ func TestConversationsRename(t *testing.T) {
 	var err error
 	ctx := context.Background()
 
-	var res *ConversationsRenamingResponse
-	if res, err = client.Api().ConversationsRenaming(ctx, &ConversationsRenamingRequest{
-		ConversationID: "ab383831-1e38-3d00-76cd-d6ppe752cedd",
+	id := testServer.AddConversation("test-user-rename", "before")
+
+	if _, err = client.Api().ConversationsRenaming(ctx, &ConversationsRenamingRequest{
+		ConversationID: id,
 		Name:           "rename!!!",
-		User:           "test-user",
+		User:           "test-user-rename",
 	}); err != nil {
 		t.Fatal(err.Error())
 	}
 
-	j, _ := json.Marshal(res)
-
-	log.Println(string(j))
+	res, err := client.Api().Conversations(ctx, &ConversationsRequest{User: "test-user-rename"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Data) != 1 || res.Data[0].Name != "rename!!!" {
+		j, _ := json.Marshal(res)
+		t.Errorf("unexpected conversations: %s", j)
+	}
 }
 
 func TestParameters(t *testing.T) {
@@ -157,9 +241,19 @@ func TestParameters(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 
-	j, _ := json.Marshal(res)
+	if res.OpeningStatement == "" || len(res.UserInputForm) == 0 {
+		j, _ := json.Marshal(res)
+		t.Errorf("unexpected parameters: %s", j)
+	}
+}
 
-	log.Println(string(j))
+func TestUnauthorized(t *testing.T) {
+	var client = NewClient(host, "app-wrong")
+
+	_, err := client.API().Parameters(context.Background(), &ParametersRequest{User: "test-user"})
+	if !IsUnauthorized(err) {
+		t.Fatalf("expected an unauthorized error, got %v", err)
+	}
 }
 
 // testEventHandler 实现 EventHandler 接口
@@ -181,3 +275,33 @@ func (h *testEventHandler) HandleTTSMessage(msg TTSMessage) {
 		h.onTTSMessage(msg)
 	}
 }
+
+func TestMiddlewareRootAPI(t *testing.T) {
+	var paths []string
+	client := NewClientWithConfig(&ClientConfig{
+		Host:             host,
+		DefaultAPISecret: apiSecretKey,
+		Middlewares: []Middleware{
+			func(next Handler) Handler {
+				return func(req *http.Request) (*http.Response, error) {
+					req.Header.Set("X-Audit", "on")
+					paths = append(paths, req.URL.Path)
+					return next(req)
+				}
+			},
+		},
+	})
+	res, err := client.API().Parameters(context.Background(), &ParametersRequest{User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.OpeningStatement == "" {
+		t.Error("expected an opening statement")
+	}
+	if len(paths) != 1 || paths[0] != "/v1/parameters" {
+		t.Errorf("paths = %v, want [/v1/parameters]", paths)
+	}
+	if got := testServer.AssertRequest(t, http.MethodGet, "/parameters").Header.Get("X-Audit"); got != "on" {
+		t.Errorf("X-Audit = %q, want %q", got, "on")
+	}
+}
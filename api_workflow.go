@@ -1,12 +1,13 @@
 package dify
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+
+	"github.com/taadis/dify-sdk-go/client"
+	"github.com/taadis/dify-sdk-go/workflow"
 )
 
 // 事件类型常量
@@ -17,15 +18,24 @@ const (
 	EventWorkflowFinished = "workflow_finished"
 	EventTTSMessage       = "tts_message"
 	EventTTSMessageEnd    = "tts_message_end"
+
+	EventNodeRetry              = workflow.EventNodeRetry
+	EventTextChunk              = workflow.EventTextChunk
+	EventIterationStarted       = workflow.EventIterationStarted
+	EventIterationNext          = workflow.EventIterationNext
+	EventIterationCompleted     = workflow.EventIterationCompleted
+	EventLoopStarted            = workflow.EventLoopStarted
+	EventLoopNext               = workflow.EventLoopNext
+	EventLoopCompleted          = workflow.EventLoopCompleted
+	EventParallelBranchStarted  = workflow.EventParallelBranchStarted
+	EventParallelBranchFinished = workflow.EventParallelBranchFinished
+	EventAgentLog               = workflow.EventAgentLog
+	EventError                  = workflow.EventError
+	EventPing                   = workflow.EventPing
 )
 
-// FileInput 结构体
-type FileInput struct {
-	Type           string `json:"type"`                     // 目前仅支持 "image"
-	TransferMethod string `json:"transfer_method"`          // "remote_url" 或 "local_file"
-	URL            string `json:"url,omitempty"`            // 当 transfer_method 为 remote_url 时使用
-	UploadFileID   string `json:"upload_file_id,omitempty"` // 当 transfer_method 为 local_file 时使用
-}
+// FileInput is a file input of a workflow, shared with the workflow package.
+type FileInput = workflow.FileInput
 
 // WorkflowRequest 结构体
 type WorkflowRequest struct {
@@ -49,15 +59,15 @@ type StreamingResponse struct {
 		Title             string                 `json:"title,omitempty"`
 		Index             int                    `json:"index"`
 		Predecessor       string                 `json:"predecessor_node_id,omitempty"`
-		Inputs            []interface{}          `json:"inputs,omitempty"`
+		Inputs            map[string]interface{} `json:"inputs,omitempty"`
 		Outputs           map[string]interface{} `json:"outputs,omitempty"`
 		Status            string                 `json:"status,omitempty"`
 		Error             string                 `json:"error,omitempty"`
 		ElapsedTime       float64                `json:"elapsed_time,omitempty"`
 		ExecutionMetadata struct {
-			TotalTokens int     `json:"total_tokens,omitempty"`
-			TotalPrice  float64 `json:"total_price,omitempty"`
-			Currency    string  `json:"currency,omitempty"`
+			TotalTokens int            `json:"total_tokens,omitempty"`
+			TotalPrice  client.Decimal `json:"total_price,omitempty"`
+			Currency    string         `json:"currency,omitempty"`
 		} `json:"execution_metadata,omitempty"`
 		CreatedAt  int64 `json:"created_at"`
 		FinishedAt int64 `json:"finished_at,omitempty"`
@@ -100,70 +110,107 @@ func (api *API) RunStreamWorkflow(ctx context.Context, request WorkflowRequest,
 }
 
 // RunStreamWorkflowWithHandler 方法
+//
+// Deprecated: StreamingResponse only fits the node and workflow events, use
+// RunStreamWorkflowWithEventHandler to receive every event typed.
 func (api *API) RunStreamWorkflowWithHandler(ctx context.Context, request WorkflowRequest, handler EventHandler) error {
-	req, err := api.createBaseRequest(ctx, http.MethodPost, "/v1/workflows/run", request)
+	stream, err := api.StreamWorkflow(ctx, request)
 	if err != nil {
 		return err
 	}
+	defer stream.Close()
+
+	for stream.Next() {
+		data := stream.Data()
+		switch stream.Current().(type) {
+		case *workflow.TTSMessageEvent:
+			var msg TTSMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				return fmt.Errorf("error decoding %s event: %w", stream.Current().EventType(), err)
+			}
+			handler.HandleTTSMessage(msg)
+		default:
+			var resp StreamingResponse
+			if err := json.Unmarshal(data, &resp); err != nil {
+				return fmt.Errorf("error decoding %s event: %w", stream.Current().EventType(), err)
+			}
+			handler.HandleStreamingResponse(resp)
+		}
+	}
+	return stream.Err()
+}
+
+// WorkflowEventHandler receives the typed events of a workflow stream, see workflow.EventHandler.
+type WorkflowEventHandler = workflow.EventHandler
+
+// BaseWorkflowEventHandler ignores every event, embed it to implement only the
+// WorkflowEventHandler methods of interest.
+type BaseWorkflowEventHandler = workflow.BaseEventHandler
 
-	resp, err := api.c.sendRequest(req)
+// RunStreamWorkflowWithEventHandler runs a workflow in streaming mode and calls the
+// method of handler matching each event. An error event is passed to OnError, then
+// returned as an *APIError.
+func (api *API) RunStreamWorkflowWithEventHandler(ctx context.Context, request WorkflowRequest, handler WorkflowEventHandler) error {
+	stream, err := api.StreamWorkflow(ctx, request)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
+	return workflow.HandleStream(stream, handler)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API request failed with status %s: %s", resp.Status, readResponseBody(resp.Body))
-	}
+// WorkflowStreamEvent is an event of a workflow stream, see workflow.Event.
+type WorkflowStreamEvent = workflow.Event
 
-	reader := bufio.NewReader(resp.Body)
-	for {
-		line, err := reader.ReadBytes('\n')
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return fmt.Errorf("error reading streaming response: %w", err)
-		}
+// EventType returns the event type.
+func (r *StreamingResponse) EventType() string {
+	return r.Event
+}
 
-		if len(line) > 6 && string(line[:6]) == "data: " {
-			var event struct {
-				Event string `json:"event"`
-			}
-			if err := json.Unmarshal(line[6:], &event); err != nil {
-				fmt.Println("Error decoding event type:", err)
-				continue
-			}
+// WorkflowStream iterates over the events of a streaming workflow run, see workflow.Stream.
+type WorkflowStream = workflow.Stream
 
-			switch event.Event {
-			case EventTTSMessage, EventTTSMessageEnd:
-				var ttsMsg TTSMessage
-				if err := json.Unmarshal(line[6:], &ttsMsg); err != nil {
-					fmt.Println("Error decoding TTS message:", err)
-					continue
-				}
-				handler.HandleTTSMessage(ttsMsg)
-			default:
-				var streamResp StreamingResponse
-				if err := json.Unmarshal(line[6:], &streamResp); err != nil {
-					fmt.Println("Error decoding streaming response:", err)
-					continue
-				}
-				handler.HandleStreamingResponse(streamResp)
-			}
-		}
+// StreamWorkflow runs a workflow in streaming mode. Error responses are returned as an *APIError.
+func (api *API) StreamWorkflow(ctx context.Context, request WorkflowRequest) (*WorkflowStream, error) {
+	request.ResponseMode = "streaming"
+	req, err := api.createBaseRequest(ctx, http.MethodPost, "/v1/workflows/run", request)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := api.c.sendStoppableStreamRequest(req, func(ctx context.Context, taskId string) error {
+		_, err := api.StopWorkflowTask(ctx, &StopWorkflowTaskRequest{TaskId: taskId, User: request.User})
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	return workflow.NewStream(stream), nil
+}
+
+type StopWorkflowTaskRequest struct {
+	TaskId string `json:"-"`
+	User   string `json:"user"`
+}
 
-	return nil
+type StopWorkflowTaskResponse struct {
+	Result string `json:"result"`
 }
 
-// readResponseBody 辅助函数
-func readResponseBody(body io.Reader) string {
-	bodyBytes, err := io.ReadAll(body)
+// StopWorkflowTask stops a streaming workflow run, TaskId comes from the stream events.
+func (api *API) StopWorkflowTask(ctx context.Context, req *StopWorkflowTaskRequest) (*StopWorkflowTaskResponse, error) {
+	if req.TaskId == "" {
+		return nil, fmt.Errorf("task_id is required")
+	}
+	r, err := api.createBaseRequest(client.WithIdempotent(ctx), http.MethodPost, fmt.Sprintf("/v1/workflows/tasks/%s/stop", req.TaskId), req)
+	if err != nil {
+		return nil, err
+	}
+
+	var rsp StopWorkflowTaskResponse
+	err = api.c.sendJSONRequest(r, &rsp)
 	if err != nil {
-		return fmt.Sprintf("failed to read response body: %v", err)
+		return nil, err
 	}
-	return string(bodyBytes)
+	return &rsp, nil
 }
 
 type GetWorkflowRunDetailRequest struct {
@@ -175,12 +222,14 @@ type GetWorkflowRunDetailResponse struct {
 	Id string `json:"id"`
 	// e.g.3c90c3cc-0d44-4b50-8888-8dd25736052a
 	WorkflowId string `json:"workflow_id"`
+	// Version of the workflow that executed, empty on Dify versions that do not report it.
+	Version string `json:"version,omitempty"`
 	// e.g.running,succeeded,failed,stopped
 	Status string `json:"status"`
-	// JSON string of input content.
-	Inputs map[string]interface{} `json:"inputs"`
-	// JSON object of output content.
-	Outputs map[string]interface{} `json:"outputs,omitempty"`
+	// Input variables, sent as an object or a JSON string depending on the Dify version.
+	Inputs WorkflowVariables `json:"inputs"`
+	// Output variables, see WorkflowVariables.Decode.
+	Outputs WorkflowVariables `json:"outputs,omitempty"`
 	//
 	Error *string `json:"error,omitempty"`
 	// e.g.123
@@ -192,7 +241,7 @@ type GetWorkflowRunDetailResponse struct {
 	// e.g.123
 	FinishedAt int64 `json:"finished_at"`
 	// e.g.123
-	ElapsedTime int64 `json:"elapsed_time"`
+	ElapsedTime float64 `json:"elapsed_time"`
 }
 
 func (r *GetWorkflowRunDetailResponse) String() string {
@@ -217,13 +266,19 @@ func (api *API) GetWorkflowRunDetail(ctx context.Context, req *GetWorkflowRunDet
 	return &rsp, nil
 }
 
-type WorkflowStatus string
+// WorkflowVariables holds the inputs or outputs of a workflow run, see workflow.Variables.
+type WorkflowVariables = workflow.Variables
+
+// WorkflowStatus is the status of a workflow run, shared with the workflow package.
+type WorkflowStatus = workflow.WorkflowStatus
 
 const (
-	WorkflowStatusSucceeded WorkflowStatus = "succeeded"
-	WorkflowStatusFailed    WorkflowStatus = "failed"
-	WorkflowStatusStopped   WorkflowStatus = "stopped"
-	WorkflowStatusRunning   WorkflowStatus = "running"
+	WorkflowStatusSucceeded = workflow.WorkflowStatusSucceeded
+	WorkflowStatusFailed    = workflow.WorkflowStatusFailed
+	WorkflowStatusStopped   = workflow.WorkflowStatusStopped
+	WorkflowStatusRunning   = workflow.WorkflowStatusRunning
+
+	WorkflowStatusPartialSucceeded = workflow.WorkflowStatusPartialSucceeded
 )
 
 type GetWorkflowLogsRequest struct {
@@ -2,13 +2,35 @@ package dify
 
 import (
 	"context"
+	"net/http"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/taadis/dify-sdk-go/difytest"
+	"github.com/taadis/dify-sdk-go/workflow"
 )
 
 func TestRunWorkflowStreaming(t *testing.T) {
-	client := NewClient(host, apiSecretKey)
+	srv := difytest.NewServer(difytest.WithAPIKey(apiSecretKey))
+	defer srv.Close()
+	srv.Handle(http.MethodPost, "/workflows/run", difytest.Stream(
+		"event: ping\n\n",
+		map[string]interface{}{"event": "workflow_started", "task_id": "task-1", "workflow_run_id": "run-1",
+			"data": map[string]interface{}{"id": "run-1", "workflow_id": "wf-1", "sequence_number": 1, "created_at": 1}},
+		map[string]interface{}{"event": "node_started", "task_id": "task-1", "workflow_run_id": "run-1",
+			"data": map[string]interface{}{"id": "node-1", "node_id": "llm", "node_type": "llm", "title": "LLM", "index": 1, "created_at": 1}},
+		map[string]interface{}{"event": "node_finished", "task_id": "task-1", "workflow_run_id": "run-1",
+			"data": map[string]interface{}{"id": "node-1", "node_id": "llm", "node_type": "llm", "title": "LLM", "index": 1,
+				"status": "succeeded", "execution_metadata": map[string]interface{}{"total_tokens": 12, "total_price": "0.0001", "currency": "USD"}}},
+		map[string]interface{}{"event": "tts_message", "task_id": "task-1", "message_id": "msg-1", "audio": "SUQz", "created_at": 1},
+		map[string]interface{}{"event": "tts_message_end", "task_id": "task-1", "message_id": "msg-1", "audio": "", "created_at": 1},
+		map[string]interface{}{"event": "workflow_finished", "task_id": "task-1", "workflow_run_id": "run-1",
+			"data": map[string]interface{}{"id": "run-1", "workflow_id": "wf-1", "status": "succeeded",
+				"outputs": map[string]interface{}{"text": "ok"}, "total_steps": 1, "created_at": 1, "finished_at": 2}},
+	))
+	client := NewClient(srv.URL, apiSecretKey)
 
 	workflowReq := WorkflowRequest{
 		Inputs: map[string]interface{}{
@@ -49,6 +71,9 @@ func TestRunWorkflowStreaming(t *testing.T) {
 				nodeStarted = true
 			case EventNodeFinished:
 				nodeFinished = true
+				if price := resp.Data.ExecutionMetadata.TotalPrice; price != "0.0001" {
+					t.Errorf("total_price = %q, want 0.0001", price)
+				}
 				if resp.Data.ExecutionMetadata.TotalTokens > 0 {
 					t.Logf("Node used %d tokens", resp.Data.ExecutionMetadata.TotalTokens)
 				}
@@ -63,6 +88,9 @@ func TestRunWorkflowStreaming(t *testing.T) {
 			mu.Lock()
 			defer mu.Unlock()
 
+			if msg.Event != EventTTSMessage {
+				return
+			}
 			ttsReceived = true
 			if msg.Audio == "" {
 				t.Error("Expected non-empty audio data in TTS message")
@@ -101,15 +129,137 @@ func TestRunWorkflowStreaming(t *testing.T) {
 
 func TestGetWorkflowRunDetail(t *testing.T) {
 	ctx := context.Background()
-	req := GetWorkflowRunDetailRequest{
-		WorkflowRunId: "test-workflow-run-id",
+	client := NewClient(host, apiSecretKey)
+
+	var runId string
+	err := client.API().RunStreamWorkflow(ctx, WorkflowRequest{
+		Inputs:       map[string]interface{}{"query": "hello"},
+		ResponseMode: "streaming",
+		User:         "test-user",
+	}, func(resp StreamingResponse) {
+		if resp.Event == EventWorkflowFinished {
+			runId = resp.WorkflowRunID
+		}
+	})
+	if err != nil {
+		t.Fatalf("RunStreamWorkflow encountered an error: %v", err)
+	}
+	if runId == "" {
+		t.Fatal("Expected a workflow_finished event")
 	}
 
-	client := NewClient(host, apiSecretKey)
+	req := GetWorkflowRunDetailRequest{
+		WorkflowRunId: runId,
+	}
 	rsp, err := client.API().GetWorkflowRunDetail(ctx, &req)
 	if err != nil {
-		t.Fatalf("RunWorkflow encountered an error: %v", err)
+		t.Fatalf("GetWorkflowRunDetail encountered an error: %v", err)
+	}
+	if rsp.Id != runId || rsp.Status != string(WorkflowStatusSucceeded) || rsp.Inputs["query"] != "hello" {
+		t.Errorf("unexpected run detail: %s", rsp.String())
+	}
+
+	_, err = client.API().GetWorkflowRunDetail(ctx, &GetWorkflowRunDetailRequest{WorkflowRunId: "unknown"})
+	if !IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+
+}
+
+func TestStreamWorkflow(t *testing.T) {
+	stream, err := NewClient(host, apiSecretKey).API().StreamWorkflow(context.Background(), WorkflowRequest{
+		Inputs: map[string]interface{}{"query": "hello"},
+		User:   "test-user",
+	})
+	if err != nil {
+		t.Fatal(err)
 	}
-	t.Log(rsp.String())
+	defer stream.Close()
 
+	var events []string
+	var finished *workflow.WorkflowFinishedEvent
+	for stream.Next() {
+		events = append(events, stream.Current().EventType())
+		if e, ok := stream.Current().(*workflow.WorkflowFinishedEvent); ok {
+			finished = e
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) == 0 || events[0] != EventWorkflowStarted {
+		t.Errorf("events = %v", events)
+	}
+	if finished == nil || finished.Data.Status != workflow.WorkflowStatusSucceeded || finished.Data.Outputs["query"] != "hello" {
+		t.Errorf("unexpected workflow_finished %+v", finished)
+	}
+}
+
+func TestStreamWorkflowErrorEvent(t *testing.T) {
+	srv := difytest.NewServer()
+	defer srv.Close()
+	srv.Handle(http.MethodPost, "/workflows/run", difytest.Stream(
+		map[string]interface{}{"event": "workflow_started", "task_id": "task-1", "workflow_run_id": "run-1", "data": map[string]interface{}{"id": "run-1"}},
+		map[string]interface{}{"event": "error", "task_id": "task-1", "status": 500, "code": "internal_server_error", "message": "boom"},
+	))
+
+	err := NewClient(srv.URL, apiSecretKey).API().RunStreamWorkflow(context.Background(), WorkflowRequest{User: "test-user"}, func(StreamingResponse) {})
+	if !IsServerError(err) {
+		t.Fatalf("expected a server error, got %v", err)
+	}
+}
+
+type recordingWorkflowHandler struct {
+	BaseWorkflowEventHandler
+	events []string
+	text   string
+	err    *workflow.ErrorEvent
+}
+
+func (h *recordingWorkflowHandler) OnWorkflowStarted(e *workflow.WorkflowStartedEvent) {
+	h.events = append(h.events, e.Event)
+}
+
+func (h *recordingWorkflowHandler) OnIterationNext(e *workflow.IterationNextEvent) {
+	h.events = append(h.events, e.Event)
+}
+
+func (h *recordingWorkflowHandler) OnTextChunk(e *workflow.TextChunkEvent) {
+	h.text += e.Data.Text
+}
+
+func (h *recordingWorkflowHandler) OnError(e *workflow.ErrorEvent) {
+	h.err = e
+}
+
+func (h *recordingWorkflowHandler) OnEvent(e workflow.Event) {
+	h.events = append(h.events, "unknown:"+e.EventType())
+}
+
+func TestRunStreamWorkflowWithEventHandler(t *testing.T) {
+	srv := difytest.NewServer()
+	defer srv.Close()
+	srv.Handle(http.MethodPost, "/workflows/run", difytest.Stream(
+		map[string]interface{}{"event": "workflow_started", "task_id": "task-1", "workflow_run_id": "run-1", "data": map[string]interface{}{"id": "run-1"}},
+		map[string]interface{}{"event": "iteration_next", "task_id": "task-1", "workflow_run_id": "run-1", "data": map[string]interface{}{"id": "it-1", "index": 1}},
+		map[string]interface{}{"event": "text_chunk", "task_id": "task-1", "workflow_run_id": "run-1", "data": map[string]interface{}{"text": "he"}},
+		map[string]interface{}{"event": "text_chunk", "task_id": "task-1", "workflow_run_id": "run-1", "data": map[string]interface{}{"text": "llo"}},
+		map[string]interface{}{"event": "node_paused", "task_id": "task-1", "workflow_run_id": "run-1"},
+		map[string]interface{}{"event": "error", "task_id": "task-1", "status": 400, "code": "invalid_param", "message": "bad input"},
+	))
+
+	h := &recordingWorkflowHandler{}
+	err := NewClient(srv.URL, apiSecretKey).API().RunStreamWorkflowWithEventHandler(context.Background(), WorkflowRequest{User: "test-user"}, h)
+	if !IsInvalidParam(err) {
+		t.Fatalf("expected an invalid_param error, got %v", err)
+	}
+	if want := []string{EventWorkflowStarted, EventIterationNext, "unknown:node_paused"}; !reflect.DeepEqual(h.events, want) {
+		t.Errorf("events = %v, want %v", h.events, want)
+	}
+	if h.text != "hello" {
+		t.Errorf("text = %q", h.text)
+	}
+	if h.err == nil || h.err.Message != "bad input" {
+		t.Errorf("error event = %+v", h.err)
+	}
 }
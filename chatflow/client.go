@@ -7,8 +7,27 @@ import (
 )
 
 type ChatflowClient interface {
+	// Send Chat Message in blocking mode
+	SendMessage(ctx context.Context, req *ChatMessageRequest) (*ChatMessageResponse, error)
+	// Send Chat Message in streaming mode, with the workflow and node events
+	SendMessageStream(ctx context.Context, req *ChatMessageRequest) (*Stream, error)
+	SendMessageStreamWithHandler(ctx context.Context, req *ChatMessageRequest, h EventHandler) error
 	// Stop Advanced Chat Message Generation
 	Stop(ctx context.Context, req *StopRequest) (*StopResponse, error)
+	// Get Conversations of a user
+	GetConversations(ctx context.Context, req *GetConversationsRequest) (*GetConversationsResponse, error)
+	// Conversation Rename
+	RenameConversation(ctx context.Context, req *RenameConversationRequest) (*Conversation, error)
+	// Delete Conversation
+	DeleteConversation(ctx context.Context, req *DeleteConversationRequest) (*DeleteConversationResponse, error)
+	// Get Conversation Variables
+	GetConversationVariables(ctx context.Context, req *GetConversationVariablesRequest) (*GetConversationVariablesResponse, error)
+	// Update Conversation Variable
+	UpdateConversationVariable(ctx context.Context, req *UpdateConversationVariableRequest) (*ConversationVariable, error)
+	// Get Conversation History Messages
+	GetMessages(ctx context.Context, req *GetMessagesRequest) (*GetMessagesResponse, error)
+	// Next Suggested Questions
+	GetSuggested(ctx context.Context, req *GetSuggestedRequest) (*GetSuggestedResponse, error)
 }
 
 type chatflowClient struct {
@@ -20,3 +39,9 @@ func NewChatflowClient(baseUrl string, apiKey string) ChatflowClient {
 	c.Client = client.NewClient(baseUrl, apiKey)
 	return c
 }
+
+func NewChatflowClientWithConfig(config *client.ClientConfig) ChatflowClient {
+	c := new(chatflowClient)
+	c.Client = client.NewClientWithConfig(config)
+	return c
+}
@@ -0,0 +1,347 @@
+package chatflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Value types of a ConversationVariable.
+const (
+	VariableTypeString      = "string"
+	VariableTypeNumber      = "number"
+	VariableTypeObject      = "object"
+	VariableTypeSecret      = "secret"
+	VariableTypeArrayString = "array[string]"
+	VariableTypeArrayNumber = "array[number]"
+	VariableTypeArrayObject = "array[object]"
+)
+
+// ConversationVariable is a variable holding the state of a conversation, e.g. the
+// content of a cart.
+type ConversationVariable struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	// One of the VariableType constants.
+	ValueType string `json:"value_type"`
+	// Value decoded according to ValueType: a string for string and secret, a
+	// float64 for number, a map[string]interface{} for object and a []interface{}
+	// for the arrays.
+	Value       interface{} `json:"value"`
+	Description string      `json:"description"`
+	CreatedAt   int64       `json:"created_at"`
+	UpdatedAt   int64       `json:"updated_at"`
+}
+
+// UnmarshalJSON decodes the value according to the value type. Depending on its
+// version, Dify encodes the values other than strings as JSON or as a string holding
+// the JSON, both are accepted.
+func (r *ConversationVariable) UnmarshalJSON(b []byte) error {
+	type plain ConversationVariable
+	var raw struct {
+		plain
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*r = ConversationVariable(raw.plain)
+	value, err := decodeVariableValue(raw.ValueType, raw.Value)
+	if e, ok := err.(*VariableTypeError); ok {
+		e.Variable = raw.Name
+		return e
+	}
+	if err != nil {
+		return fmt.Errorf("chatflow: cannot decode variable %q: %w", raw.Name, err)
+	}
+	r.Value = value
+	return nil
+}
+
+func decodeVariableValue(valueType string, b json.RawMessage) (interface{}, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 || bytes.Equal(b, []byte("null")) {
+		return nil, nil
+	}
+	if b[0] == '"' && valueType != VariableTypeString && valueType != VariableTypeSecret {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(s) == "" {
+			return nil, nil
+		}
+		b = json.RawMessage(s)
+	}
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return nil, err
+	}
+	if err := checkVariableValue(valueType, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Decode decodes the value into dst, e.g. a struct for an object variable.
+func (r *ConversationVariable) Decode(dst interface{}) error {
+	bs, err := json.Marshal(r.Value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bs, dst)
+}
+
+func (r *ConversationVariable) String() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+func (r *ConversationVariable) MarshalIndent() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+// VariableTypeError reports a value that does not match the type of its variable.
+type VariableTypeError struct {
+	// Variable is the name of the variable, its ID for an update.
+	Variable string
+	// ValueType is the declared type of the variable, e.g. "array[number]".
+	ValueType string
+	// Value describes the JSON value, e.g. "string" or "array of number".
+	Value string
+}
+
+func (e *VariableTypeError) Error() string {
+	return fmt.Sprintf("chatflow: invalid value for variable %q: got %s, want %s", e.Variable, e.Value, e.ValueType)
+}
+
+// CheckVariableValue checks that value encodes to the JSON of the variable type
+// valueType, e.g. a []int for array[number]. A mismatch is reported as a
+// *VariableTypeError.
+func CheckVariableValue(valueType string, value interface{}) error {
+	// check the JSON of the value, as Dify will see it
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("chatflow: cannot encode variable value: %w", err)
+	}
+	var v interface{}
+	if err := json.Unmarshal(bs, &v); err != nil {
+		return fmt.Errorf("chatflow: cannot encode variable value: %w", err)
+	}
+	if err := checkVariableValue(valueType, v); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkVariableValue checks that the JSON value v is of the variable type t, unknown
+// types accept any value.
+func checkVariableValue(t string, v interface{}) *VariableTypeError {
+	if v == nil {
+		return nil
+	}
+	var ok bool
+	switch t {
+	case VariableTypeString, VariableTypeSecret:
+		_, ok = v.(string)
+	case VariableTypeNumber:
+		_, ok = v.(float64)
+	case VariableTypeObject:
+		_, ok = v.(map[string]interface{})
+	default:
+		if !strings.HasPrefix(t, "array[") || !strings.HasSuffix(t, "]") {
+			return nil
+		}
+		items, isArray := v.([]interface{})
+		if !isArray {
+			break
+		}
+		for _, item := range items {
+			if item == nil || checkVariableValue(t[len("array["):len(t)-1], item) != nil {
+				return &VariableTypeError{ValueType: t, Value: "array of " + jsonKind(item)}
+			}
+		}
+		ok = true
+	}
+	if !ok {
+		return &VariableTypeError{ValueType: t, Value: jsonKind(v)}
+	}
+	return nil
+}
+
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+type GetConversationVariablesRequest struct {
+	ConversationId string
+	// User identifier, consistent with the send message call.
+	User string
+	// ID of the last variable of the previous page, empty for the first page.
+	LastId string
+	// Variables per page, 20 by default, at most 100.
+	Limit int
+	// Only return the variable with this name.
+	VariableName string
+}
+
+type GetConversationVariablesResponse struct {
+	Limit   int                    `json:"limit"`
+	HasMore bool                   `json:"has_more"`
+	Data    []ConversationVariable `json:"data"`
+}
+
+func (r *GetConversationVariablesResponse) String() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+func (r *GetConversationVariablesResponse) MarshalIndent() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+// GetConversationVariables returns a page of the variables of a conversation.
+func (c *chatflowClient) GetConversationVariables(ctx context.Context, req *GetConversationVariablesRequest) (*GetConversationVariablesResponse, error) {
+	if req == nil || req.ConversationId == "" {
+		return nil, fmt.Errorf("conversation_id is required")
+	}
+	// %s={conversation_id}
+	apiUrl := fmt.Sprintf("/conversations/%s/variables", req.ConversationId)
+	r, err := c.CreateBaseRequest(ctx, http.MethodGet, apiUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.URL.Query()
+	query.Set("user", req.User)
+	if req.LastId != "" {
+		query.Set("last_id", req.LastId)
+	}
+	if req.Limit > 0 {
+		query.Set("limit", strconv.FormatInt(int64(req.Limit), 10))
+	}
+	if req.VariableName != "" {
+		query.Set("variable_name", req.VariableName)
+	}
+	r.URL.RawQuery = query.Encode()
+
+	var rsp GetConversationVariablesResponse
+	err = c.SendJSONRequest(r, &rsp)
+	if err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+type UpdateConversationVariableRequest struct {
+	ConversationId string `json:"-"`
+	VariableId     string `json:"-"`
+	// ValueType is the declared type of the variable, see ConversationVariable.ValueType,
+	// Value is checked against it before the request is sent. When empty, the type is
+	// read from the variables of the conversation first.
+	ValueType string `json:"-"`
+	// New value, any value encoding to the JSON of the type, e.g. a struct for an object.
+	Value interface{} `json:"value"`
+	User  string      `json:"user"`
+}
+
+// UpdateConversationVariable sets the value of a conversation variable and returns the
+// updated variable. A value not matching the type of the variable is reported as a
+// *VariableTypeError without sending the update.
+func (c *chatflowClient) UpdateConversationVariable(ctx context.Context, req *UpdateConversationVariableRequest) (*ConversationVariable, error) {
+	if req == nil || req.ConversationId == "" || req.VariableId == "" {
+		return nil, fmt.Errorf("conversation_id and variable_id are required")
+	}
+	valueType := req.ValueType
+	if valueType == "" {
+		v, err := c.findConversationVariable(ctx, req.ConversationId, req.VariableId, req.User)
+		if err != nil {
+			return nil, err
+		}
+		valueType = v.ValueType
+	}
+	if err := CheckVariableValue(valueType, req.Value); err != nil {
+		if e, ok := err.(*VariableTypeError); ok {
+			e.Variable = req.VariableId
+		}
+		return nil, err
+	}
+
+	// %s={conversation_id}, %s={variable_id}
+	apiUrl := fmt.Sprintf("/conversations/%s/variables/%s", req.ConversationId, req.VariableId)
+	r, err := c.CreateBaseRequest(ctx, http.MethodPut, apiUrl, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create base request: %w", err)
+	}
+
+	var rsp ConversationVariable
+	err = c.SendJSONRequest(r, &rsp)
+	if err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+// findConversationVariable pages through the variables of a conversation for the one
+// with id.
+func (c *chatflowClient) findConversationVariable(ctx context.Context, conversationId, id, user string) (*ConversationVariable, error) {
+	req := &GetConversationVariablesRequest{ConversationId: conversationId, User: user, Limit: 100}
+	for {
+		rsp, err := c.GetConversationVariables(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		for i := range rsp.Data {
+			if rsp.Data[i].Id == id {
+				return &rsp.Data[i], nil
+			}
+		}
+		if !rsp.HasMore || len(rsp.Data) == 0 {
+			return nil, fmt.Errorf("conversation variable %s not found", id)
+		}
+		req.LastId = rsp.Data[len(rsp.Data)-1].Id
+	}
+}
@@ -0,0 +1,157 @@
+package chatflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/taadis/dify-sdk-go/difytest"
+)
+
+func newVariablesServer() *difytest.Server {
+	return difytest.NewServer(difytest.WithAPIKey(testApiKey), difytest.WithChatflow(), difytest.WithConversationVariables(
+		difytest.ConversationVariable{Name: "name", ValueType: VariableTypeString, Value: "Ada"},
+		difytest.ConversationVariable{Name: "visits", ValueType: VariableTypeNumber, Value: 3},
+		difytest.ConversationVariable{Name: "profile", ValueType: VariableTypeObject, Value: map[string]interface{}{"lang": "en"}},
+		difytest.ConversationVariable{Name: "cart", ValueType: VariableTypeArrayString, Value: []interface{}{"apple"}},
+		difytest.ConversationVariable{Name: "token", ValueType: VariableTypeSecret, Value: "s3cr3t"},
+	))
+}
+
+func TestGetConversationVariables(t *testing.T) {
+	srv := newVariablesServer()
+	defer srv.Close()
+	ctx := context.Background()
+	c := NewChatflowClient(srv.BaseUrl(), testApiKey)
+
+	conversationId := srv.AddConversation("test-user", "variables")
+	page, err := c.GetConversationVariables(ctx, &GetConversationVariablesRequest{ConversationId: conversationId, User: "test-user", Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !page.HasMore || len(page.Data) != 3 {
+		t.Fatalf("unexpected first page: %s", page.MarshalIndent())
+	}
+	if v := page.Data[0]; v.Name != "name" || v.Value != "Ada" {
+		t.Errorf("unexpected variable: %s", v.MarshalIndent())
+	}
+	if v := page.Data[1]; v.Value != float64(3) {
+		t.Errorf("visits = %#v", v.Value)
+	}
+	var profile struct {
+		Lang string `json:"lang"`
+	}
+	if err := page.Data[2].Decode(&profile); err != nil || profile.Lang != "en" {
+		t.Errorf("profile = %+v, err = %v", profile, err)
+	}
+
+	page, err = c.GetConversationVariables(ctx, &GetConversationVariablesRequest{ConversationId: conversationId, User: "test-user", LastId: page.Data[2].Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.HasMore || len(page.Data) != 2 || page.Data[1].Value != "s3cr3t" {
+		t.Errorf("unexpected last page: %s", page.MarshalIndent())
+	}
+	if cart, ok := page.Data[0].Value.([]interface{}); !ok || len(cart) != 1 || cart[0] != "apple" {
+		t.Errorf("cart = %#v", page.Data[0].Value)
+	}
+
+	page, err = c.GetConversationVariables(ctx, &GetConversationVariablesRequest{ConversationId: conversationId, User: "test-user", VariableName: "cart"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Data) != 1 || page.Data[0].Name != "cart" {
+		t.Errorf("unexpected filtered page: %s", page.MarshalIndent())
+	}
+	if q := srv.AssertRequest(t, http.MethodGet, "/conversations/"+conversationId+"/variables").Query; q.Get("variable_name") != "cart" || q.Get("user") != "test-user" {
+		t.Errorf("query = %v", q)
+	}
+}
+
+func TestConversationVariableUnmarshal(t *testing.T) {
+	// older versions of Dify send the values as strings
+	var v ConversationVariable
+	if err := json.Unmarshal([]byte(`{"id":"v1","name":"cart","value_type":"array[number]","value":"[1, 2]"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if items, ok := v.Value.([]interface{}); !ok || len(items) != 2 || items[1] != float64(2) {
+		t.Errorf("value = %#v", v.Value)
+	}
+	if err := json.Unmarshal([]byte(`{"id":"v2","name":"note","value_type":"string","value":"[1, 2]"}`), &v); err != nil || v.Value != "[1, 2]" {
+		t.Errorf("value = %#v, err = %v", v.Value, err)
+	}
+
+	err := json.Unmarshal([]byte(`{"id":"v3","name":"visits","value_type":"number","value":"many"}`), &v)
+	if err == nil {
+		t.Error("decoded a number variable holding text")
+	}
+	err = json.Unmarshal([]byte(`{"id":"v3","name":"visits","value_type":"number","value":{"a":1}}`), &v)
+	var typeErr *VariableTypeError
+	if !errors.As(err, &typeErr) || typeErr.Variable != "visits" || typeErr.Value != "object" {
+		t.Errorf("err = %v, want a *VariableTypeError", err)
+	}
+}
+
+func TestUpdateConversationVariable(t *testing.T) {
+	srv := newVariablesServer()
+	defer srv.Close()
+	ctx := context.Background()
+	c := NewChatflowClient(srv.BaseUrl(), testApiKey)
+
+	conversationId := srv.AddConversation("test-user", "variables")
+	page, err := c.GetConversationVariables(ctx, &GetConversationVariablesRequest{ConversationId: conversationId, User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	visits, profile, cart := page.Data[1], page.Data[2], page.Data[3]
+
+	v, err := c.UpdateConversationVariable(ctx, &UpdateConversationVariableRequest{
+		ConversationId: conversationId,
+		VariableId:     cart.Id,
+		ValueType:      cart.ValueType,
+		Value:          []string{"apple", "pear"},
+		User:           "test-user",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items, ok := v.Value.([]interface{}); !ok || len(items) != 2 || items[1] != "pear" {
+		t.Errorf("unexpected variable: %s", v.MarshalIndent())
+	}
+	if body := srv.AssertRequest(t, http.MethodPut, "/conversations/"+conversationId+"/variables/"+cart.Id).Map(); body["user"] != "test-user" {
+		t.Errorf("unexpected body %v", body)
+	}
+
+	// the type is looked up when not given
+	type Profile struct {
+		Lang string `json:"lang"`
+	}
+	v, err = c.UpdateConversationVariable(ctx, &UpdateConversationVariableRequest{ConversationId: conversationId, VariableId: profile.Id, Value: Profile{Lang: "fr"}, User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Value.(map[string]interface{})["lang"] != "fr" {
+		t.Errorf("unexpected variable: %s", v.MarshalIndent())
+	}
+
+	n := len(srv.Requests())
+	for _, value := range []interface{}{"4", []int{4}, map[string]int{"n": 4}} {
+		_, err = c.UpdateConversationVariable(ctx, &UpdateConversationVariableRequest{ConversationId: conversationId, VariableId: visits.Id, ValueType: visits.ValueType, Value: value, User: "test-user"})
+		var typeErr *VariableTypeError
+		if !errors.As(err, &typeErr) || typeErr.Variable != visits.Id || typeErr.ValueType != VariableTypeNumber {
+			t.Errorf("value %#v: err = %v, want a *VariableTypeError", value, err)
+		}
+	}
+	_, err = c.UpdateConversationVariable(ctx, &UpdateConversationVariableRequest{ConversationId: conversationId, VariableId: cart.Id, ValueType: cart.ValueType, Value: []interface{}{"apple", 1}, User: "test-user"})
+	if _, ok := err.(*VariableTypeError); !ok {
+		t.Errorf("err = %v, want a *VariableTypeError", err)
+	}
+	if len(srv.Requests()) != n {
+		t.Error("sent an update with a value of the wrong type")
+	}
+	if _, err := c.UpdateConversationVariable(ctx, &UpdateConversationVariableRequest{ConversationId: conversationId, VariableId: visits.Id, ValueType: visits.ValueType, Value: 4, User: "test-user"}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,223 @@
+package chatflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/taadis/dify-sdk-go/client"
+)
+
+// Sort orders of GetConversationsRequest, a leading "-" sorts in descending order.
+const (
+	SortByCreatedAt     = "created_at"
+	SortByCreatedAtDesc = "-created_at"
+	SortByUpdatedAt     = "updated_at"
+	SortByUpdatedAtDesc = "-updated_at"
+)
+
+// Conversation is a conversation of a user.
+type Conversation struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	// Values of the variables of the app for the conversation.
+	Inputs map[string]interface{} `json:"inputs"`
+	// Example: "normal"
+	Status string `json:"status"`
+	// Opening statement of the conversation.
+	Introduction string `json:"introduction"`
+	CreatedAt    int64  `json:"created_at"`
+	UpdatedAt    int64  `json:"updated_at"`
+}
+
+func (r *Conversation) String() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+func (r *Conversation) MarshalIndent() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+type GetConversationsRequest struct {
+	// User identifier, consistent with the send message call.
+	User string
+	// ID of the last conversation of the previous page, empty for the first page.
+	LastId string
+	// Conversations per page, 20 by default, at most 100.
+	Limit int
+	// One of the SortBy constants, SortByUpdatedAtDesc by default.
+	SortBy string
+}
+
+type GetConversationsResponse struct {
+	Limit   int            `json:"limit"`
+	HasMore bool           `json:"has_more"`
+	Data    []Conversation `json:"data"`
+}
+
+func (r *GetConversationsResponse) String() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+func (r *GetConversationsResponse) MarshalIndent() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+// GetConversations returns a page of the conversations of req.User, the next page
+// starts after the last conversation of this one.
+func (c *chatflowClient) GetConversations(ctx context.Context, req *GetConversationsRequest) (*GetConversationsResponse, error) {
+	if req == nil || req.User == "" {
+		return nil, fmt.Errorf("user is required")
+	}
+	r, err := c.CreateBaseRequest(ctx, http.MethodGet, "/conversations", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.URL.Query()
+	query.Set("user", req.User)
+	if req.LastId != "" {
+		query.Set("last_id", req.LastId)
+	}
+	if req.Limit > 0 {
+		query.Set("limit", strconv.FormatInt(int64(req.Limit), 10))
+	}
+	if req.SortBy != "" {
+		query.Set("sort_by", req.SortBy)
+	}
+	r.URL.RawQuery = query.Encode()
+
+	var rsp GetConversationsResponse
+	err = c.SendJSONRequest(r, &rsp)
+	if err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+type RenameConversationRequest struct {
+	ConversationId string `json:"-"`
+	// New name, ignored when AutoGenerate is set.
+	Name string `json:"name,omitempty"`
+	// Let Dify generate the name from the conversation.
+	AutoGenerate bool   `json:"auto_generate"`
+	User         string `json:"user"`
+}
+
+// RenameConversation renames a conversation and returns it with its new name.
+func (c *chatflowClient) RenameConversation(ctx context.Context, req *RenameConversationRequest) (*Conversation, error) {
+	if req == nil || req.ConversationId == "" {
+		return nil, fmt.Errorf("conversation_id is required")
+	}
+	if !req.AutoGenerate && req.Name == "" {
+		return nil, fmt.Errorf("name is required without auto_generate")
+	}
+	// %s={conversation_id}
+	apiUrl := fmt.Sprintf("/conversations/%s/name", req.ConversationId)
+	r, err := c.CreateBaseRequest(ctx, http.MethodPost, apiUrl, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create base request: %w", err)
+	}
+
+	var rsp Conversation
+	err = c.SendJSONRequest(r, &rsp)
+	if err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+type DeleteConversationRequest struct {
+	ConversationId string `json:"-"`
+	User           string `json:"user"`
+}
+
+type DeleteConversationResponse struct {
+	// Example: "success"
+	Result string `json:"result"`
+}
+
+func (r *DeleteConversationResponse) String() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+func (r *DeleteConversationResponse) MarshalIndent() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+// DeleteConversation deletes a conversation with its messages.
+func (c *chatflowClient) DeleteConversation(ctx context.Context, req *DeleteConversationRequest) (*DeleteConversationResponse, error) {
+	if req == nil || req.ConversationId == "" {
+		return nil, fmt.Errorf("conversation_id is required")
+	}
+	// %s={conversation_id}
+	apiUrl := fmt.Sprintf("/conversations/%s", req.ConversationId)
+	r, err := c.CreateBaseRequest(ctx, http.MethodDelete, apiUrl, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create base request: %w", err)
+	}
+
+	rsp, err := c.SendRequest(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer rsp.Body.Close()
+
+	// recent versions of Dify answer 204 without a body
+	if rsp.StatusCode == http.StatusNoContent {
+		return &DeleteConversationResponse{Result: "success"}, nil
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return nil, client.NewAPIError(rsp)
+	}
+
+	var ret DeleteConversationResponse
+	if err := json.NewDecoder(rsp.Body).Decode(&ret); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &ret, nil
+}
@@ -0,0 +1,111 @@
+package chatflow
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/taadis/dify-sdk-go/client"
+	"github.com/taadis/dify-sdk-go/difytest"
+)
+
+func TestConversations(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey), difytest.WithChatflow())
+	defer srv.Close()
+	ctx := context.Background()
+	c := NewChatflowClient(srv.BaseUrl(), testApiKey)
+
+	var ids []string
+	for _, query := range []string{"first question", "second question", "third question"} {
+		rsp, err := c.SendMessage(ctx, &ChatMessageRequest{
+			Query:  query,
+			Inputs: map[string]interface{}{"query": query, "count": 3, "tags": []interface{}{"a"}},
+			User:   "test-user",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, rsp.ConversationId)
+	}
+
+	page, err := c.GetConversations(ctx, &GetConversationsRequest{User: "test-user", Limit: 2, SortBy: SortByCreatedAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !page.HasMore || len(page.Data) != 2 || page.Data[0].Id != ids[0] || page.Data[1].Id != ids[1] {
+		t.Fatalf("unexpected first page: %s", page.MarshalIndent())
+	}
+	conv := page.Data[0]
+	if conv.Name != "first question" || conv.Introduction == "" || conv.UpdatedAt == 0 || conv.Inputs["count"] != float64(3) {
+		t.Errorf("unexpected conversation: %s", conv.MarshalIndent())
+	}
+	if q := srv.AssertRequest(t, http.MethodGet, "/conversations").Query; q.Get("sort_by") != SortByCreatedAt || q.Get("limit") != "2" {
+		t.Errorf("query = %v", q)
+	}
+	page, err = c.GetConversations(ctx, &GetConversationsRequest{User: "test-user", LastId: page.Data[1].Id, SortBy: SortByCreatedAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.HasMore || len(page.Data) != 1 || page.Data[0].Id != ids[2] {
+		t.Errorf("unexpected last page: %s", page.MarshalIndent())
+	}
+
+	renamed, err := c.RenameConversation(ctx, &RenameConversationRequest{ConversationId: ids[0], Name: "Renamed", User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.Id != ids[0] || renamed.Name != "Renamed" {
+		t.Errorf("unexpected renamed conversation: %s", renamed.MarshalIndent())
+	}
+	renamed, err = c.RenameConversation(ctx, &RenameConversationRequest{ConversationId: ids[0], AutoGenerate: true, User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.Name != "first question" {
+		t.Errorf("generated name = %q", renamed.Name)
+	}
+	if body := srv.AssertRequest(t, http.MethodPost, "/conversations/"+ids[0]+"/name").Map(); body["auto_generate"] != true {
+		t.Errorf("unexpected body %v", body)
+	}
+	if _, err := c.RenameConversation(ctx, &RenameConversationRequest{ConversationId: ids[0], User: "test-user"}); err == nil {
+		t.Error("renamed a conversation without a name")
+	}
+
+	rsp, err := c.DeleteConversation(ctx, &DeleteConversationRequest{ConversationId: ids[1], User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Result != "success" {
+		t.Errorf("unexpected response: %s", rsp.MarshalIndent())
+	}
+	if body := srv.AssertRequest(t, http.MethodDelete, "/conversations/"+ids[1]).Map(); body["user"] != "test-user" {
+		t.Errorf("unexpected body %v", body)
+	}
+	if _, err := c.DeleteConversation(ctx, &DeleteConversationRequest{ConversationId: ids[1], User: "test-user"}); !client.IsNotFound(err) {
+		t.Errorf("err = %v, want a not found error", err)
+	}
+	page, err = c.GetConversations(ctx, &GetConversationsRequest{User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Data) != 2 || page.Data[0].Id == ids[1] || page.Data[1].Id == ids[1] {
+		t.Errorf("unexpected conversations after delete: %s", page.MarshalIndent())
+	}
+}
+
+func TestDeleteConversationNoContent(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey))
+	defer srv.Close()
+	srv.Handle(http.MethodDelete, "/conversations/{conversation_id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	c := NewChatflowClient(srv.BaseUrl(), testApiKey)
+
+	rsp, err := c.DeleteConversation(context.Background(), &DeleteConversationRequest{ConversationId: "c1", User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Result != "success" {
+		t.Errorf("unexpected response: %s", rsp.MarshalIndent())
+	}
+}
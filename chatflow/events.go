@@ -0,0 +1,136 @@
+package chatflow
+
+import (
+	"encoding/json"
+
+	"github.com/taadis/dify-sdk-go/client"
+	"github.com/taadis/dify-sdk-go/workflow"
+)
+
+// Message stream event types, the other events of a chatflow stream are the workflow
+// ones, see the workflow package.
+const (
+	EventMessage        = "message"
+	EventMessageFile    = "message_file"
+	EventMessageEnd     = "message_end"
+	EventMessageReplace = "message_replace"
+)
+
+// Event is a chatflow stream event. Its dynamic type is one of the *...Event types of
+// this package for the message events, of the workflow package otherwise.
+type Event = workflow.Event
+
+// EventBase holds the fields shared by the message events.
+type EventBase struct {
+	Event          string `json:"event"`
+	TaskId         string `json:"task_id"`
+	MessageId      string `json:"message_id"`
+	ConversationId string `json:"conversation_id"`
+	CreatedAt      int64  `json:"created_at"`
+}
+
+func (e *EventBase) EventType() string {
+	return e.Event
+}
+
+// Metadata is the usage and the citations of a message.
+type Metadata struct {
+	Usage              client.Usage               `json:"usage"`
+	RetrieverResources []client.RetrieverResource `json:"retriever_resources,omitempty"`
+}
+
+// MessageEvent is a chunk of the answer.
+type MessageEvent struct {
+	EventBase
+	// Message ID
+	Id     string `json:"id"`
+	Answer string `json:"answer"`
+}
+
+// MessageFileEvent is a file generated by a tool.
+type MessageFileEvent struct {
+	EventBase
+	// File ID
+	Id   string `json:"id"`
+	Type string `json:"type"`
+	// Only "assistant"
+	BelongsTo string `json:"belongs_to"`
+	Url       string `json:"url"`
+}
+
+// MessageEndEvent ends the message with its usage.
+type MessageEndEvent struct {
+	EventBase
+	// Message ID
+	Id       string   `json:"id"`
+	Metadata Metadata `json:"metadata"`
+}
+
+// MessageReplaceEvent replaces the whole answer, e.g. after content moderation.
+type MessageReplaceEvent struct {
+	EventBase
+	Answer string `json:"answer"`
+}
+
+// DecodeEvent decodes the JSON data of a chatflow stream event.
+func DecodeEvent(data []byte) (Event, error) {
+	var base EventBase
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, err
+	}
+
+	var e Event
+	switch base.Event {
+	case EventMessage:
+		e = new(MessageEvent)
+	case EventMessageFile:
+		e = new(MessageFileEvent)
+	case EventMessageEnd:
+		e = new(MessageEndEvent)
+	case EventMessageReplace:
+		e = new(MessageReplaceEvent)
+	default:
+		return workflow.DecodeEvent(data)
+	}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// EventHandler receives the events of a chatflow stream, the workflow events through
+// the methods of workflow.EventHandler. Embed BaseEventHandler to only implement the
+// methods of interest.
+type EventHandler interface {
+	workflow.EventHandler
+	OnMessage(e *MessageEvent)
+	OnMessageFile(e *MessageFileEvent)
+	OnMessageEnd(e *MessageEndEvent)
+	OnMessageReplace(e *MessageReplaceEvent)
+}
+
+// BaseEventHandler implements EventHandler by ignoring every event.
+type BaseEventHandler struct {
+	workflow.BaseEventHandler
+}
+
+func (BaseEventHandler) OnMessage(e *MessageEvent)               {}
+func (BaseEventHandler) OnMessageFile(e *MessageFileEvent)       {}
+func (BaseEventHandler) OnMessageEnd(e *MessageEndEvent)         {}
+func (BaseEventHandler) OnMessageReplace(e *MessageReplaceEvent) {}
+
+// DispatchEvent calls the method of h matching the type of e.
+func DispatchEvent(h EventHandler, e Event) {
+	switch e := e.(type) {
+	case *MessageEvent:
+		h.OnMessage(e)
+	case *MessageFileEvent:
+		h.OnMessageFile(e)
+	case *MessageEndEvent:
+		h.OnMessageEnd(e)
+	case *MessageReplaceEvent:
+		h.OnMessageReplace(e)
+	default:
+		workflow.DispatchEvent(h, e)
+	}
+}
@@ -0,0 +1,178 @@
+package chatflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/taadis/dify-sdk-go/client"
+)
+
+// Message is a message of the history of a conversation.
+type Message struct {
+	Id             string `json:"id"`
+	ConversationId string `json:"conversation_id"`
+	// ParentMessageId links the messages of a conversation into a tree, a regenerated
+	// answer being a sibling of the original one. Empty for the first message.
+	ParentMessageId string                 `json:"parent_message_id"`
+	Inputs          map[string]interface{} `json:"inputs"`
+	Query           string                 `json:"query"`
+	Answer          string                 `json:"answer"`
+	MessageFiles    []MessageFile          `json:"message_files"`
+	// Nil without feedback.
+	Feedback           *Feedback                  `json:"feedback"`
+	RetrieverResources []client.RetrieverResource `json:"retriever_resources"`
+	AgentThoughts      []map[string]interface{}   `json:"agent_thoughts,omitempty"`
+	// Example: "normal", "error"
+	Status    string `json:"status"`
+	Error     string `json:"error"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// MessageFile is a file of a message.
+type MessageFile struct {
+	Id   string `json:"id"`
+	Type string `json:"type"`
+	Url  string `json:"url"`
+	// "user" or "assistant"
+	BelongsTo string `json:"belongs_to"`
+}
+
+// Feedback is the rating of a message.
+type Feedback struct {
+	// "like" or "dislike"
+	Rating string `json:"rating"`
+}
+
+type GetMessagesRequest struct {
+	ConversationId string
+	// User identifier, consistent with the send message call.
+	User string
+	// ID of the first message of the page read last, to page backwards; empty for the
+	// most recent messages.
+	FirstId string
+	// Messages per page, 20 by default, at most 100.
+	Limit int
+}
+
+type GetMessagesResponse struct {
+	Limit int `json:"limit"`
+	// HasMore reports older messages before Data.
+	HasMore bool `json:"has_more"`
+	// In chronological order.
+	Data []Message `json:"data"`
+}
+
+func (r *GetMessagesResponse) String() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+func (r *GetMessagesResponse) MarshalIndent() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+// GetMessages returns a page of the history of a conversation, in chronological order.
+// The first page holds the most recent messages, set FirstId to the ID of the first
+// message of a page to get the older messages before it.
+func (c *chatflowClient) GetMessages(ctx context.Context, req *GetMessagesRequest) (*GetMessagesResponse, error) {
+	if req == nil || req.ConversationId == "" {
+		return nil, fmt.Errorf("conversation_id is required")
+	}
+	r, err := c.CreateBaseRequest(ctx, http.MethodGet, "/messages", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.URL.Query()
+	query.Set("conversation_id", req.ConversationId)
+	query.Set("user", req.User)
+	if req.FirstId != "" {
+		query.Set("first_id", req.FirstId)
+	}
+	if req.Limit > 0 {
+		query.Set("limit", strconv.FormatInt(int64(req.Limit), 10))
+	}
+	r.URL.RawQuery = query.Encode()
+
+	var rsp GetMessagesResponse
+	err = c.SendJSONRequest(r, &rsp)
+	if err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
+
+type GetSuggestedRequest struct {
+	MessageId string
+	// User identifier, consistent with the send message call.
+	User string
+}
+
+type GetSuggestedResponse struct {
+	// Example: "success"
+	Result string   `json:"result"`
+	Data   []string `json:"data"`
+}
+
+func (r *GetSuggestedResponse) String() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+func (r *GetSuggestedResponse) MarshalIndent() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+// GetSuggested returns the questions suggested after the answer of a message. Apps
+// without suggested questions answer with a *client.APIError.
+func (c *chatflowClient) GetSuggested(ctx context.Context, req *GetSuggestedRequest) (*GetSuggestedResponse, error) {
+	if req == nil || req.MessageId == "" {
+		return nil, fmt.Errorf("message_id is required")
+	}
+	// %s={message_id}
+	apiUrl := fmt.Sprintf("/messages/%s/suggested", req.MessageId)
+	r, err := c.CreateBaseRequest(ctx, http.MethodGet, apiUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.URL.Query()
+	query.Set("user", req.User)
+	r.URL.RawQuery = query.Encode()
+
+	var rsp GetSuggestedResponse
+	err = c.SendJSONRequest(r, &rsp)
+	if err != nil {
+		return nil, err
+	}
+	return &rsp, nil
+}
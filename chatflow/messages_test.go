@@ -0,0 +1,119 @@
+package chatflow
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/taadis/dify-sdk-go/client"
+	"github.com/taadis/dify-sdk-go/difytest"
+)
+
+func TestGetMessages(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey), difytest.WithChatflow())
+	defer srv.Close()
+	ctx := context.Background()
+	c := NewChatflowClient(srv.BaseUrl(), testApiKey)
+
+	var conversationId string
+	var ids []string
+	for _, query := range []string{"one", "two", "three"} {
+		rsp, err := c.SendMessage(ctx, &ChatMessageRequest{Query: query, User: "test-user", ConversationId: conversationId})
+		if err != nil {
+			t.Fatal(err)
+		}
+		conversationId = rsp.ConversationId
+		ids = append(ids, rsp.MessageId)
+	}
+
+	page, err := c.GetMessages(ctx, &GetMessagesRequest{ConversationId: conversationId, User: "test-user", Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !page.HasMore || len(page.Data) != 2 || page.Data[0].Id != ids[1] || page.Data[1].Query != "three" {
+		t.Fatalf("unexpected latest page: %s", page.MarshalIndent())
+	}
+	if m := page.Data[1]; m.ConversationId != conversationId || m.Answer == "" || m.Feedback != nil || m.Status != "normal" {
+		t.Errorf("unexpected message %+v", m)
+	}
+	page, err = c.GetMessages(ctx, &GetMessagesRequest{ConversationId: conversationId, User: "test-user", FirstId: page.Data[0].Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.HasMore || len(page.Data) != 1 || page.Data[0].Id != ids[0] {
+		t.Errorf("unexpected previous page: %s", page.MarshalIndent())
+	}
+	if _, err := c.GetMessages(ctx, &GetMessagesRequest{User: "test-user"}); err == nil {
+		t.Error("got messages without a conversation_id")
+	}
+}
+
+func TestRegenerateFromParent(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey), difytest.WithChatflow())
+	defer srv.Close()
+	ctx := context.Background()
+	c := NewChatflowClient(srv.BaseUrl(), testApiKey)
+
+	first, err := c.SendMessage(ctx, &ChatMessageRequest{Query: "one", User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.SendMessage(ctx, &ChatMessageRequest{Query: "two", User: "test-user", ConversationId: first.ConversationId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// answer "two" again, as a sibling of the second message
+	retry, err := c.SendMessage(ctx, &ChatMessageRequest{Query: "two", User: "test-user", ConversationId: first.ConversationId, ParentMessageId: first.MessageId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := srv.AssertRequest(t, http.MethodPost, "/chat-messages").Map(); body["parent_message_id"] != first.MessageId {
+		t.Errorf("unexpected body %v", body)
+	}
+
+	page, err := c.GetMessages(ctx, &GetMessagesRequest{ConversationId: first.ConversationId, User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parents := make(map[string]string)
+	for _, m := range page.Data {
+		parents[m.Id] = m.ParentMessageId
+	}
+	if len(parents) != 3 || parents[first.MessageId] != "" || parents[second.MessageId] != first.MessageId || parents[retry.MessageId] != first.MessageId {
+		t.Errorf("parents = %v", parents)
+	}
+}
+
+func TestGetSuggested(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey), difytest.WithChatflow(), difytest.WithSuggestedQuestions("Why?", "How?"))
+	defer srv.Close()
+	ctx := context.Background()
+	c := NewChatflowClient(srv.BaseUrl(), testApiKey)
+
+	msg, err := c.SendMessage(ctx, &ChatMessageRequest{Query: "hello", User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp, err := c.GetSuggested(ctx, &GetSuggestedRequest{MessageId: msg.MessageId, User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Result != "success" || len(rsp.Data) != 2 || rsp.Data[0] != "Why?" {
+		t.Errorf("unexpected response: %s", rsp.MarshalIndent())
+	}
+	if q := srv.AssertRequest(t, http.MethodGet, "/messages/"+msg.MessageId+"/suggested").Query; q.Get("user") != "test-user" {
+		t.Errorf("query = %v", q)
+	}
+
+	// disabled by default
+	c = NewChatflowClient(testBaseUrl, testApiKey)
+	msg, err = c.SendMessage(ctx, &ChatMessageRequest{Query: "hello", User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetSuggested(ctx, &GetSuggestedRequest{MessageId: msg.MessageId, User: "test-user"}); err == nil {
+		t.Error("got suggested questions from an app without them")
+	} else if e, ok := client.AsAPIError(err); !ok || e.StatusCode != http.StatusBadRequest {
+		t.Errorf("err = %v, want a 400 *client.APIError", err)
+	}
+}
@@ -0,0 +1,300 @@
+package chatflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/taadis/dify-sdk-go/client"
+	"github.com/taadis/dify-sdk-go/workflow"
+)
+
+type ChatMessageRequest struct {
+	// User input/question content.
+	Query string `json:"query"`
+	// Values of the variables defined by the app, nil is sent as an empty object.
+	Inputs map[string]interface{} `json:"inputs"`
+	// Set by SendMessage and SendMessageStream, any value given here is ignored.
+	ResponseMode string `json:"response_mode"`
+	// User identifier, unique within the application.
+	User string `json:"user"`
+	// Conversation ID to continue, see ChatMessageResponse.ConversationId. Empty to
+	// start a new conversation.
+	ConversationId string `json:"conversation_id,omitempty"`
+	// ParentMessageId answers the query again as a sibling branch of the messages
+	// following this one, e.g. to regenerate an answer. Empty to follow the last
+	// message of the conversation.
+	ParentMessageId string               `json:"parent_message_id,omitempty"`
+	Files           []workflow.FileInput `json:"files,omitempty"`
+	// Generate the name of a new conversation, true by default.
+	AutoGenerateName *bool `json:"auto_generate_name,omitempty"`
+}
+
+type ChatMessageResponse struct {
+	// Always "message"
+	Event          string `json:"event"`
+	TaskId         string `json:"task_id"`
+	Id             string `json:"id"`
+	MessageId      string `json:"message_id"`
+	ConversationId string `json:"conversation_id"`
+	// Always "advanced-chat"
+	Mode      string   `json:"mode"`
+	Answer    string   `json:"answer"`
+	Metadata  Metadata `json:"metadata"`
+	CreatedAt int64    `json:"created_at"`
+}
+
+func (r *ChatMessageResponse) String() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+func (r *ChatMessageResponse) MarshalIndent() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+// SendMessage sends a chat message in blocking mode, req.ResponseMode is ignored.
+func (c *chatflowClient) SendMessage(ctx context.Context, req *ChatMessageRequest) (*ChatMessageResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request is required")
+	}
+	r, err := c.CreateBaseRequest(ctx, http.MethodPost, "/chat-messages", messageBody(req, workflow.ResponseModeBlocking))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create base request: %w", err)
+	}
+
+	rsp, err := c.SendRequest(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return nil, client.NewAPIError(rsp)
+	}
+
+	var ret ChatMessageResponse
+	if err := json.NewDecoder(rsp.Body).Decode(&ret); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &ret, nil
+}
+
+func messageBody(req *ChatMessageRequest, mode string) *ChatMessageRequest {
+	body := *req
+	body.ResponseMode = mode
+	if body.Inputs == nil {
+		body.Inputs = map[string]interface{}{}
+	}
+	return &body
+}
+
+// Stream iterates over the events of a streaming chat message, the message events of
+// this package and the workflow and node events of the workflow package:
+//
+//	stream, err := c.SendMessageStream(ctx, req)
+//	if err != nil {
+//		return err
+//	}
+//	defer stream.Close()
+//	for stream.Next() {
+//		switch e := stream.Current().(type) {
+//		case *workflow.NodeStartedEvent:
+//			fmt.Println("running", e.Data.Title)
+//		case *MessageEvent:
+//			fmt.Print(e.Answer)
+//		}
+//	}
+//	if err := stream.Err(); err != nil {
+//		return err
+//	}
+//	next.ConversationId = stream.ConversationId()
+//
+// Events are decoded by DecodeEvent, keep-alive pings are skipped unless KeepPings is
+// called. The response body
+// is closed at the end of the stream, after an error event, by Close, or when ctx is done.
+type Stream struct {
+	stream         *client.EventStream
+	current        Event
+	err            error
+	done           bool
+	answer         strings.Builder
+	taskId         string
+	messageId      string
+	conversationId string
+	metadata       *Metadata
+}
+
+// NewStream returns a Stream decoding the events of stream.
+func NewStream(stream *client.EventStream) *Stream {
+	return &Stream{stream: stream}
+}
+
+// KeepPings makes Next return the keep-alive pings as *workflow.PingEvent, from the
+// next event on.
+func (s *Stream) KeepPings() {
+	s.stream.KeepPings()
+}
+
+// Next advances to the next event, it returns false when the stream is over.
+// After an error event, Current returns the *workflow.ErrorEvent and Err its
+// *client.APIError.
+func (s *Stream) Next() bool {
+	if s.done {
+		return false
+	}
+	if !s.stream.Next() {
+		s.done = true
+		return false
+	}
+
+	e, err := DecodeEvent(s.stream.Data())
+	if err != nil {
+		s.err = fmt.Errorf("error decoding %s event: %w", s.stream.Event(), err)
+		s.Close()
+		return false
+	}
+	s.current = e
+	if s.taskId == "" {
+		s.taskId = s.stream.TaskId()
+	}
+	switch e := e.(type) {
+	case *MessageEvent:
+		s.answer.WriteString(e.Answer)
+		s.setIds(&e.EventBase)
+	case *MessageReplaceEvent:
+		s.answer.Reset()
+		s.answer.WriteString(e.Answer)
+		s.setIds(&e.EventBase)
+	case *MessageFileEvent:
+		s.setIds(&e.EventBase)
+	case *MessageEndEvent:
+		s.setIds(&e.EventBase)
+		s.metadata = &e.Metadata
+	case *workflow.ErrorEvent:
+		s.err = client.StreamEventError(s.stream.Data())
+		s.stream.End()
+		return false
+	}
+	return true
+}
+
+func (s *Stream) setIds(e *EventBase) {
+	if e.MessageId != "" {
+		s.messageId = e.MessageId
+	}
+	if e.ConversationId != "" {
+		s.conversationId = e.ConversationId
+	}
+}
+
+// Current returns the current event.
+func (s *Stream) Current() Event {
+	return s.current
+}
+
+// Data returns the JSON data of the current event.
+func (s *Stream) Data() []byte {
+	return s.stream.Data()
+}
+
+// Answer returns the answer streamed so far.
+func (s *Stream) Answer() string {
+	return s.answer.String()
+}
+
+// TaskId returns the task ID of the stream, e.g. to Stop it.
+func (s *Stream) TaskId() string {
+	return s.taskId
+}
+
+// MessageId returns the ID of the message, once its first event is read.
+func (s *Stream) MessageId() string {
+	return s.messageId
+}
+
+// ConversationId returns the ID of the conversation of the message, to continue it
+// with the next message.
+func (s *Stream) ConversationId() string {
+	return s.conversationId
+}
+
+// Metadata returns the usage of the message, nil until message_end is read.
+func (s *Stream) Metadata() *Metadata {
+	return s.metadata
+}
+
+// Err returns the error that ended the stream, nil for a complete stream.
+func (s *Stream) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.stream.Err()
+}
+
+// Close ends the stream and releases the connection, it may be called from another goroutine.
+func (s *Stream) Close() error {
+	return s.stream.Close()
+}
+
+// SendMessageStream sends a chat message in streaming mode, req.ResponseMode is ignored.
+// Error responses are returned as a *client.APIError.
+func (c *chatflowClient) SendMessageStream(ctx context.Context, req *ChatMessageRequest) (*Stream, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request is required")
+	}
+	r, err := c.CreateBaseRequest(ctx, http.MethodPost, "/chat-messages", messageBody(req, workflow.ResponseModeStreaming))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create base request: %w", err)
+	}
+
+	stream, err := c.SendStoppableStreamRequest(r, func(ctx context.Context, taskId string) error {
+		_, err := c.Stop(ctx, &StopRequest{TaskId: taskId, User: req.User})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewStream(stream), nil
+}
+
+// SendMessageStreamWithHandler sends a chat message in streaming mode and calls the
+// method of h matching each event. An error event is passed to OnError, then returned
+// as a *client.APIError.
+func (c *chatflowClient) SendMessageStreamWithHandler(ctx context.Context, req *ChatMessageRequest, h EventHandler) error {
+	stream, err := c.SendMessageStream(ctx, req)
+	if err != nil {
+		return err
+	}
+	return HandleStream(stream, h)
+}
+
+// HandleStream reads stream to the end, dispatching each event to h, pings included,
+// then closes it.
+func HandleStream(stream *Stream, h EventHandler) error {
+	defer stream.Close()
+	stream.KeepPings()
+
+	for stream.Next() {
+		DispatchEvent(h, stream.Current())
+	}
+	if e, ok := stream.Current().(*workflow.ErrorEvent); ok {
+		h.OnError(e)
+	}
+	return stream.Err()
+}
@@ -0,0 +1,137 @@
+package chatflow
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/taadis/dify-sdk-go/client"
+	"github.com/taadis/dify-sdk-go/workflow"
+)
+
+func TestSendMessage(t *testing.T) {
+	ctx := context.Background()
+	c := NewChatflowClient(testBaseUrl, testApiKey)
+
+	rsp, err := c.SendMessage(ctx, &ChatMessageRequest{Query: "hello", User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Mode != "advanced-chat" || rsp.Answer == "" || rsp.ConversationId == "" || rsp.MessageId == "" {
+		t.Errorf("unexpected response: %s", rsp.MarshalIndent())
+	}
+	if rsp.Metadata.Usage.TotalTokens == 0 {
+		t.Errorf("usage = %+v", rsp.Metadata.Usage)
+	}
+	body := testServer.AssertRequest(t, http.MethodPost, "/chat-messages").Map()
+	if body["response_mode"] != workflow.ResponseModeBlocking || body["inputs"] == nil {
+		t.Errorf("unexpected body %v", body)
+	}
+}
+
+func TestSendMessageStream(t *testing.T) {
+	ctx := context.Background()
+	c := NewChatflowClient(testBaseUrl, testApiKey)
+
+	stream, err := c.SendMessageStream(ctx, &ChatMessageRequest{Query: "hello stream", User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var (
+		events []string
+		nodes  []string
+		answer string
+	)
+	for stream.Next() {
+		events = append(events, stream.Current().EventType())
+		switch e := stream.Current().(type) {
+		case *workflow.NodeStartedEvent:
+			nodes = append(nodes, e.Data.NodeType)
+		case *MessageEvent:
+			answer += e.Answer
+			if e.ConversationId == "" || e.MessageId == "" {
+				t.Errorf("message without ids %+v", e)
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) == 0 || events[0] != workflow.EventWorkflowStarted || events[len(events)-1] != EventMessageEnd {
+		t.Errorf("events = %v", events)
+	}
+	if len(nodes) != 3 || nodes[1] != "llm" {
+		t.Errorf("nodes = %v", nodes)
+	}
+	if answer == "" || stream.Answer() != answer {
+		t.Errorf("answer = %q, stream.Answer() = %q", answer, stream.Answer())
+	}
+	if stream.TaskId() == "" || stream.MessageId() == "" || stream.ConversationId() == "" {
+		t.Errorf("task_id = %q, message_id = %q, conversation_id = %q", stream.TaskId(), stream.MessageId(), stream.ConversationId())
+	}
+	if m := stream.Metadata(); m == nil || m.Usage.TotalTokens == 0 {
+		t.Errorf("metadata = %+v", m)
+	}
+
+	// continue the conversation
+	rsp, err := c.SendMessage(ctx, &ChatMessageRequest{Query: "and then?", User: "test-user", ConversationId: stream.ConversationId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.ConversationId != stream.ConversationId() {
+		t.Errorf("conversation_id = %q, want %q", rsp.ConversationId, stream.ConversationId())
+	}
+	body := testServer.AssertRequest(t, http.MethodPost, "/chat-messages").Map()
+	if body["conversation_id"] != stream.ConversationId() {
+		t.Errorf("unexpected body %v", body)
+	}
+}
+
+type recordingHandler struct {
+	BaseEventHandler
+	pings  int
+	nodes  int
+	answer string
+	end    *MessageEndEvent
+}
+
+func (h *recordingHandler) OnPing(e *workflow.PingEvent) {
+	h.pings++
+}
+
+func (h *recordingHandler) OnNodeFinished(e *workflow.NodeFinishedEvent) {
+	h.nodes++
+}
+
+func (h *recordingHandler) OnMessage(e *MessageEvent) {
+	h.answer += e.Answer
+}
+
+func (h *recordingHandler) OnMessageEnd(e *MessageEndEvent) {
+	h.end = e
+}
+
+func TestSendMessageStreamWithHandler(t *testing.T) {
+	ctx := context.Background()
+	c := NewChatflowClient(testBaseUrl, testApiKey)
+
+	h := new(recordingHandler)
+	if err := c.SendMessageStreamWithHandler(ctx, &ChatMessageRequest{Query: "hello handler", User: "test-user"}, h); err != nil {
+		t.Fatal(err)
+	}
+	if h.pings != 1 || h.nodes != 3 || h.answer == "" || h.end == nil || h.end.Metadata.Usage.TotalTokens == 0 {
+		t.Errorf("pings = %d, nodes = %d, answer = %q, message_end = %+v", h.pings, h.nodes, h.answer, h.end)
+	}
+}
+
+func TestSendMessageNotFound(t *testing.T) {
+	ctx := context.Background()
+	c := NewChatflowClient(testBaseUrl, testApiKey)
+
+	_, err := c.SendMessageStream(ctx, &ChatMessageRequest{Query: "hello", User: "test-user", ConversationId: "missing"})
+	if !client.IsNotFound(err) {
+		t.Errorf("err = %v, want a not found error", err)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+
+	"github.com/taadis/dify-sdk-go/client"
 )
 
 type StopRequest struct {
@@ -50,7 +52,7 @@ func (c *chatflowClient) Stop(ctx context.Context, req *StopRequest) (*StopRespo
 	}
 	// %s={task_id}
 	apiUrl := fmt.Sprintf("/chat-messages/%s/stop", req.TaskId)
-	r, err := c.CreateBaseRequest(ctx, http.MethodPost, apiUrl, req)
+	r, err := c.CreateBaseRequest(client.WithIdempotent(ctx), http.MethodPost, apiUrl, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create base request: %w", err)
 	}
@@ -62,7 +64,7 @@ func (c *chatflowClient) Stop(ctx context.Context, req *StopRequest) (*StopRespo
 	defer rsp.Body.Close()
 
 	if rsp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %s: %s", rsp.Status, c.ReadResponseBody(rsp.Body))
+		return nil, client.NewAPIError(rsp)
 	}
 
 	var ret StopResponse
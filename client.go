@@ -1,12 +1,10 @@
 package dify
 
 import (
-	"encoding/json"
-	"fmt"
 	"net/http"
 	"strings"
 
-	"github.com/taadis/http2curl"
+	"github.com/taadis/dify-sdk-go/client"
 )
 
 // from https://docs.dify.ai/en/openapi-api-access-readme#%F0%9F%94%91-api-access-configuration
@@ -15,27 +13,30 @@ var DifyCloud = "https://api.dify.ai"
 type Client struct {
 	host             string
 	defaultAPISecret string
-	httpClient       *http.Client
+	core             *client.Client
 }
 
 func NewClientWithConfig(c *ClientConfig) *Client {
-	var httpClient = &http.Client{}
-
-	if c.Timeout != 0 {
-		httpClient.Timeout = c.Timeout
-	}
-	if c.Transport != nil {
-		httpClient.Transport = c.Transport
-	}
-
 	secret := c.DefaultAPISecret
 	if secret == "" {
 		secret = c.ApiSecretKey
 	}
+	core := client.NewClientWithConfig(&client.ClientConfig{
+		BaseUrl:     c.Host,
+		ApiKey:      secret,
+		Timeout:     c.Timeout,
+		Transport:   c.Transport,
+		Logger:      c.Logger,
+		Debug:       c.Debug,
+		Retry:       c.Retry,
+		Middlewares: c.Middlewares,
+		AutoStop:    c.AutoStop,
+		Budget:      c.Budget,
+	})
 	return &Client{
 		host:             c.Host,
 		defaultAPISecret: secret,
-		httpClient:       httpClient,
+		core:             core,
 	}
 }
 
@@ -47,41 +48,15 @@ func NewClient(host, defaultAPISecret string) *Client {
 }
 
 func (c *Client) sendRequest(req *http.Request) (*http.Response, error) {
-
-	curlcmd, err := http2curl.GetCurlCommand(req)
-	if err != nil {
-		return nil, err
-	}
-	fmt.Println(curlcmd.String())
-
-	return c.httpClient.Do(req)
+	return c.core.SendRequest(req)
 }
 
 func (c *Client) sendJSONRequest(req *http.Request, res interface{}) error {
-	resp, err := c.sendRequest(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		var errBody struct {
-			Code    string `json:"code"`
-			Message string `json:"message"`
-			Status  int    `json:"status"`
-		}
-		err = json.NewDecoder(resp.Body).Decode(&errBody)
-		if err != nil {
-			return err
-		}
-		return fmt.Errorf("HTTP response error: [%v]%v", errBody.Code, errBody.Message)
-	}
+	return c.core.SendJSONRequest(req, res)
+}
 
-	err = json.NewDecoder(resp.Body).Decode(res)
-	if err != nil {
-		return err
-	}
-	return nil
+func (c *Client) sendStoppableStreamRequest(req *http.Request, stop client.StopFunc) (*client.EventStream, error) {
+	return c.core.SendStoppableStreamRequest(req, stop)
 }
 
 func (c *Client) getHost() string {
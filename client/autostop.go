@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// AutoStop stops the server-side task of a stream when the context of the call is
+// canceled, so that Dify does not keep generating, and billing, an answer nobody reads.
+//
+// The task ID is taken from the first event that carries one, a stream canceled before
+// that cannot be stopped. Streams that end, or are closed, before the context is
+// canceled are left alone.
+type AutoStop struct {
+	// Timeout of the stop call, which runs detached from the canceled context. 5s by default.
+	Timeout time.Duration
+	// OnStop, when set, is called with the outcome of every stop call, from its own goroutine.
+	OnStop func(StopResult)
+}
+
+// StopResult is the outcome of a stop call made by AutoStop.
+type StopResult struct {
+	// Task that was stopped.
+	TaskId string
+	// Err is nil when the task was stopped.
+	Err error
+}
+
+// StopFunc stops the task of a stream, e.g. by calling /chat-messages/{task_id}/stop.
+type StopFunc func(ctx context.Context, taskId string) error
+
+// SendStoppableStreamRequest sends a streaming request like SendStreamRequest. When the
+// client was configured with AutoStop, stop is called if the context of req is canceled
+// before the end of the stream; with a Budget, it is called when the stream exceeds a
+// limit.
+func (c *Client) SendStoppableStreamRequest(req *http.Request, stop StopFunc) (*EventStream, error) {
+	var meter *budgetMeter
+	if c.budget != nil {
+		var err error
+		if meter, err = c.budget.meter(req); err != nil {
+			return nil, err
+		}
+	}
+	stream, err := c.SendStreamRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if stop != nil {
+		stopTask := func(taskId string) {
+			c.stopTask(req.Context(), taskId, stop)
+		}
+		if c.autoStop != nil {
+			stream.stop = stopTask
+		}
+		if meter != nil {
+			meter.stop = stopTask
+		}
+	}
+	stream.meter = meter
+	return stream, nil
+}
+
+func (c *Client) stopTask(ctx context.Context, taskId string, stop StopFunc) {
+	var timeout time.Duration
+	if c.autoStop != nil {
+		timeout = c.autoStop.Timeout
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	err := stop(ctx, taskId)
+	if err != nil {
+		c.logger.Warn("dify: failed to stop the task of a stream", "task_id", taskId, "error", err)
+	} else {
+		c.logger.Debug("dify: stopped the task of a stream", "task_id", taskId)
+	}
+	if c.autoStop != nil && c.autoStop.OnStop != nil {
+		c.autoStop.OnStop(StopResult{TaskId: taskId, Err: err})
+	}
+}
+
+// WithoutCancel returns a context keeping the values of ctx, such as trace spans,
+// but neither its deadline nor its cancellation.
+func WithoutCancel(ctx context.Context) context.Context {
+	return detachedContext{ctx}
+}
+
+type detachedContext struct {
+	parent context.Context
+}
+
+func (c detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (c detachedContext) Done() <-chan struct{}             { return nil }
+func (c detachedContext) Err() error                        { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
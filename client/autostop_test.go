@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newHangingStreamServer streams a message of task "task-1", then waits for the client
+// to go away unless complete is set.
+func newHangingStreamServer(complete bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: {\"event\":\"message\",\"task_id\":\"task-1\",\"answer\":\"hi\"}\n\n")
+		w.(http.Flusher).Flush()
+		if !complete {
+			<-r.Context().Done()
+		}
+	}))
+}
+
+func TestAutoStopOnCancel(t *testing.T) {
+	srv := newHangingStreamServer(false)
+	defer srv.Close()
+
+	results := make(chan StopResult, 1)
+	c := NewClientWithConfig(&ClientConfig{BaseUrl: srv.URL, ApiKey: "app-test", AutoStop: &AutoStop{
+		OnStop: func(r StopResult) { results <- r },
+	}})
+	ctx, cancel := context.WithCancel(context.Background())
+	var stopCtxErr error
+	stream, err := c.SendStoppableStreamRequest(newStreamRequest(t, ctx, c), func(ctx context.Context, taskId string) error {
+		stopCtxErr = ctx.Err()
+		return errors.New("stop failed")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if !stream.Next() || stream.TaskId() != "task-1" {
+		t.Fatalf("task_id = %q, err = %v", stream.TaskId(), stream.Err())
+	}
+	cancel()
+
+	select {
+	case r := <-results:
+		if r.TaskId != "task-1" || r.Err == nil || r.Err.Error() != "stop failed" {
+			t.Errorf("unexpected result %+v", r)
+		}
+		if stopCtxErr != nil {
+			t.Errorf("the stop call ran with a canceled context: %v", stopCtxErr)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the task was not stopped")
+	}
+	if stream.Next() || stream.Err() != context.Canceled {
+		t.Errorf("err = %v, want context.Canceled", stream.Err())
+	}
+}
+
+func TestAutoStopCancelThenClose(t *testing.T) {
+	srv := newHangingStreamServer(false)
+	defer srv.Close()
+
+	stopped := make(chan string, 1)
+	c := NewClientWithConfig(&ClientConfig{BaseUrl: srv.URL, ApiKey: "app-test", AutoStop: &AutoStop{}})
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := c.SendStoppableStreamRequest(newStreamRequest(t, ctx, c), func(ctx context.Context, taskId string) error {
+		stopped <- taskId
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Next()
+	// as with a deferred Close, the stream is closed once the context is canceled
+	cancel()
+	stream.Close()
+
+	select {
+	case id := <-stopped:
+		if id != "task-1" {
+			t.Errorf("stopped task %s, want task-1", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the task was not stopped")
+	}
+}
+
+func TestAutoStopLeavesEndedStreams(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		complete bool
+		close    bool
+		end      bool
+		config   bool
+	}{
+		{name: "complete", complete: true, config: true},
+		{name: "closed", close: true, config: true},
+		{name: "ended", end: true, config: true},
+		{name: "disabled"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newHangingStreamServer(tt.complete)
+			defer srv.Close()
+
+			config := &ClientConfig{BaseUrl: srv.URL, ApiKey: "app-test"}
+			if tt.config {
+				config.AutoStop = &AutoStop{}
+			}
+			c := NewClientWithConfig(config)
+			ctx, cancel := context.WithCancel(context.Background())
+			stopped := make(chan string, 1)
+			stream, err := c.SendStoppableStreamRequest(newStreamRequest(t, ctx, c), func(ctx context.Context, taskId string) error {
+				stopped <- taskId
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.close {
+				stream.Next()
+				stream.Close()
+			} else if tt.end {
+				stream.Next()
+				stream.End()
+			} else if tt.complete {
+				for stream.Next() {
+				}
+			} else {
+				stream.Next()
+			}
+			cancel()
+			stream.Close()
+
+			select {
+			case id := <-stopped:
+				t.Errorf("task %s was stopped", id)
+			case <-time.After(100 * time.Millisecond):
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrBudgetExceeded is matched by errors.Is on the *BudgetExceededError of a Budget.
+var ErrBudgetExceeded = errors.New("dify: budget exceeded")
+
+// BudgetScope is the scope of a BudgetLimit.
+type BudgetScope string
+
+const (
+	// BudgetScopeRun limits a single stream, i.e. a workflow run or a message.
+	BudgetScopeRun BudgetScope = "run"
+	// BudgetScopeUser limits all the streams of a user.
+	BudgetScopeUser BudgetScope = "user"
+	// BudgetScopeApp limits all the streams of an API key.
+	BudgetScopeApp BudgetScope = "app"
+)
+
+// BudgetLimit caps the tokens and the price spent, a zero field sets no cap.
+type BudgetLimit struct {
+	Tokens int64
+	// Price in the currency reported by Dify, usually USD.
+	Price float64
+}
+
+// BudgetTotal is the usage counted by a Budget.
+type BudgetTotal struct {
+	Tokens   int64
+	Price    Decimal
+	Currency string
+}
+
+// Budget counts the tokens and the price reported by the streams of the clients it is
+// configured on, see ClientConfig.Budget, per run, per user and per API key, and
+// enforces limits on them.
+//
+// The usage is taken from the execution_metadata of the node_finished events of
+// workflows, container nodes such as iterations excepted as their children are counted,
+// and from the usage of message_end. A stream exceeding a limit fails with a
+// *BudgetExceededError and its task is stopped; the usage of message_end and
+// workflow_finished, the last events of a stream, is counted without failing it. Once
+// the limit of a user or an API key is reached, their new streams are refused before
+// any request is sent.
+//
+// A Budget can be shared by several clients, the totals are kept until Reset.
+type Budget struct {
+	PerRun  BudgetLimit
+	PerUser BudgetLimit
+	PerApp  BudgetLimit
+	// OnExceeded, when set, is called with every exceeded limit, from the goroutine
+	// reading the stream or sending the request.
+	OnExceeded func(err *BudgetExceededError)
+
+	mu    sync.Mutex
+	users map[string]*budgetTotal
+	apps  map[string]*budgetTotal
+}
+
+// BudgetExceededError is returned when a stream exceeds a limit of a Budget, or when
+// a user or an API key has no budget left.
+type BudgetExceededError struct {
+	Scope BudgetScope
+	User  string
+	// TaskId is the task of the stopped stream, empty when the request was refused.
+	TaskId string
+	Limit  BudgetLimit
+	Total  BudgetTotal
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("dify: %s budget exceeded: %d tokens, %s %s spent", e.Scope, e.Total.Tokens, e.Total.Price, e.Total.Currency)
+}
+
+// Is reports whether target is ErrBudgetExceeded.
+func (e *BudgetExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// UserTotal returns the usage of user.
+func (b *Budget) UserTotal(user string) BudgetTotal {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.users[user].export()
+}
+
+// AppTotal returns the usage of the API key.
+func (b *Budget) AppTotal(apiKey string) BudgetTotal {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.apps[apiKey].export()
+}
+
+// UserTotals returns the usage of every user, e.g. to bill them.
+func (b *Budget) UserTotals() map[string]BudgetTotal {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ret := make(map[string]BudgetTotal, len(b.users))
+	for user, t := range b.users {
+		ret[user] = t.export()
+	}
+	return ret
+}
+
+// Reset clears the totals of the users and the API keys, e.g. at the start of a
+// billing period.
+func (b *Budget) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.users, b.apps = nil, nil
+}
+
+// meter returns the meter of a stream sent with req, or a *BudgetExceededError when
+// its user or its API key has no budget left.
+func (b *Budget) meter(req *http.Request) (*budgetMeter, error) {
+	m := &budgetMeter{
+		b:    b,
+		user: requestUser(req),
+		app:  strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "),
+	}
+	b.mu.Lock()
+	var err *BudgetExceededError
+	if t := b.users[m.user]; b.PerUser.reached(t) {
+		err = &BudgetExceededError{Scope: BudgetScopeUser, Limit: b.PerUser, Total: t.export()}
+	} else if t := b.apps[m.app]; b.PerApp.reached(t) {
+		err = &BudgetExceededError{Scope: BudgetScopeApp, Limit: b.PerApp, Total: t.export()}
+	}
+	b.mu.Unlock()
+	if err != nil {
+		err.User = m.user
+		b.exceeded(err)
+		return nil, err
+	}
+	return m, nil
+}
+
+func (b *Budget) exceeded(err *BudgetExceededError) {
+	if b.OnExceeded != nil {
+		b.OnExceeded(err)
+	}
+}
+
+// budgetMeter counts the usage of a stream.
+type budgetMeter struct {
+	b    *Budget
+	user string
+	app  string
+	run  budgetTotal
+	// stop stops the task of the stream, nil when it cannot be stopped
+	stop func(taskId string)
+}
+
+// add counts the usage reported by an event and returns a *BudgetExceededError when
+// the stream must be aborted.
+func (m *budgetMeter) add(event string, data []byte, taskId string) error {
+	var e struct {
+		Data struct {
+			NodeType          string     `json:"node_type"`
+			TotalTokens       int64      `json:"total_tokens"`
+			ExecutionMetadata usageTotal `json:"execution_metadata"`
+		} `json:"data"`
+		Metadata struct {
+			Usage usageTotal `json:"usage"`
+		} `json:"metadata"`
+	}
+	var tokens int64
+	price := new(big.Rat)
+	var currency string
+	last := false
+	switch event {
+	case "node_finished":
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil
+		}
+		if t := e.Data.NodeType; t == "iteration" || t == "loop" {
+			return nil
+		}
+		tokens, currency = e.Data.ExecutionMetadata.TotalTokens, e.Data.ExecutionMetadata.Currency
+		price = e.Data.ExecutionMetadata.TotalPrice.rat()
+	case "message_end", "workflow_finished":
+		// these report the total of the run, count what the nodes did not
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil
+		}
+		last = true
+		total, totalPrice := e.Metadata.Usage.TotalTokens, e.Metadata.Usage.TotalPrice.rat()
+		currency = e.Metadata.Usage.Currency
+		if event == "workflow_finished" {
+			total, totalPrice = e.Data.TotalTokens, new(big.Rat)
+		}
+		if total > m.run.tokens {
+			tokens = total - m.run.tokens
+		}
+		if totalPrice.Cmp(&m.run.price) > 0 {
+			price.Sub(totalPrice, &m.run.price)
+		}
+	default:
+		return nil
+	}
+	if tokens == 0 && price.Sign() == 0 {
+		return nil
+	}
+
+	b := m.b
+	b.mu.Lock()
+	if b.users == nil {
+		b.users = make(map[string]*budgetTotal)
+		b.apps = make(map[string]*budgetTotal)
+	}
+	user, app := b.users[m.user], b.apps[m.app]
+	if user == nil {
+		user = new(budgetTotal)
+		b.users[m.user] = user
+	}
+	if app == nil {
+		app = new(budgetTotal)
+		b.apps[m.app] = app
+	}
+	m.run.add(tokens, price, currency)
+	user.add(tokens, price, currency)
+	app.add(tokens, price, currency)
+	var err *BudgetExceededError
+	switch {
+	case last:
+		// the run is over, the limits apply to the next ones
+	case b.PerRun.exceeded(&m.run):
+		err = &BudgetExceededError{Scope: BudgetScopeRun, Limit: b.PerRun, Total: m.run.export()}
+	case b.PerUser.exceeded(user):
+		err = &BudgetExceededError{Scope: BudgetScopeUser, Limit: b.PerUser, Total: user.export()}
+	case b.PerApp.exceeded(app):
+		err = &BudgetExceededError{Scope: BudgetScopeApp, Limit: b.PerApp, Total: app.export()}
+	}
+	b.mu.Unlock()
+	if err == nil {
+		return nil
+	}
+
+	err.User, err.TaskId = m.user, taskId
+	if m.stop != nil && taskId != "" {
+		go m.stop(taskId)
+	}
+	b.exceeded(err)
+	return err
+}
+
+// usageTotal is the part of an execution_metadata or a usage counted by a Budget.
+type usageTotal struct {
+	TotalTokens int64   `json:"total_tokens"`
+	TotalPrice  Decimal `json:"total_price"`
+	Currency    string  `json:"currency"`
+}
+
+type budgetTotal struct {
+	tokens   int64
+	price    big.Rat
+	currency string
+}
+
+func (t *budgetTotal) add(tokens int64, price *big.Rat, currency string) {
+	t.tokens += tokens
+	t.price.Add(&t.price, price)
+	if t.currency == "" {
+		t.currency = currency
+	}
+}
+
+func (t *budgetTotal) export() BudgetTotal {
+	if t == nil {
+		return BudgetTotal{Price: "0"}
+	}
+	price := strings.TrimRight(t.price.FloatString(10), "0")
+	price = strings.TrimSuffix(price, ".")
+	return BudgetTotal{Tokens: t.tokens, Price: Decimal(price), Currency: t.currency}
+}
+
+// exceeded reports whether t is over the limit.
+func (l BudgetLimit) exceeded(t *budgetTotal) bool {
+	if t == nil {
+		return false
+	}
+	if l.Tokens > 0 && t.tokens > l.Tokens {
+		return true
+	}
+	return l.Price > 0 && t.price.Cmp(new(big.Rat).SetFloat64(l.Price)) > 0
+}
+
+// reached reports whether t has no budget left under the limit.
+func (l BudgetLimit) reached(t *budgetTotal) bool {
+	if t == nil {
+		return false
+	}
+	if l.Tokens > 0 && t.tokens >= l.Tokens {
+		return true
+	}
+	return l.Price > 0 && t.price.Cmp(new(big.Rat).SetFloat64(l.Price)) >= 0
+}
+
+// rat returns d as a big.Rat, zero when d is empty or invalid.
+func (d Decimal) rat() *big.Rat {
+	r, ok := new(big.Rat).SetString(string(d))
+	if !ok {
+		return new(big.Rat)
+	}
+	return r
+}
+
+// requestUser returns the "user" field of the JSON body of req.
+func requestUser(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	var v struct {
+		User string `json:"user"`
+	}
+	json.NewDecoder(io.LimitReader(body, 1<<20)).Decode(&v)
+	return v.User
+}
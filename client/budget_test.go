@@ -0,0 +1,90 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newBudgetRequest(t *testing.T, user string) *http.Request {
+	req, err := http.NewRequest(http.MethodPost, "http://dify/v1/workflows/run", strings.NewReader(`{"inputs":{},"user":"`+user+`"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer app-test")
+	return req
+}
+
+func TestBudgetCountsUsage(t *testing.T) {
+	b := &Budget{}
+	m, err := b.meter(newBudgetRequest(t, "alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []struct{ event, data string }{
+		{"node_finished", `{"data":{"node_type":"llm","execution_metadata":{"total_tokens":10,"total_price":"0.0000100","currency":"USD"}}}`},
+		{"node_finished", `{"data":{"node_type":"llm","execution_metadata":{"total_tokens":5,"total_price":0.000005,"currency":"USD"}}}`},
+		// the iteration reports the usage of its children, counted already
+		{"node_finished", `{"data":{"node_type":"iteration","execution_metadata":{"total_tokens":15,"total_price":"0.000015"}}}`},
+		{"workflow_finished", `{"data":{"total_tokens":15}}`},
+	} {
+		if err := m.add(e.event, []byte(e.data), "task-1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a chat message reports its usage at the end only
+	m, _ = b.meter(newBudgetRequest(t, "alice"))
+	m.add("message_end", []byte(`{"metadata":{"usage":{"total_tokens":3,"total_price":"0.0000030","currency":"USD"}}}`), "task-2")
+
+	want := BudgetTotal{Tokens: 18, Price: "0.000018", Currency: "USD"}
+	if got := b.UserTotal("alice"); got != want {
+		t.Errorf("user total = %+v, want %+v", got, want)
+	}
+	if got := b.AppTotal("app-test"); got != want {
+		t.Errorf("app total = %+v, want %+v", got, want)
+	}
+	if got := b.UserTotal("bob"); got.Tokens != 0 || got.Price != "0" {
+		t.Errorf("bob total = %+v", got)
+	}
+	b.Reset()
+	if totals := b.UserTotals(); len(totals) != 0 {
+		t.Errorf("totals after reset = %v", totals)
+	}
+}
+
+func TestBudgetLimits(t *testing.T) {
+	var exceeded []*BudgetExceededError
+	b := &Budget{
+		PerRun:     BudgetLimit{Tokens: 10},
+		PerUser:    BudgetLimit{Price: 0.00002},
+		OnExceeded: func(err *BudgetExceededError) { exceeded = append(exceeded, err) },
+	}
+	m, _ := b.meter(newBudgetRequest(t, "alice"))
+	stopped := make(chan string, 1)
+	m.stop = func(taskId string) { stopped <- taskId }
+	node := []byte(`{"data":{"node_type":"llm","execution_metadata":{"total_tokens":6,"total_price":"0.000012","currency":"USD"}}}`)
+	if err := m.add("node_finished", node, "task-1"); err != nil {
+		t.Fatal(err)
+	}
+	err := m.add("node_finished", node, "task-1")
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) || !errors.Is(err, ErrBudgetExceeded) || budgetErr.Scope != BudgetScopeRun || budgetErr.TaskId != "task-1" || budgetErr.Total.Tokens != 12 {
+		t.Fatalf("err = %#v", err)
+	}
+	if id := <-stopped; id != "task-1" {
+		t.Errorf("stopped %q, want task-1", id)
+	}
+
+	// the user spent 0.000024, over the limit: the next stream is refused
+	_, err = b.meter(newBudgetRequest(t, "alice"))
+	if !errors.As(err, &budgetErr) || budgetErr.Scope != BudgetScopeUser || budgetErr.User != "alice" || budgetErr.TaskId != "" {
+		t.Errorf("err = %#v", err)
+	}
+	if _, err := b.meter(newBudgetRequest(t, "bob")); err != nil {
+		t.Errorf("bob was refused: %v", err)
+	}
+	if len(exceeded) != 2 {
+		t.Errorf("OnExceeded called %d times, want 2", len(exceeded))
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/taadis/http2curl"
 )
@@ -22,6 +23,18 @@ type Client struct {
 	apiKey string
 	// HTTP Client
 	httpClient *http.Client
+	// Logger
+	logger Logger
+	// Debug mode
+	debug bool
+	// Retry policy, nil disables retries
+	retry *RetryPolicy
+	// Handler running the middlewares around a single attempt
+	handler Handler
+	// Stops the tasks of canceled streams, nil disables it
+	autoStop *AutoStop
+	// Counts and limits the usage of streams, nil disables it
+	budget *Budget
 }
 
 func NewClientWithConfig(c *ClientConfig) *Client {
@@ -34,11 +47,28 @@ func NewClientWithConfig(c *ClientConfig) *Client {
 		httpClient.Transport = c.Transport
 	}
 
-	return &Client{
+	logger := c.Logger
+	if logger == nil {
+		logger = nopLogger{}
+		if c.Debug {
+			logger = NewStdLogger(nil)
+		}
+	}
+
+	ret := &Client{
 		baseUrl:    c.BaseUrl,
 		apiKey:     c.ApiKey,
 		httpClient: httpClient,
+		logger:     logger,
+		debug:      c.Debug,
+		autoStop:   c.AutoStop,
+		budget:     c.Budget,
 	}
+	if c.Retry != nil {
+		ret.retry = c.Retry.withDefaults()
+	}
+	ret.handler = Chain(ret.do, c.Middlewares...)
+	return ret
 }
 
 func NewClient(baseUrl string, apiKey string) *Client {
@@ -53,13 +83,43 @@ func (c *Client) SendRequest(req *http.Request) (*http.Response, error) {
 }
 
 func (c *Client) sendRequest(req *http.Request) (*http.Response, error) {
-	curlcmd, err := http2curl.GetCurlCommand(req)
+	return c.sendWithRetry(req)
+}
+
+// send performs a single attempt of req through the middlewares.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	return c.handler(req)
+}
+
+// do performs the HTTP call, it is the innermost Handler.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.debug {
+		c.logRequest(req)
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		c.logger.Error("dify: request failed", "method", req.Method, "url", req.URL.String(), "elapsed", time.Since(start), "error", err)
 		return nil, err
 	}
-	fmt.Println(curlcmd.String())
+	if c.debug {
+		c.logger.Debug("dify: response", "method", req.Method, "url", req.URL.String(), "status", resp.StatusCode,
+			"content_type", resp.Header.Get("Content-Type"), "content_length", resp.ContentLength, "elapsed", time.Since(start))
+	}
+	return resp, nil
+}
 
-	return c.httpClient.Do(req)
+// logRequest logs the request summary and its curl command, secrets are masked.
+func (c *Client) logRequest(req *http.Request) {
+	safeReq := redactedRequest(req)
+	c.logger.Debug("dify: request", "method", req.Method, "url", req.URL.String())
+	curlcmd, err := http2curl.GetCurlCommand(safeReq)
+	if err != nil {
+		c.logger.Debug("dify: failed to build curl command", "error", err)
+		return
+	}
+	c.logger.Debug("dify: curl", "command", curlcmd.String())
 }
 
 func (c *Client) SendJSONRequest(req *http.Request, res interface{}) error {
@@ -74,16 +134,7 @@ func (c *Client) sendJSONRequest(req *http.Request, res interface{}) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		var errBody struct {
-			Code    string `json:"code"`
-			Message string `json:"message"`
-			Status  int    `json:"status"`
-		}
-		err = json.NewDecoder(resp.Body).Decode(&errBody)
-		if err != nil {
-			return err
-		}
-		return fmt.Errorf("HTTP response error: [%v]%v", errBody.Code, errBody.Message)
+		return NewAPIError(resp)
 	}
 
 	err = json.NewDecoder(resp.Body).Decode(res)
@@ -123,7 +174,6 @@ func (c *Client) CreateBaseRequest(ctx context.Context, method string, apiUrl st
 	if err != nil {
 		return nil, err
 	}
-	fmt.Println("got api key=", c.getApiKey())
 	req.Header.Set("Authorization", "Bearer "+c.getApiKey())
 	req.Header.Set("Cache-Control", "no-cache")
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
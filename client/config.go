@@ -12,6 +12,24 @@ type ClientConfig struct {
 	ApiKey string
 	//
 	Timeout time.Duration
-	//
-	Transport *http.Transport
+	// Transport used to make the HTTP calls, http.DefaultTransport when nil.
+	Transport http.RoundTripper
+	// Logger receives the client logs, nothing is logged when nil.
+	// A *slog.Logger can be used directly.
+	Logger Logger
+	// Debug enables curl dumps and request/response summaries at debug level,
+	// with the Authorization header and secret-looking fields masked.
+	// When Logger is nil, the debug output goes to the standard logger.
+	Debug bool
+	// Retry enables automatic retries of transient failures, nil disables them.
+	// See RetryPolicy for which requests are retried.
+	Retry *RetryPolicy
+	// Middlewares run around every HTTP call, the first one being the outermost.
+	Middlewares []Middleware
+	// AutoStop stops the server-side task of a stream when the context of the call is
+	// canceled, nil disables it. See AutoStop.
+	AutoStop *AutoStop
+	// Budget counts the tokens and the price spent by the streams and enforces its
+	// limits, nil disables it. See Budget.
+	Budget *Budget
 }
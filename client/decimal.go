@@ -0,0 +1,53 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Decimal is a decimal number, such as a price, that Dify encodes either as a JSON
+// number or as a string depending on the endpoint and version.
+type Decimal string
+
+// UnmarshalJSON accepts a number, a numeric string or null.
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		*d = ""
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		var n json.Number
+		if s != "" && json.Unmarshal([]byte(s), &n) != nil {
+			return fmt.Errorf("dify: invalid decimal %q", s)
+		}
+		*d = Decimal(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*d = Decimal(n)
+	return nil
+}
+
+// MarshalJSON encodes d as a JSON number, or null when empty.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	if d == "" {
+		return []byte("null"), nil
+	}
+	return []byte(d), nil
+}
+
+// Float64 returns d as a float64, 0 when empty.
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(string(d), 64)
+	return f
+}
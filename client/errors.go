@@ -0,0 +1,217 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Known Dify error codes, see the "Errors" section of each Service API endpoint.
+const (
+	ErrCodeInvalidParam                 = "invalid_param"
+	ErrCodeBadRequest                   = "bad_request"
+	ErrCodeUnauthorized                 = "unauthorized"
+	ErrCodeForbidden                    = "forbidden"
+	ErrCodeNotFound                     = "not_found"
+	ErrCodeAppUnavailable               = "app_unavailable"
+	ErrCodeNotChatApp                   = "not_chat_app"
+	ErrCodeNotCompletionApp             = "not_completion_app"
+	ErrCodeNotWorkflowApp               = "not_workflow_app"
+	ErrCodeConversationNotExists        = "conversation_not_exists"
+	ErrCodeConversationCompleted        = "conversation_completed"
+	ErrCodeMessageNotExists             = "message_not_exists"
+	ErrCodeProviderNotInitialize        = "provider_not_initialize"
+	ErrCodeProviderQuotaExceeded        = "provider_quota_exceeded"
+	ErrCodeModelCurrentlyNotSupport     = "model_currently_not_support"
+	ErrCodeCompletionRequestError       = "completion_request_error"
+	ErrCodeWorkflowRequestError         = "workflow_request_error"
+	ErrCodeNoFileUploaded               = "no_file_uploaded"
+	ErrCodeTooManyFiles                 = "too_many_files"
+	ErrCodeFileTooLarge                 = "file_too_large"
+	ErrCodeUnsupportedFileType          = "unsupported_file_type"
+	ErrCodeNoAudioUploaded              = "no_audio_uploaded"
+	ErrCodeAudioTooLarge                = "audio_too_large"
+	ErrCodeUnsupportedAudioType         = "unsupported_audio_type"
+	ErrCodeProviderNotSupportSpeech     = "provider_not_support_speech_to_text"
+	ErrCodeS3ConnectionFailed           = "s3_connection_failed"
+	ErrCodeS3PermissionDenied           = "s3_permission_denied"
+	ErrCodeS3FileTooLarge               = "s3_file_too_large"
+	ErrCodeTooManyRequests              = "too_many_requests"
+	ErrCodeRateLimitError               = "rate_limit_error"
+	ErrCodeInternalServerError          = "internal_server_error"
+	ErrCodeInvokeError                  = "invoke_error"
+	ErrCodeAppInvokeQuotaExceeded       = "app_invoke_quota_exceeded"
+	ErrCodeWorkflowNotFound             = "workflow_not_found"
+	ErrCodeWorkflowIdFormatError        = "workflow_id_format_error"
+	ErrCodeConversationVariableNotFound = "conversation_variable_not_exists"
+)
+
+// maxErrorBodySize limits how much of an error response body is kept.
+const maxErrorBodySize = 64 << 10
+
+// requestIdHeaders are the response headers checked, in order, for a request identifier.
+var requestIdHeaders = []string{"X-Request-Id", "X-Trace-Id", "X-Amzn-Trace-Id", "Cf-Ray"}
+
+// APIError is returned when the Dify API responds with a non-success status.
+// Use errors.As to inspect it, or the helpers such as IsRateLimited and IsNotFound.
+type APIError struct {
+	// HTTP status code, e.g. 400.
+	StatusCode int `json:"-"`
+	// Dify error code, e.g. "invalid_param". Empty when the body is not a Dify error.
+	Code string `json:"code"`
+	// Human readable message.
+	Message string `json:"message"`
+	// Status reported in the error body, usually equal to StatusCode.
+	Status int `json:"status"`
+	// Raw response body, truncated to 64KB.
+	Body []byte `json:"-"`
+	// Request identifier taken from the response headers, if any.
+	RequestId string `json:"-"`
+	// Response headers.
+	Header http.Header `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "dify: status %d", e.StatusCode)
+	if e.Code != "" {
+		fmt.Fprintf(&b, " [%s]", e.Code)
+	}
+	if e.Message != "" {
+		b.WriteString(" ")
+		b.WriteString(e.Message)
+	}
+	if e.RequestId != "" {
+		fmt.Fprintf(&b, " (request id %s)", e.RequestId)
+	}
+	return b.String()
+}
+
+// Is reports whether target is an *APIError with the same non-zero StatusCode and Code,
+// so that errors.Is(err, &APIError{Code: ErrCodeInvalidParam}) works.
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	if !ok {
+		return false
+	}
+	if t.StatusCode != 0 && t.StatusCode != e.StatusCode {
+		return false
+	}
+	if t.Code != "" && t.Code != e.Code {
+		return false
+	}
+	return true
+}
+
+// NewAPIError builds an *APIError from a non-success response, consuming its body.
+// Bodies that are not a Dify JSON error (e.g. an HTML page from a reverse proxy)
+// still produce an error carrying the status and a snippet of the body.
+func NewAPIError(resp *http.Response) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+	}
+	for _, h := range requestIdHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			e.RequestId = v
+			break
+		}
+	}
+
+	bs, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	e.Body = bs
+	if err != nil {
+		e.Message = fmt.Sprintf("failed to read response body: %v", err)
+		return e
+	}
+
+	if json.Unmarshal(bs, e) == nil && (e.Code != "" || e.Message != "") {
+		return e
+	}
+	e.Code = ""
+	e.Message = http.StatusText(resp.StatusCode)
+	if snippet := bodySnippet(bs); snippet != "" {
+		e.Message += ": " + snippet
+	}
+	return e
+}
+
+// bodySnippet returns a short single line excerpt of a body for error messages.
+func bodySnippet(bs []byte) string {
+	const maxLen = 256
+	s := strings.Join(strings.Fields(string(bs)), " ")
+	if len(s) > maxLen {
+		s = s[:maxLen] + "..."
+	}
+	return s
+}
+
+// AsAPIError returns the *APIError in err's chain, if any.
+func AsAPIError(err error) (*APIError, bool) {
+	var e *APIError
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// IsRateLimited reports whether err is a 429 or a Dify rate limit error.
+func IsRateLimited(err error) bool {
+	e, ok := AsAPIError(err)
+	if !ok {
+		return false
+	}
+	return e.StatusCode == http.StatusTooManyRequests ||
+		e.Code == ErrCodeTooManyRequests ||
+		e.Code == ErrCodeRateLimitError ||
+		e.Code == ErrCodeAppInvokeQuotaExceeded
+}
+
+// IsNotFound reports whether err is a 404 or a Dify "not exists" error.
+func IsNotFound(err error) bool {
+	e, ok := AsAPIError(err)
+	if !ok {
+		return false
+	}
+	return e.StatusCode == http.StatusNotFound ||
+		e.Code == ErrCodeNotFound ||
+		e.Code == ErrCodeConversationNotExists ||
+		e.Code == ErrCodeMessageNotExists ||
+		e.Code == ErrCodeWorkflowNotFound
+}
+
+// IsUnauthorized reports whether err is a 401 or 403, usually an invalid API key.
+func IsUnauthorized(err error) bool {
+	e, ok := AsAPIError(err)
+	if !ok {
+		return false
+	}
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+}
+
+// IsInvalidParam reports whether err is a Dify invalid_param error.
+func IsInvalidParam(err error) bool {
+	e, ok := AsAPIError(err)
+	return ok && e.Code == ErrCodeInvalidParam
+}
+
+// IsQuotaExceeded reports whether err is a Dify provider quota error.
+func IsQuotaExceeded(err error) bool {
+	e, ok := AsAPIError(err)
+	return ok && e.Code == ErrCodeProviderQuotaExceeded
+}
+
+// IsServerError reports whether err is a 5xx response.
+func IsServerError(err error) bool {
+	e, ok := AsAPIError(err)
+	return ok && e.StatusCode >= http.StatusInternalServerError
+}
+
+// HasErrorCode reports whether err is an *APIError with the given Dify error code.
+func HasErrorCode(err error, code string) bool {
+	e, ok := AsAPIError(err)
+	return ok && e.Code == code
+}
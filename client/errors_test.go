@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendJSONRequestAPIError(t *testing.T) {
+	cases := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantCode    string
+		wantMessage string
+		check       func(error) bool
+	}{
+		{
+			name:        "dify json error",
+			status:      http.StatusBadRequest,
+			contentType: "application/json",
+			body:        `{"code":"invalid_param","message":"query is required","status":400}`,
+			wantCode:    ErrCodeInvalidParam,
+			wantMessage: "query is required",
+			check:       IsInvalidParam,
+		},
+		{
+			name:        "rate limited",
+			status:      http.StatusTooManyRequests,
+			contentType: "application/json",
+			body:        `{"code":"too_many_requests","message":"slow down","status":429}`,
+			wantCode:    ErrCodeTooManyRequests,
+			wantMessage: "slow down",
+			check:       IsRateLimited,
+		},
+		{
+			name:        "not found",
+			status:      http.StatusNotFound,
+			contentType: "application/json",
+			body:        `{"code":"not_found","message":"Conversation Not Exists.","status":404}`,
+			wantCode:    ErrCodeNotFound,
+			wantMessage: "Conversation Not Exists.",
+			check:       IsNotFound,
+		},
+		{
+			name:        "html from reverse proxy",
+			status:      http.StatusBadGateway,
+			contentType: "text/html",
+			body:        "<html>\n<body><h1>502 Bad Gateway</h1></body>\n</html>",
+			wantCode:    "",
+			wantMessage: "Bad Gateway: <html> <body><h1>502 Bad Gateway</h1></body> </html>",
+			check:       IsServerError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				w.Header().Set("X-Request-Id", "req-1")
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			c := NewClient(srv.URL, "app-test")
+			req, err := c.CreateBaseRequest(context.Background(), http.MethodGet, "/info", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = c.SendJSONRequest(req, &struct{}{})
+
+			wrapped := fmt.Errorf("wrapped: %w", err)
+			var apiErr *APIError
+			if !errors.As(wrapped, &apiErr) {
+				t.Fatalf("expected *APIError, got %T: %v", err, err)
+			}
+			if apiErr.StatusCode != tc.status {
+				t.Errorf("StatusCode = %d, want %d", apiErr.StatusCode, tc.status)
+			}
+			if apiErr.Code != tc.wantCode {
+				t.Errorf("Code = %q, want %q", apiErr.Code, tc.wantCode)
+			}
+			if apiErr.Message != tc.wantMessage {
+				t.Errorf("Message = %q, want %q", apiErr.Message, tc.wantMessage)
+			}
+			if apiErr.RequestId != "req-1" {
+				t.Errorf("RequestId = %q, want %q", apiErr.RequestId, "req-1")
+			}
+			if string(apiErr.Body) != tc.body {
+				t.Errorf("Body = %q, want %q", apiErr.Body, tc.body)
+			}
+			if !tc.check(wrapped) {
+				t.Errorf("helper did not match %v", err)
+			}
+			if !strings.Contains(err.Error(), fmt.Sprint(tc.status)) {
+				t.Errorf("Error() = %q, want status in message", err.Error())
+			}
+		})
+	}
+}
+
+func TestAPIErrorIs(t *testing.T) {
+	err := fmt.Errorf("call failed: %w", &APIError{StatusCode: 400, Code: ErrCodeAppUnavailable})
+	if !errors.Is(err, &APIError{Code: ErrCodeAppUnavailable}) {
+		t.Error("expected match on code")
+	}
+	if !errors.Is(err, &APIError{StatusCode: 400}) {
+		t.Error("expected match on status")
+	}
+	if errors.Is(err, &APIError{Code: ErrCodeInvalidParam}) {
+		t.Error("unexpected match on a different code")
+	}
+	if IsRateLimited(errors.New("plain")) {
+		t.Error("plain errors are not rate limited")
+	}
+}
@@ -0,0 +1,167 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Logger is the logging interface used by Client.
+// Its method set matches the leveled methods of *slog.Logger,
+// so a *slog.Logger can be passed as is.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// nopLogger discards everything, it is the default logger.
+type nopLogger struct{}
+
+func (nopLogger) Debug(msg string, args ...interface{}) {}
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+
+// stdLogger adapts a standard library *log.Logger to Logger.
+type stdLogger struct {
+	l *log.Logger
+}
+
+// NewStdLogger returns a Logger writing "LEVEL msg key=value ..." lines to l.
+// If l is nil, log.Default() is used.
+func NewStdLogger(l *log.Logger) Logger {
+	if l == nil {
+		l = log.Default()
+	}
+	return &stdLogger{l: l}
+}
+
+func (s *stdLogger) Debug(msg string, args ...interface{}) { s.output("DEBUG", msg, args) }
+func (s *stdLogger) Info(msg string, args ...interface{})  { s.output("INFO", msg, args) }
+func (s *stdLogger) Warn(msg string, args ...interface{})  { s.output("WARN", msg, args) }
+func (s *stdLogger) Error(msg string, args ...interface{}) { s.output("ERROR", msg, args) }
+
+func (s *stdLogger) output(level string, msg string, args []interface{}) {
+	var b strings.Builder
+	b.WriteString(level)
+	b.WriteString(" ")
+	b.WriteString(msg)
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+		} else {
+			fmt.Fprintf(&b, " %v", args[i])
+		}
+	}
+	s.l.Output(3, b.String())
+}
+
+const redacted = "******"
+
+// secretWords are the key segments considered secret, e.g. "api_key", "access-token".
+var secretWords = map[string]bool{
+	"key":           true,
+	"apikey":        true,
+	"secret":        true,
+	"token":         true,
+	"password":      true,
+	"passwd":        true,
+	"authorization": true,
+	"credential":    true,
+	"credentials":   true,
+}
+
+// isSecretKey reports whether a header or JSON field name looks like it holds a secret.
+func isSecretKey(name string) bool {
+	f := func(r rune) bool {
+		return r == '_' || r == '-' || r == '.' || r == ' '
+	}
+	for _, word := range strings.FieldsFunc(strings.ToLower(name), f) {
+		if secretWords[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// redactHeader returns a copy of h with the Authorization header and any secret-looking headers masked.
+func redactHeader(h http.Header) http.Header {
+	ret := make(http.Header, len(h))
+	for k, v := range h {
+		if !isSecretKey(k) {
+			ret[k] = v
+			continue
+		}
+		masked := make([]string, len(v))
+		for i, s := range v {
+			if strings.HasPrefix(s, "Bearer ") {
+				masked[i] = "Bearer " + redacted
+			} else {
+				masked[i] = redacted
+			}
+		}
+		ret[k] = masked
+	}
+	return ret
+}
+
+// redactJSON masks the values of secret-looking fields in a JSON document.
+// Non-JSON input is returned unchanged.
+func redactJSON(bs []byte) []byte {
+	var v interface{}
+	if err := json.Unmarshal(bs, &v); err != nil {
+		return bs
+	}
+	ret, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return bs
+	}
+	return ret
+}
+
+func redactValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, e := range vv {
+			if isSecretKey(k) {
+				vv[k] = redacted
+			} else {
+				vv[k] = redactValue(e)
+			}
+		}
+	case []interface{}:
+		for i, e := range vv {
+			vv[i] = redactValue(e)
+		}
+	}
+	return v
+}
+
+// redactedRequest returns a copy of req that is safe to log.
+// The original body is left untouched; the copy only carries a body
+// when it can be replayed through req.GetBody.
+func redactedRequest(req *http.Request) *http.Request {
+	ret := req.Clone(req.Context())
+	ret.Header = redactHeader(req.Header)
+	ret.Body = nil
+	if req.GetBody == nil {
+		return ret
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ret
+	}
+	defer body.Close()
+	bs, err := io.ReadAll(body)
+	if err != nil {
+		return ret
+	}
+	ret.Body = io.NopCloser(bytes.NewReader(redactJSON(bs)))
+	return ret
+}
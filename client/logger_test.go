@@ -0,0 +1,103 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDebugLogRedactsSecrets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"result":"success"}`))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	c := NewClientWithConfig(&ClientConfig{
+		BaseUrl: srv.URL,
+		ApiKey:  "app-very-secret",
+		Logger:  NewStdLogger(log.New(&buf, "", 0)),
+		Debug:   true,
+	})
+
+	body := map[string]interface{}{
+		"user": "test-user",
+		"inputs": map[string]interface{}{
+			"api_key": "sk-inner-secret",
+			"query":   "hello",
+		},
+	}
+	req, err := c.CreateBaseRequest(context.Background(), http.MethodPost, "/chat-messages", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rsp struct {
+		Result string `json:"result"`
+	}
+	if err := c.SendJSONRequest(req, &rsp); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, secret := range []string{"app-very-secret", "sk-inner-secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log output leaks %q:\n%s", secret, out)
+		}
+	}
+	for _, want := range []string{"curl", "Bearer ******", "hello", "status=200"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output missing %q:\n%s", want, out)
+		}
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer app-very-secret" {
+		t.Errorf("request header was modified: %q", got)
+	}
+}
+
+func TestDefaultClientIsSilent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	c := NewClient(srv.URL, "app-very-secret")
+	req, err := c.CreateBaseRequest(context.Background(), http.MethodGet, "/info", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rsp map[string]interface{}
+	if err := c.SendJSONRequest(req, &rsp); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got:\n%s", buf.String())
+	}
+}
+
+func TestIsSecretKey(t *testing.T) {
+	cases := map[string]bool{
+		"Authorization": true,
+		"api_key":       true,
+		"X-Api-Key":     true,
+		"access_token":  true,
+		"password":      true,
+		"total_tokens":  false,
+		"task_id":       false,
+		"query":         false,
+	}
+	for name, want := range cases {
+		if got := isSecretKey(name); got != want {
+			t.Errorf("isSecretKey(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
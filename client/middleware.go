@@ -0,0 +1,44 @@
+package client
+
+import "net/http"
+
+// Handler sends a request and returns its response.
+type Handler func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps a Handler to run code around every HTTP call made by a Client,
+// e.g. to inject headers, refresh credentials, record metrics or inject faults.
+//
+// Middlewares run once per attempt, so with a RetryPolicy they also see the retries.
+type Middleware func(next Handler) Handler
+
+// BeforeRequest returns a Middleware calling f before the request is sent.
+// A non-nil error from f aborts the call.
+func BeforeRequest(f func(req *http.Request) error) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			if err := f(req); err != nil {
+				return nil, err
+			}
+			return next(req)
+		}
+	}
+}
+
+// AfterResponse returns a Middleware calling f with the outcome of each call.
+// f may inspect or replace the response and error; the result of f is returned to the caller.
+func AfterResponse(f func(req *http.Request, resp *http.Response, err error) (*http.Response, error)) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			resp, err := next(req)
+			return f(req, resp, err)
+		}
+	}
+}
+
+// Chain composes middlewares so that the first one is the outermost.
+func Chain(h Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareOrderAndHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Tenant"); got != "acme" {
+			t.Errorf("X-Tenant = %q, want %q", got, "acme")
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	var trace []string
+	around := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(req *http.Request) (*http.Response, error) {
+				trace = append(trace, name+":before")
+				resp, err := next(req)
+				trace = append(trace, name+":after")
+				return resp, err
+			}
+		}
+	}
+	var status int
+	c := NewClientWithConfig(&ClientConfig{
+		BaseUrl: srv.URL,
+		Middlewares: []Middleware{
+			around("outer"),
+			BeforeRequest(func(req *http.Request) error {
+				req.Header.Set("X-Tenant", "acme")
+				return nil
+			}),
+			AfterResponse(func(req *http.Request, resp *http.Response, err error) (*http.Response, error) {
+				if resp != nil {
+					status = resp.StatusCode
+				}
+				return resp, err
+			}),
+			around("inner"),
+		},
+	})
+
+	req, _ := c.CreateBaseRequest(context.Background(), http.MethodGet, "/info", nil)
+	if err := c.SendJSONRequest(req, &struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "outer:before,inner:before,inner:after,outer:after"
+	if got := strings.Join(trace, ","); got != want {
+		t.Errorf("trace = %s, want %s", got, want)
+	}
+	if status != http.StatusOK {
+		t.Errorf("status = %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestBeforeRequestAbortsCall(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	denied := errors.New("denied")
+	c := NewClientWithConfig(&ClientConfig{
+		BaseUrl: srv.URL,
+		Middlewares: []Middleware{BeforeRequest(func(req *http.Request) error {
+			return denied
+		})},
+	})
+	req, _ := c.CreateBaseRequest(context.Background(), http.MethodGet, "/info", nil)
+	if err := c.SendJSONRequest(req, &struct{}{}); !errors.Is(err, denied) {
+		t.Fatalf("err = %v, want %v", err, denied)
+	}
+	if called {
+		t.Error("server should not be called")
+	}
+}
+
+func TestMiddlewareFaultInjectionWithRetry(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	attempts := 0
+	faulty := func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts == 1 {
+				return &http.Response{
+					StatusCode: http.StatusServiceUnavailable,
+					Header:     http.Header{},
+					Body:       http.NoBody,
+					Request:    req,
+				}, nil
+			}
+			return next(req)
+		}
+	}
+	c := NewClientWithConfig(&ClientConfig{
+		BaseUrl:     srv.URL,
+		Retry:       testRetryPolicy(),
+		Middlewares: []Middleware{faulty},
+	})
+	req, _ := c.CreateBaseRequest(context.Background(), http.MethodGet, "/info", nil)
+	if err := c.SendJSONRequest(req, &struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestCustomRoundTripper(t *testing.T) {
+	c := NewClientWithConfig(&ClientConfig{
+		BaseUrl: "http://dify.invalid",
+		Transport: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       http.NoBody,
+				Request:    req,
+			}, nil
+		}),
+	})
+	req, _ := c.CreateBaseRequest(context.Background(), http.MethodGet, "/info", nil)
+	resp, err := c.SendRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
@@ -0,0 +1,256 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures automatic retries of failed requests.
+// Unset fields take the values of DefaultRetryPolicy: a zero MaxAttempts,
+// InitialBackoff, MaxBackoff, Multiplier or MaxRetryAfter, and a nil
+// RetryableStatusCodes or RetryNetworkErrors. A zero Jitter disables jitter.
+// A partial policy thus only overrides the fields it sets:
+//
+//	&client.RetryPolicy{InitialBackoff: time.Second}
+//
+// Only idempotent requests are retried: GET, HEAD, OPTIONS, PUT and DELETE,
+// and requests whose context was marked with WithIdempotent (e.g. Stop calls).
+// Set RetryNonIdempotent to also retry POST endpoints such as run or chat,
+// which may execute the app twice.
+type RetryPolicy struct {
+	// Maximum number of attempts, including the first one. 1 disables retries.
+	MaxAttempts int
+	// Backoff before the first retry.
+	InitialBackoff time.Duration
+	// Upper bound of the exponential backoff.
+	MaxBackoff time.Duration
+	// Backoff growth factor between attempts.
+	Multiplier float64
+	// Random fraction of the backoff added or removed, in [0, 1].
+	Jitter float64
+	// Status codes that trigger a retry.
+	RetryableStatusCodes []int
+	// Whether network errors (connection reset/refused, timeouts, unexpected EOF) trigger a
+	// retry, true when nil. Set it with Bool, e.g. Bool(false).
+	RetryNetworkErrors *bool
+	// Upper bound applied to a server provided Retry-After delay.
+	MaxRetryAfter time.Duration
+	// Whether non-idempotent requests are retried too.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns a policy with 3 attempts, exponential backoff from 500ms to 30s
+// with 20% jitter, retrying network errors and 429/502/503/504 responses.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:          3,
+		InitialBackoff:       500 * time.Millisecond,
+		MaxBackoff:           30 * time.Second,
+		Multiplier:           2,
+		Jitter:               0.2,
+		RetryableStatusCodes: []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+		RetryNetworkErrors:   Bool(true),
+		MaxRetryAfter:        60 * time.Second,
+	}
+}
+
+// withDefaults returns a copy of p with unset fields filled from DefaultRetryPolicy.
+func (p *RetryPolicy) withDefaults() *RetryPolicy {
+	d := DefaultRetryPolicy()
+	ret := *p
+	if ret.MaxAttempts <= 0 {
+		ret.MaxAttempts = d.MaxAttempts
+	}
+	if ret.InitialBackoff <= 0 {
+		ret.InitialBackoff = d.InitialBackoff
+	}
+	if ret.MaxBackoff <= 0 {
+		ret.MaxBackoff = d.MaxBackoff
+	}
+	if ret.Multiplier < 1 {
+		ret.Multiplier = d.Multiplier
+	}
+	if ret.Jitter < 0 || ret.Jitter > 1 {
+		ret.Jitter = d.Jitter
+	}
+	if ret.RetryableStatusCodes == nil {
+		ret.RetryableStatusCodes = d.RetryableStatusCodes
+	}
+	if ret.MaxRetryAfter <= 0 {
+		ret.MaxRetryAfter = d.MaxRetryAfter
+	}
+	if ret.RetryNetworkErrors == nil {
+		ret.RetryNetworkErrors = d.RetryNetworkErrors
+	}
+	return &ret
+}
+
+// Bool returns a pointer to v, for optional fields such as RetryPolicy.RetryNetworkErrors.
+func Bool(v bool) *bool {
+	return &v
+}
+
+// backoff returns the delay before the given retry, starting at 1.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	d := float64(p.InitialBackoff)
+	for i := 1; i < retry; i++ {
+		d *= p.Multiplier
+		if d >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+func (p *RetryPolicy) retryableStatus(code int) bool {
+	for _, c := range p.RetryableStatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) retryableError(err error) bool {
+	if !*p.RetryNetworkErrors {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+type idempotentKey struct{}
+
+// WithIdempotent marks the requests created with ctx as safe to retry, even when their
+// method is POST. The SDK uses it for the stop calls: stopping a task twice is harmless.
+func WithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	v, _ := req.Context().Value(idempotentKey{}).(bool)
+	return v
+}
+
+// canRetry reports whether req may be sent again under the policy.
+func (p *RetryPolicy) canRetry(req *http.Request) bool {
+	if p.MaxAttempts < 2 {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	return p.RetryNonIdempotent || isIdempotent(req)
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// RetryEnabled reports whether the client is configured to retry requests.
+func (c *Client) RetryEnabled() bool {
+	return c.retry != nil && c.retry.MaxAttempts > 1
+}
+
+// sendWithRetry sends req, retrying it according to the client's retry policy.
+func (c *Client) sendWithRetry(req *http.Request) (*http.Response, error) {
+	if !c.RetryEnabled() || !c.retry.canRetry(req) {
+		return c.send(req)
+	}
+	p := c.retry
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.send(req)
+		if attempt >= p.MaxAttempts {
+			return resp, err
+		}
+
+		var wait time.Duration
+		switch {
+		case err != nil:
+			if !p.retryableError(err) {
+				return nil, err
+			}
+			wait = p.backoff(attempt)
+			c.logger.Warn("dify: retrying request", "method", req.Method, "url", req.URL.String(), "attempt", attempt, "wait", wait, "error", err)
+		case p.retryableStatus(resp.StatusCode):
+			wait = p.backoff(attempt)
+			if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				wait = d
+				if wait > p.MaxRetryAfter {
+					wait = p.MaxRetryAfter
+				}
+			}
+			c.logger.Warn("dify: retrying request", "method", req.Method, "url", req.URL.String(), "attempt", attempt, "wait", wait, "status", resp.StatusCode)
+			io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodySize))
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		if err := sleepContext(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testRetryPolicy() *RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.InitialBackoff = time.Millisecond
+	p.MaxBackoff = 5 * time.Millisecond
+	p.Jitter = 0
+	return p
+}
+
+// flakyServer fails the first n calls with status, then answers {"result":"success"}.
+func flakyServer(t *testing.T, n int32, status int, header http.Header) (*httptest.Server, *int32) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, _ := io.ReadAll(r.Body)
+		if r.Method == http.MethodPost && string(bs) != `{"user":"test-user"}` {
+			t.Errorf("unexpected body on attempt %d: %q", atomic.LoadInt32(&calls)+1, bs)
+		}
+		if atomic.AddInt32(&calls, 1) <= n {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			w.Write([]byte(`{"code":"too_many_requests","message":"busy","status":429}`))
+			return
+		}
+		w.Write([]byte(`{"result":"success"}`))
+	}))
+	return srv, &calls
+}
+
+func TestRetryGet(t *testing.T) {
+	srv, calls := flakyServer(t, 2, http.StatusServiceUnavailable, nil)
+	defer srv.Close()
+
+	c := NewClientWithConfig(&ClientConfig{BaseUrl: srv.URL, Retry: testRetryPolicy()})
+	req, _ := c.CreateBaseRequest(context.Background(), http.MethodGet, "/parameters", nil)
+	var rsp struct{ Result string }
+	if err := c.SendJSONRequest(req, &rsp); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 3 {
+		t.Errorf("calls = %d, want 3", *calls)
+	}
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	srv, calls := flakyServer(t, 10, http.StatusTooManyRequests, nil)
+	defer srv.Close()
+
+	c := NewClientWithConfig(&ClientConfig{BaseUrl: srv.URL, Retry: testRetryPolicy()})
+	req, _ := c.CreateBaseRequest(context.Background(), http.MethodGet, "/parameters", nil)
+	err := c.SendJSONRequest(req, &struct{}{})
+	if !IsRateLimited(err) {
+		t.Fatalf("expected rate limited error, got %v", err)
+	}
+	if *calls != 3 {
+		t.Errorf("calls = %d, want 3", *calls)
+	}
+}
+
+func TestRetryPostOnlyWhenAllowed(t *testing.T) {
+	body := map[string]string{"user": "test-user"}
+
+	t.Run("not retried by default", func(t *testing.T) {
+		srv, calls := flakyServer(t, 1, http.StatusBadGateway, nil)
+		defer srv.Close()
+
+		c := NewClientWithConfig(&ClientConfig{BaseUrl: srv.URL, Retry: testRetryPolicy()})
+		req, _ := c.CreateBaseRequest(context.Background(), http.MethodPost, "/workflows/run", body)
+		if err := c.SendJSONRequest(req, &struct{}{}); err == nil {
+			t.Fatal("expected error")
+		}
+		if *calls != 1 {
+			t.Errorf("calls = %d, want 1", *calls)
+		}
+	})
+
+	t.Run("idempotent context", func(t *testing.T) {
+		srv, calls := flakyServer(t, 1, http.StatusBadGateway, nil)
+		defer srv.Close()
+
+		c := NewClientWithConfig(&ClientConfig{BaseUrl: srv.URL, Retry: testRetryPolicy()})
+		req, _ := c.CreateBaseRequest(WithIdempotent(context.Background()), http.MethodPost, "/workflows/tasks/1/stop", body)
+		if err := c.SendJSONRequest(req, &struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+		if *calls != 2 {
+			t.Errorf("calls = %d, want 2", *calls)
+		}
+	})
+
+	t.Run("non idempotent opt-in", func(t *testing.T) {
+		srv, calls := flakyServer(t, 1, http.StatusBadGateway, nil)
+		defer srv.Close()
+
+		p := testRetryPolicy()
+		p.RetryNonIdempotent = true
+		c := NewClientWithConfig(&ClientConfig{BaseUrl: srv.URL, Retry: p})
+		req, _ := c.CreateBaseRequest(context.Background(), http.MethodPost, "/workflows/run", body)
+		if err := c.SendJSONRequest(req, &struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+		if *calls != 2 {
+			t.Errorf("calls = %d, want 2", *calls)
+		}
+	})
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	srv, calls := flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+	defer srv.Close()
+
+	p := testRetryPolicy()
+	p.MaxRetryAfter = 50 * time.Millisecond
+	c := NewClientWithConfig(&ClientConfig{BaseUrl: srv.URL, Retry: p})
+	req, _ := c.CreateBaseRequest(context.Background(), http.MethodGet, "/info", nil)
+
+	start := time.Now()
+	if err := c.SendJSONRequest(req, &struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("elapsed = %v, want at least the capped Retry-After", elapsed)
+	}
+	if *calls != 2 {
+		t.Errorf("calls = %d, want 2", *calls)
+	}
+}
+
+func TestRetryNetworkError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := srv.URL
+	srv.Close()
+
+	c := NewClientWithConfig(&ClientConfig{BaseUrl: url, Retry: testRetryPolicy()})
+	req, _ := c.CreateBaseRequest(context.Background(), http.MethodGet, "/info", nil)
+	var attempts int
+	c.logger = loggerFunc(func(msg string) {
+		if msg == "dify: retrying request" {
+			attempts++
+		}
+	})
+	if err := c.SendJSONRequest(req, &struct{}{}); err == nil {
+		t.Fatal("expected error")
+	}
+	if attempts != 2 {
+		t.Errorf("retries = %d, want 2", attempts)
+	}
+}
+
+func TestRetryPartialPolicy(t *testing.T) {
+	srv, calls := flakyServer(t, 2, http.StatusServiceUnavailable, nil)
+	defer srv.Close()
+
+	p := &RetryPolicy{InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	c := NewClientWithConfig(&ClientConfig{BaseUrl: srv.URL, Retry: p})
+	req, _ := c.CreateBaseRequest(context.Background(), http.MethodGet, "/parameters", nil)
+	if err := c.SendJSONRequest(req, &struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 3 {
+		t.Errorf("calls = %d, want 3", *calls)
+	}
+	if !*c.retry.RetryNetworkErrors {
+		t.Error("network errors are not retried by default")
+	}
+
+	p.RetryNetworkErrors = Bool(false)
+	if p.withDefaults().retryableError(io.ErrUnexpectedEOF) {
+		t.Error("network errors are retried with RetryNetworkErrors set to false")
+	}
+}
+
+func TestRetryStopsOnContextCancel(t *testing.T) {
+	srv, _ := flakyServer(t, 10, http.StatusServiceUnavailable, nil)
+	defer srv.Close()
+
+	p := testRetryPolicy()
+	p.InitialBackoff = time.Hour
+	p.MaxBackoff = time.Hour
+	c := NewClientWithConfig(&ClientConfig{BaseUrl: srv.URL, Retry: p})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := c.CreateBaseRequest(ctx, http.MethodGet, "/info", nil)
+	if err := c.SendJSONRequest(req, &struct{}{}); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"Wed, 01 Jan 2025 00:00:10 GMT", 10 * time.Second, true},
+		{"soon", 0, false},
+	}
+	for _, tc := range cases {
+		got, ok := parseRetryAfter(tc.in, now)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+// loggerFunc is a Logger calling f with the message of every entry.
+type loggerFunc func(msg string)
+
+func (f loggerFunc) Debug(msg string, args ...interface{}) { f(msg) }
+func (f loggerFunc) Info(msg string, args ...interface{})  { f(msg) }
+func (f loggerFunc) Warn(msg string, args ...interface{})  { f(msg) }
+func (f loggerFunc) Error(msg string, args ...interface{}) { f(msg) }
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+)
+
+// SSEEvent is a server-sent event.
+type SSEEvent struct {
+	// Event type, "message" when the stream did not set one.
+	Event string
+	// Data lines of the event joined with "\n", nil for an event without data.
+	Data []byte
+	// Last event ID seen on the stream.
+	Id string
+	// Reconnection time in milliseconds requested by the server, 0 if unset.
+	Retry int
+}
+
+// SSEDecoder reads server-sent events from a text/event-stream body, following
+// https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation:
+// lines end with CRLF, LF or CR, comments and unknown fields are ignored, multiple
+// "data:" lines are joined, and events without data, such as Dify's "event: ping",
+// are skipped unless KeepTypedEvents is set. Lines may be of any length.
+type SSEDecoder struct {
+	// KeepTypedEvents makes Decode return the events without data that have an event
+	// type, e.g. "event: ping", with a nil Data.
+	KeepTypedEvents bool
+
+	r      io.Reader
+	buf    []byte
+	start  int
+	end    int
+	err    error
+	skipLF bool
+
+	lastId string
+}
+
+const sseInitialBufferSize = 4096
+
+// NewSSEDecoder returns a decoder reading events from r.
+func NewSSEDecoder(r io.Reader) *SSEDecoder {
+	return &SSEDecoder{r: r, buf: make([]byte, sseInitialBufferSize)}
+}
+
+// Decode returns the next event. It returns io.EOF at the end of the stream, an event
+// not terminated by a blank line is discarded.
+func (d *SSEDecoder) Decode() (*SSEEvent, error) {
+	var (
+		eventType string
+		data      []byte
+		hasData   bool
+		retry     int
+	)
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 {
+			if !hasData {
+				if d.KeepTypedEvents && eventType != "" {
+					return &SSEEvent{Event: eventType, Id: d.lastId, Retry: retry}, nil
+				}
+				// nothing to dispatch, e.g. a ping or a lone id field
+				eventType, retry = "", 0
+				continue
+			}
+			if eventType == "" {
+				eventType = "message"
+			}
+			return &SSEEvent{Event: eventType, Data: data, Id: d.lastId, Retry: retry}, nil
+		}
+		if line[0] == ':' {
+			continue
+		}
+
+		field, value := line, []byte(nil)
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], line[i+1:]
+			if len(value) > 0 && value[0] == ' ' {
+				value = value[1:]
+			}
+		}
+		switch string(field) {
+		case "event":
+			eventType = string(value)
+		case "data":
+			if hasData {
+				data = append(data, '\n')
+			}
+			if data == nil {
+				// tell an empty data line from no data at all
+				data = []byte{}
+			}
+			data = append(data, value...)
+			hasData = true
+		case "id":
+			if bytes.IndexByte(value, 0) < 0 {
+				d.lastId = string(value)
+			}
+		case "retry":
+			if n, err := strconv.Atoi(string(value)); err == nil && n >= 0 && isDigits(value) {
+				retry = n
+			}
+		}
+	}
+}
+
+func isDigits(bs []byte) bool {
+	for _, b := range bs {
+		if b < '0' || b > '9' {
+			return false
+		}
+	}
+	return len(bs) > 0
+}
+
+// readLine returns the next line without its terminator. The line is only valid
+// until the next call.
+func (d *SSEDecoder) readLine() ([]byte, error) {
+	for {
+		if d.skipLF && d.start < d.end {
+			if d.buf[d.start] == '\n' {
+				d.start++
+			}
+			d.skipLF = false
+		}
+		if i := bytes.IndexAny(d.buf[d.start:d.end], "\r\n"); i >= 0 {
+			line := d.buf[d.start : d.start+i]
+			d.start += i + 1
+			if d.buf[d.start-1] == '\r' {
+				// a CRLF may be split across two reads
+				d.skipLF = true
+			}
+			return line, nil
+		}
+		if d.err != nil {
+			// a last line without terminator is an incomplete event
+			return nil, d.err
+		}
+		d.fill()
+	}
+}
+
+// fill reads more data, moving the pending bytes to the front of the buffer or
+// growing it when a line does not fit.
+func (d *SSEDecoder) fill() {
+	if d.start > 0 {
+		n := copy(d.buf, d.buf[d.start:d.end])
+		d.start, d.end = 0, n
+	}
+	if d.end == len(d.buf) {
+		buf := make([]byte, 2*len(d.buf))
+		copy(buf, d.buf[:d.end])
+		d.buf = buf
+	}
+	n, err := d.r.Read(d.buf[d.end:])
+	d.end += n
+	if err != nil {
+		d.err = err
+	}
+}
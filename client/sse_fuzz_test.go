@@ -0,0 +1,34 @@
+//go:build go1.18
+// +build go1.18
+
+package client
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// FuzzSSEDecoder checks that decoding never fails on arbitrary input and does not
+// depend on how the input is split into reads.
+func FuzzSSEDecoder(f *testing.F) {
+	f.Add("event: ping\n\ndata: {\"event\":\"message\"}\n\n")
+	f.Add("data: a\r\ndata: b\r\rid: 1\nretry: 10\n\n: comment\n")
+	f.Add("data:\n\nevent\ndata\n\n\r")
+	f.Fuzz(func(t *testing.T, input string) {
+		whole := decodeAll(t, strings.NewReader(input))
+		split := decodeAll(t, iotest.OneByteReader(strings.NewReader(input)))
+		if !reflect.DeepEqual(whole, split) {
+			t.Fatalf("whole read %q, byte by byte %q", whole, split)
+		}
+		for _, e := range whole {
+			if e.Event == "" {
+				t.Fatalf("event without type: %q", e)
+			}
+			if strings.ContainsAny(e.Event, "\r\n") || strings.ContainsAny(e.Id, "\r\n\x00") {
+				t.Fatalf("line terminator in field: %q", e)
+			}
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func decodeAll(t testing.TB, r io.Reader) []SSEEvent {
+	d := NewSSEDecoder(r)
+	var ret []SSEEvent
+	for {
+		e, err := d.Decode()
+		if err == io.EOF {
+			return ret
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret = append(ret, *e)
+	}
+}
+
+func TestSSEDecoder(t *testing.T) {
+	long := strings.Repeat("x", 3*sseInitialBufferSize+17)
+	cases := []struct {
+		name  string
+		input string
+		want  []SSEEvent
+	}{
+		{
+			name:  "dify",
+			input: "event: ping\n\ndata: {\"event\":\"message\"}\n\ndata: {\"event\":\"message_end\"}\n\n",
+			want: []SSEEvent{
+				{Event: "message", Data: []byte(`{"event":"message"}`)},
+				{Event: "message", Data: []byte(`{"event":"message_end"}`)},
+			},
+		},
+		{
+			name:  "no space after colon",
+			input: "data:{}\n\n",
+			want:  []SSEEvent{{Event: "message", Data: []byte(`{}`)}},
+		},
+		{
+			name:  "multi-line data",
+			input: "data: a\ndata:  b\ndata\n\n",
+			want:  []SSEEvent{{Event: "message", Data: []byte("a\n b\n")}},
+		},
+		{
+			name:  "event type, id and retry",
+			input: "event: update\nid: 7\nretry: 1500\ndata: x\n\ndata: y\n\n",
+			want: []SSEEvent{
+				{Event: "update", Data: []byte("x"), Id: "7", Retry: 1500},
+				{Event: "message", Data: []byte("y"), Id: "7"},
+			},
+		},
+		{
+			name:  "comments and unknown fields",
+			input: ": keep-alive\nfoo: bar\ndata: x\n\n",
+			want:  []SSEEvent{{Event: "message", Data: []byte("x")}},
+		},
+		{
+			name:  "CRLF and CR",
+			input: "data: a\r\n\r\ndata: b\r\rdata: c\r\n\n",
+			want: []SSEEvent{
+				{Event: "message", Data: []byte("a")},
+				{Event: "message", Data: []byte("b")},
+				{Event: "message", Data: []byte("c")},
+			},
+		},
+		{
+			name:  "invalid retry and id with NUL",
+			input: "retry: 1s\nid: a\x00b\ndata: x\n\n",
+			want:  []SSEEvent{{Event: "message", Data: []byte("x")}},
+		},
+		{
+			name:  "empty data is dispatched",
+			input: "data:\n\n",
+			want:  []SSEEvent{{Event: "message", Data: []byte{}}},
+		},
+		{
+			name:  "long line",
+			input: "data: " + long + "\n\n",
+			want:  []SSEEvent{{Event: "message", Data: []byte(long)}},
+		},
+		{
+			name:  "incomplete last event",
+			input: "data: a\n\ndata: b\n",
+			want:  []SSEEvent{{Event: "message", Data: []byte("a")}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			for name, r := range map[string]io.Reader{
+				"whole":    strings.NewReader(c.input),
+				"one byte": iotest.OneByteReader(strings.NewReader(c.input)),
+			} {
+				got := decodeAll(t, r)
+				for i := range got {
+					if len(got[i].Data) == 0 {
+						got[i].Data = []byte{}
+					}
+				}
+				if !reflect.DeepEqual(got, c.want) {
+					t.Errorf("%s: got %q, want %q", name, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSSEDecoderKeepTypedEvents(t *testing.T) {
+	d := NewSSEDecoder(strings.NewReader(": comment\n\nevent: ping\n\nid: 1\n\ndata:\n\n"))
+	d.KeepTypedEvents = true
+	var got []SSEEvent
+	for {
+		e, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, *e)
+	}
+	want := []SSEEvent{{Event: "ping"}, {Event: "message", Data: []byte{}, Id: "1"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSSEDecoderReadError(t *testing.T) {
+	d := NewSSEDecoder(io.MultiReader(strings.NewReader("data: a\n\ndata: b"), iotest.ErrReader(io.ErrUnexpectedEOF)))
+	if e, err := d.Decode(); err != nil || string(e.Data) != "a" {
+		t.Fatalf("Decode() = %v, %v", e, err)
+	}
+	if _, err := d.Decode(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("err = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func benchmarkSSEDecoder(b *testing.B, event string, n int) {
+	input := bytes.Repeat([]byte(event), n)
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d := NewSSEDecoder(bytes.NewReader(input))
+		for {
+			if _, err := d.Decode(); err != nil {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkSSEDecoderSmallEvents(b *testing.B) {
+	benchmarkSSEDecoder(b, `data: {"event":"message","task_id":"t","id":"m","answer":"hi","created_at":1}`+"\n\n", 1000)
+}
+
+func BenchmarkSSEDecoderLargeEvents(b *testing.B) {
+	benchmarkSSEDecoder(b, "data: "+strings.Repeat("x", 256<<10)+"\n\n", 4)
+}
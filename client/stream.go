@@ -0,0 +1,227 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// EventStream iterates over the JSON events of a streaming response:
+//
+//	stream, err := c.SendStreamRequest(req)
+//	if err != nil {
+//		return err
+//	}
+//	defer stream.Close()
+//	for stream.Next() {
+//		handle(stream.Event(), stream.Data())
+//	}
+//	return stream.Err()
+//
+// Pings are skipped unless KeepPings is called. The body is closed by Close, at the end of the stream, on read
+// errors and once the context of the request is done, so an abandoned stream does
+// not hold the connection.
+type EventStream struct {
+	ctx     context.Context
+	body    io.ReadCloser
+	decoder *SSEDecoder
+	event   string
+	data    []byte
+	err     error
+	closed  bool
+	// keepPings makes Next return the pings
+	keepPings bool
+
+	// task ID of the first event carrying one
+	taskId string
+	// stop, when set, stops the task if ctx is canceled before the end of the stream
+	stop func(taskId string)
+	// meter, when set, counts the usage of the stream against a Budget
+	meter *budgetMeter
+	// mu guards closed, ended and closedByCaller; closing is closed along with the
+	// stream, ended reports whether the stream was read to the end, closedByCaller
+	// whether Close was called while it was still open and ctx not yet canceled
+	mu             sync.Mutex
+	closing        chan struct{}
+	ended          bool
+	closedByCaller bool
+}
+
+// NewEventStream returns an EventStream reading the body of resp.
+func NewEventStream(ctx context.Context, resp *http.Response) *EventStream {
+	return &EventStream{ctx: ctx, body: resp.Body, decoder: NewSSEDecoder(resp.Body), closing: make(chan struct{})}
+}
+
+// SendStreamRequest sends a streaming request. A non-200 response is returned as an *APIError.
+func (c *Client) SendStreamRequest(req *http.Request) (*EventStream, error) {
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, NewAPIError(resp)
+	}
+	return NewEventStream(req.Context(), resp), nil
+}
+
+// Next advances to the next event, it returns false at the end of the stream, on error
+// or after Close.
+func (s *EventStream) Next() bool {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		return false
+	}
+	for {
+		if err := s.ctx.Err(); err != nil {
+			s.fail(err)
+			return false
+		}
+		e, err := s.decoder.Decode()
+		if err != nil {
+			if err == io.EOF {
+				s.End()
+			} else if ctxErr := s.ctx.Err(); ctxErr != nil {
+				// reads fail once the request is canceled, report why
+				s.fail(ctxErr)
+			} else {
+				s.fail(fmt.Errorf("error reading event: %w", err))
+			}
+			return false
+		}
+
+		if e.Data == nil {
+			// a typed event without data, only pings are kept
+			if e.Event != "ping" {
+				continue
+			}
+			e.Data = []byte(`{"event":"ping"}`)
+		}
+
+		var head struct {
+			Event  string `json:"event"`
+			TaskId string `json:"task_id"`
+		}
+		if err := json.Unmarshal(e.Data, &head); err != nil {
+			s.fail(fmt.Errorf("error unmarshalling event: %w", err))
+			return false
+		}
+		if s.taskId == "" && head.TaskId != "" {
+			s.taskId = head.TaskId
+			if s.stop != nil {
+				go s.stopOnCancel(s.taskId)
+			}
+		}
+		if head.Event == "ping" {
+			if !s.keepPings {
+				continue
+			}
+			s.event, s.data = head.Event, e.Data
+			return true
+		}
+		if s.meter != nil {
+			if err := s.meter.add(head.Event, e.Data, s.taskId); err != nil {
+				s.fail(err)
+				return false
+			}
+		}
+		s.event, s.data = head.Event, e.Data
+		return true
+	}
+}
+
+// KeepPings makes Next return the keep-alive pings of the stream, as "ping" events,
+// instead of skipping them, from the next event on. Next must not run concurrently.
+func (s *EventStream) KeepPings() {
+	s.keepPings = true
+	s.decoder.KeepTypedEvents = true
+}
+
+// Event returns the "event" field of the current event, e.g. "message".
+func (s *EventStream) Event() string {
+	return s.event
+}
+
+// TaskId returns the task ID of the stream, from the first event carrying one.
+func (s *EventStream) TaskId() string {
+	return s.taskId
+}
+
+// Data returns the JSON data of the current event.
+func (s *EventStream) Data() []byte {
+	return s.data
+}
+
+// Err returns the error that ended the stream, nil at the end of the stream or after Close.
+func (s *EventStream) Err() error {
+	return s.err
+}
+
+// Close ends the stream and closes the response body, it is safe to call it several times.
+// AutoStop leaves the task of a stream closed before its context is canceled alone.
+func (s *EventStream) Close() error {
+	return s.close(true)
+}
+
+// End marks the stream as read to the end and closes it. Callers that stop reading on
+// the last event of a stream, e.g. message_end, call it instead of Close.
+func (s *EventStream) End() error {
+	s.mu.Lock()
+	s.ended = true
+	s.mu.Unlock()
+	return s.close(false)
+}
+
+func (s *EventStream) close(byCaller bool) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	// a Close after the cancellation, e.g. deferred, does not spare the task
+	s.closedByCaller = byCaller && s.ctx.Err() == nil
+	close(s.closing)
+	s.mu.Unlock()
+	return s.body.Close()
+}
+
+// stopOnCancel calls stop when ctx is canceled while the stream is still in progress.
+func (s *EventStream) stopOnCancel(taskId string) {
+	select {
+	case <-s.ctx.Done():
+	case <-s.closing:
+		// reads fail and close the stream once ctx is canceled
+		if s.ctx.Err() == nil {
+			return
+		}
+	}
+	s.mu.Lock()
+	skip := s.ended || s.closedByCaller
+	s.mu.Unlock()
+	if !skip {
+		s.stop(taskId)
+	}
+}
+
+func (s *EventStream) fail(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+	s.close(false)
+}
+
+// StreamEventError returns the *APIError carried by the data of an "error" stream event.
+func StreamEventError(data []byte) error {
+	e := &APIError{Body: data}
+	if err := json.Unmarshal(data, e); err != nil {
+		return fmt.Errorf("error unmarshalling error event: %w", err)
+	}
+	e.StatusCode = e.Status
+	return e
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newStreamRequest(t *testing.T, ctx context.Context, c *Client) *http.Request {
+	t.Helper()
+	req, err := c.CreateBaseRequest(ctx, http.MethodPost, "/chat-messages", map[string]interface{}{"response_mode": "streaming"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestEventStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "event: ping\n\ndata: {\"event\":\"ping\"}\n\ndata: {\"event\":\"message\",\"answer\":\"hi\"}\n\n"+
+			"data: {\"event\":\"message_end\"}\n\n")
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL, "app-test")
+
+	stream, err := c.SendStreamRequest(newStreamRequest(t, context.Background(), c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	var events []string
+	for stream.Next() {
+		events = append(events, stream.Event())
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(events, ",") != "message,message_end" {
+		t.Errorf("events = %v", events)
+	}
+	if stream.Next() {
+		t.Error("Next after the end of the stream")
+	}
+}
+
+func TestEventStreamKeepPings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "event: ping\n\ndata: {\"event\":\"ping\"}\n\nevent: other\n\ndata: {\"event\":\"message\",\"answer\":\"hi\"}\n\n")
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL, "app-test")
+
+	stream, err := c.SendStreamRequest(newStreamRequest(t, context.Background(), c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	stream.KeepPings()
+	var events []string
+	for stream.Next() {
+		events = append(events, stream.Event()+" "+string(stream.Data()))
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := `ping {"event":"ping"},ping {"event":"ping"},message {"event":"message","answer":"hi"}`
+	if got := strings.Join(events, ","); got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+}
+
+func TestEventStreamHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"code":"conversation_not_exists","message":"Conversation Not Exists.","status":404}`)
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL, "app-test")
+
+	_, err := c.SendStreamRequest(newStreamRequest(t, context.Background(), c))
+	if !IsNotFound(err) || !HasErrorCode(err, ErrCodeConversationNotExists) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
+
+func TestEventStreamReleasesConnection(t *testing.T) {
+	gone := make(chan struct{}, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for {
+			if _, err := io.WriteString(w, "data: {\"event\":\"message\"}\n\n"); err != nil {
+				break
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				gone <- struct{}{}
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+		gone <- struct{}{}
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL, "app-test")
+
+	t.Run("close", func(t *testing.T) {
+		stream, err := c.SendStreamRequest(newStreamRequest(t, context.Background(), c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stream.Next() {
+			t.Fatal(stream.Err())
+		}
+		stream.Close()
+		if stream.Next() || stream.Err() != nil {
+			t.Errorf("Next or Err after Close: %v", stream.Err())
+		}
+		waitGone(t, gone)
+	})
+
+	t.Run("context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		stream, err := c.SendStreamRequest(newStreamRequest(t, ctx, c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stream.Next() {
+			t.Fatal(stream.Err())
+		}
+		cancel()
+		for stream.Next() {
+		}
+		if stream.Err() != context.Canceled {
+			t.Errorf("err = %v, want %v", stream.Err(), context.Canceled)
+		}
+		waitGone(t, gone)
+	})
+}
+
+func waitGone(t *testing.T, gone chan struct{}) {
+	t.Helper()
+	select {
+	case <-gone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server is still streaming")
+	}
+}
+
+func TestStreamEventError(t *testing.T) {
+	err := StreamEventError([]byte(`{"event":"error","status":429,"code":"too_many_requests","message":"slow down"}`))
+	if !IsRateLimited(err) {
+		t.Fatalf("expected a rate limit error, got %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package client
+
+// Usage is the model usage reported in the metadata of a message.
+// Prices are decimal strings, as returned by Dify.
+type Usage struct {
+	PromptTokens        int     `json:"prompt_tokens"`
+	PromptUnitPrice     string  `json:"prompt_unit_price"`
+	PromptPriceUnit     string  `json:"prompt_price_unit"`
+	PromptPrice         string  `json:"prompt_price"`
+	CompletionTokens    int     `json:"completion_tokens"`
+	CompletionUnitPrice string  `json:"completion_unit_price"`
+	CompletionPriceUnit string  `json:"completion_price_unit"`
+	CompletionPrice     string  `json:"completion_price"`
+	TotalTokens         int     `json:"total_tokens"`
+	TotalPrice          string  `json:"total_price"`
+	Currency            string  `json:"currency"`
+	Latency             float64 `json:"latency"`
+}
+
+// RetrieverResource is a knowledge base citation of a message.
+type RetrieverResource struct {
+	Position     int     `json:"position"`
+	DatasetId    string  `json:"dataset_id"`
+	DatasetName  string  `json:"dataset_name"`
+	DocumentId   string  `json:"document_id"`
+	DocumentName string  `json:"document_name"`
+	SegmentId    string  `json:"segment_id"`
+	Score        float64 `json:"score"`
+	Content      string  `json:"content"`
+}
@@ -7,6 +7,10 @@ import (
 )
 
 type CompletionClient interface {
+	// Send Completion Message in blocking mode
+	SendMessage(ctx context.Context, req *CompletionMessageRequest) (*CompletionMessageResponse, error)
+	// Send Completion Message in streaming mode
+	SendMessageStream(ctx context.Context, req *CompletionMessageRequest) (*Stream, error)
 	// Stop Generate
 	Stop(ctx context.Context, req *StopRequest) (*StopResponse, error)
 }
@@ -20,3 +24,9 @@ func NewCompletionClient(baseUrl string, apiKey string) CompletionClient {
 	c.Client = client.NewClient(baseUrl, apiKey)
 	return c
 }
+
+func NewCompletionClientWithConfig(config *client.ClientConfig) CompletionClient {
+	c := new(completionClient)
+	c.Client = client.NewClientWithConfig(config)
+	return c
+}
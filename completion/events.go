@@ -0,0 +1,112 @@
+package completion
+
+import (
+	"encoding/json"
+
+	"github.com/taadis/dify-sdk-go/client"
+)
+
+// Stream event types of a completion message.
+const (
+	EventMessage        = "message"
+	EventMessageEnd     = "message_end"
+	EventMessageReplace = "message_replace"
+	EventTTSMessage     = "tts_message"
+	EventTTSMessageEnd  = "tts_message_end"
+	EventError          = "error"
+	EventPing           = "ping" // keep-alive, skipped by the streams
+)
+
+// Event is a completion stream event. Its dynamic type is one of the *...Event types
+// of this package, *UnknownEvent for the events this SDK does not know yet.
+type Event interface {
+	EventType() string
+}
+
+// EventBase holds the fields shared by the completion stream events.
+type EventBase struct {
+	Event     string `json:"event"`
+	TaskId    string `json:"task_id"`
+	MessageId string `json:"message_id"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+func (e *EventBase) EventType() string {
+	return e.Event
+}
+
+// Metadata is the usage and the citations of a message.
+type Metadata struct {
+	Usage              client.Usage               `json:"usage"`
+	RetrieverResources []client.RetrieverResource `json:"retriever_resources,omitempty"`
+}
+
+// MessageEvent is a chunk of the answer.
+type MessageEvent struct {
+	EventBase
+	// Message ID
+	Id     string `json:"id"`
+	Answer string `json:"answer"`
+}
+
+// MessageEndEvent ends the message with its usage.
+type MessageEndEvent struct {
+	EventBase
+	// Message ID
+	Id       string   `json:"id"`
+	Metadata Metadata `json:"metadata"`
+}
+
+// MessageReplaceEvent replaces the whole answer, e.g. after content moderation.
+type MessageReplaceEvent struct {
+	EventBase
+	Answer string `json:"answer"`
+}
+
+// TTSMessageEvent is a chunk of base64 encoded audio, tts_message_end has no audio.
+type TTSMessageEvent struct {
+	EventBase
+	Audio string `json:"audio"`
+}
+
+// ErrorEvent reports an error that ended the stream.
+type ErrorEvent struct {
+	EventBase
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// UnknownEvent is an event type this SDK does not decode, Raw holds its JSON.
+type UnknownEvent struct {
+	EventBase
+	Raw json.RawMessage `json:"-"`
+}
+
+// DecodeEvent decodes the JSON data of a completion stream event.
+func DecodeEvent(data []byte) (Event, error) {
+	var base EventBase
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, err
+	}
+
+	var e Event
+	switch base.Event {
+	case EventMessage:
+		e = new(MessageEvent)
+	case EventMessageEnd:
+		e = new(MessageEndEvent)
+	case EventMessageReplace:
+		e = new(MessageReplaceEvent)
+	case EventTTSMessage, EventTTSMessageEnd:
+		e = new(TTSMessageEvent)
+	case EventError:
+		e = new(ErrorEvent)
+	default:
+		return &UnknownEvent{EventBase: base, Raw: append(json.RawMessage(nil), data...)}, nil
+	}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
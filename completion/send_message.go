@@ -0,0 +1,238 @@
+package completion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/taadis/dify-sdk-go/client"
+	"github.com/taadis/dify-sdk-go/workflow"
+)
+
+// Response modes of CompletionMessageRequest.
+const (
+	ResponseModeBlocking  = "blocking"
+	ResponseModeStreaming = "streaming"
+)
+
+type CompletionMessageRequest struct {
+	// Values of the variables defined by the app, the text to complete is usually the
+	// "query" input. Nil is sent as an empty object.
+	Inputs map[string]interface{} `json:"inputs"`
+	// Set by SendMessage and SendMessageStream, any value given here is ignored.
+	ResponseMode string `json:"response_mode"`
+	// User identifier, unique within the application.
+	User  string               `json:"user"`
+	Files []workflow.FileInput `json:"files,omitempty"`
+}
+
+type CompletionMessageResponse struct {
+	// Always "message"
+	Event     string `json:"event"`
+	TaskId    string `json:"task_id"`
+	Id        string `json:"id"`
+	MessageId string `json:"message_id"`
+	// Always "completion"
+	Mode      string   `json:"mode"`
+	Answer    string   `json:"answer"`
+	Metadata  Metadata `json:"metadata"`
+	CreatedAt int64    `json:"created_at"`
+}
+
+func (r *CompletionMessageResponse) String() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+func (r *CompletionMessageResponse) MarshalIndent() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+// SendMessage sends a completion message in blocking mode, req.ResponseMode is ignored.
+func (c *completionClient) SendMessage(ctx context.Context, req *CompletionMessageRequest) (*CompletionMessageResponse, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request is required")
+	}
+	r, err := c.CreateBaseRequest(ctx, http.MethodPost, "/completion-messages", messageBody(req, ResponseModeBlocking))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create base request: %w", err)
+	}
+
+	rsp, err := c.SendRequest(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return nil, client.NewAPIError(rsp)
+	}
+
+	var ret CompletionMessageResponse
+	if err := json.NewDecoder(rsp.Body).Decode(&ret); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &ret, nil
+}
+
+func messageBody(req *CompletionMessageRequest, mode string) *CompletionMessageRequest {
+	body := *req
+	body.ResponseMode = mode
+	if body.Inputs == nil {
+		body.Inputs = map[string]interface{}{}
+	}
+	return &body
+}
+
+// Stream iterates over the events of a streaming completion message:
+//
+//	stream, err := c.SendMessageStream(ctx, req)
+//	if err != nil {
+//		return err
+//	}
+//	defer stream.Close()
+//	for stream.Next() {
+//		if e, ok := stream.Current().(*MessageEvent); ok {
+//			fmt.Print(e.Answer)
+//		}
+//	}
+//	if err := stream.Err(); err != nil {
+//		return err
+//	}
+//	fmt.Println(stream.Metadata().Usage.TotalTokens)
+//
+// Events are decoded by DecodeEvent, keep-alive pings are skipped. The response body
+// is closed at the end of the stream, after an error event, by Close, or when ctx is done.
+type Stream struct {
+	stream    *client.EventStream
+	current   Event
+	err       error
+	done      bool
+	answer    strings.Builder
+	taskId    string
+	messageId string
+	metadata  *Metadata
+}
+
+// NewStream returns a Stream decoding the events of stream.
+func NewStream(stream *client.EventStream) *Stream {
+	return &Stream{stream: stream}
+}
+
+// Next advances to the next event, it returns false when the stream is over.
+// After an error event, Current returns the *ErrorEvent and Err its *client.APIError.
+func (s *Stream) Next() bool {
+	if s.done {
+		return false
+	}
+	if !s.stream.Next() {
+		s.done = true
+		return false
+	}
+
+	e, err := DecodeEvent(s.stream.Data())
+	if err != nil {
+		s.err = fmt.Errorf("error decoding %s event: %w", s.stream.Event(), err)
+		s.Close()
+		return false
+	}
+	s.current = e
+	if s.taskId == "" {
+		s.taskId = s.stream.TaskId()
+	}
+	switch e := e.(type) {
+	case *MessageEvent:
+		s.answer.WriteString(e.Answer)
+		s.messageId = e.MessageId
+	case *MessageReplaceEvent:
+		s.answer.Reset()
+		s.answer.WriteString(e.Answer)
+	case *MessageEndEvent:
+		s.messageId = e.MessageId
+		s.metadata = &e.Metadata
+	case *ErrorEvent:
+		s.err = client.StreamEventError(s.stream.Data())
+		s.stream.End()
+		return false
+	}
+	return true
+}
+
+// Current returns the current event.
+func (s *Stream) Current() Event {
+	return s.current
+}
+
+// Data returns the JSON data of the current event.
+func (s *Stream) Data() []byte {
+	return s.stream.Data()
+}
+
+// Answer returns the answer streamed so far.
+func (s *Stream) Answer() string {
+	return s.answer.String()
+}
+
+// TaskId returns the task ID of the stream, e.g. to Stop it.
+func (s *Stream) TaskId() string {
+	return s.taskId
+}
+
+// MessageId returns the ID of the message, once its first event is read.
+func (s *Stream) MessageId() string {
+	return s.messageId
+}
+
+// Metadata returns the usage of the message, nil until message_end is read.
+func (s *Stream) Metadata() *Metadata {
+	return s.metadata
+}
+
+// Err returns the error that ended the stream, nil for a complete stream.
+func (s *Stream) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.stream.Err()
+}
+
+// Close ends the stream and releases the connection, it may be called from another goroutine.
+func (s *Stream) Close() error {
+	return s.stream.Close()
+}
+
+// SendMessageStream sends a completion message in streaming mode, req.ResponseMode is
+// ignored. Error responses are returned as a *client.APIError.
+func (c *completionClient) SendMessageStream(ctx context.Context, req *CompletionMessageRequest) (*Stream, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request is required")
+	}
+	r, err := c.CreateBaseRequest(ctx, http.MethodPost, "/completion-messages", messageBody(req, ResponseModeStreaming))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create base request: %w", err)
+	}
+
+	stream, err := c.SendStoppableStreamRequest(r, func(ctx context.Context, taskId string) error {
+		_, err := c.Stop(ctx, &StopRequest{TaskId: taskId, User: req.User})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewStream(stream), nil
+}
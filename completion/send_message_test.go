@@ -0,0 +1,107 @@
+package completion
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/taadis/dify-sdk-go/difytest"
+	"github.com/taadis/dify-sdk-go/workflow"
+)
+
+func TestSendMessage(t *testing.T) {
+	ctx := context.Background()
+	c := NewCompletionClient(testBaseUrl, testApiKey)
+
+	req := &CompletionMessageRequest{
+		Inputs: map[string]interface{}{"query": "hello"},
+		User:   "test-user",
+		Files:  []workflow.FileInput{{Type: "image", TransferMethod: "remote_url", URL: "https://example.com/a.png"}},
+	}
+	rsp, err := c.SendMessage(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Mode != "completion" || rsp.Answer != "echo: hello" || rsp.MessageId == "" || rsp.TaskId == "" {
+		t.Errorf("unexpected response: %s", rsp.MarshalIndent())
+	}
+	if u := rsp.Metadata.Usage; u.TotalTokens == 0 || u.TotalPrice == "" || u.Currency == "" {
+		t.Errorf("usage = %+v", u)
+	}
+	body := testServer.AssertRequest(t, http.MethodPost, "/completion-messages").Map()
+	if body["response_mode"] != ResponseModeBlocking || body["files"] == nil {
+		t.Errorf("unexpected body %v", body)
+	}
+}
+
+func TestSendMessageStream(t *testing.T) {
+	ctx := context.Background()
+	c := NewCompletionClient(testBaseUrl, testApiKey)
+
+	stream, err := c.SendMessageStream(ctx, &CompletionMessageRequest{
+		Inputs: map[string]interface{}{"query": "hello streaming world"},
+		User:   "test-user",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var chunks []string
+	var end *MessageEndEvent
+	for stream.Next() {
+		switch e := stream.Current().(type) {
+		case *MessageEvent:
+			chunks = append(chunks, e.Answer)
+		case *MessageEndEvent:
+			end = e
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 4 || stream.Answer() != "echo: hello streaming world" {
+		t.Errorf("chunks = %q, answer = %q", chunks, stream.Answer())
+	}
+	if end == nil || stream.Metadata() != &end.Metadata || end.Metadata.Usage.TotalTokens == 0 {
+		t.Errorf("message_end = %+v", end)
+	}
+	if stream.TaskId() == "" || stream.MessageId() == "" {
+		t.Errorf("task_id = %q, message_id = %q", stream.TaskId(), stream.MessageId())
+	}
+	if mode := testServer.AssertRequest(t, http.MethodPost, "/completion-messages").Map()["response_mode"]; mode != ResponseModeStreaming {
+		t.Errorf("response_mode = %v", mode)
+	}
+}
+
+func TestSendMessageStreamStop(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey), difytest.WithStreamDelay(20*time.Millisecond))
+	defer srv.Close()
+	ctx := context.Background()
+	c := NewCompletionClient(srv.BaseUrl(), testApiKey)
+
+	stream, err := c.SendMessageStream(ctx, &CompletionMessageRequest{
+		Inputs: map[string]interface{}{"query": "one two three four five six seven eight"},
+		User:   "test-user",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	if !stream.Next() {
+		t.Fatal(stream.Err())
+	}
+	if _, err := c.Stop(ctx, &StopRequest{TaskId: stream.TaskId(), User: "test-user"}); err != nil {
+		t.Fatal(err)
+	}
+	for stream.Next() {
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if stream.Answer() == "echo: one two three four five six seven eight" {
+		t.Errorf("answer = %q, want it cut by the stop", stream.Answer())
+	}
+}
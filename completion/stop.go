@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+
+	"github.com/taadis/dify-sdk-go/client"
 )
 
 type StopRequest struct {
@@ -46,8 +48,8 @@ func (c *completionClient) Stop(ctx context.Context, req *StopRequest) (*StopRes
 		return nil, fmt.Errorf("missing required task_id")
 	}
 	// %s={task_id}
-	apiUrl := fmt.Sprintf("/completions-messages/%s/stop", req.TaskId)
-	r, err := c.CreateBaseRequest(ctx, http.MethodPost, apiUrl, req)
+	apiUrl := fmt.Sprintf("/completion-messages/%s/stop", req.TaskId)
+	r, err := c.CreateBaseRequest(client.WithIdempotent(ctx), http.MethodPost, apiUrl, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create base request: %w", err)
 	}
@@ -59,7 +61,7 @@ func (c *completionClient) Stop(ctx context.Context, req *StopRequest) (*StopRes
 	defer rsp.Body.Close()
 
 	if rsp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %s: %s", rsp.Status, c.ReadResponseBody(rsp.Body))
+		return nil, client.NewAPIError(rsp)
 	}
 
 	var ret StopResponse
@@ -2,6 +2,7 @@ package completion
 
 import (
 	"context"
+	"net/http"
 	"testing"
 )
 
@@ -17,5 +18,11 @@ func TestStop(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	t.Log(rsp.MarshalIndent())
+	if rsp.Result != "success" {
+		t.Errorf("unexpected response: %s", rsp.MarshalIndent())
+	}
+	body := testServer.AssertRequest(t, http.MethodPost, "/completion-messages/test-task-id/stop").Map()
+	if body["user"] != "test-user" {
+		t.Errorf("user = %v, want test-user", body["user"])
+	}
 }
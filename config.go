@@ -3,6 +3,8 @@ package dify
 import (
 	"net/http"
 	"time"
+
+	"github.com/taadis/dify-sdk-go/client"
 )
 
 type ClientConfig struct {
@@ -10,5 +12,54 @@ type ClientConfig struct {
 	ApiSecretKey     string // deprecated: use DefaultAPISecret instead
 	DefaultAPISecret string
 	Timeout          time.Duration
-	Transport        *http.Transport
+	Transport        http.RoundTripper
+	// Logger receives the client logs, nothing is logged when nil.
+	Logger Logger
+	// Debug enables curl dumps and request/response summaries with secrets masked.
+	Debug bool
+	// Retry enables automatic retries of transient failures, nil disables them.
+	Retry *RetryPolicy
+	// Middlewares run around every HTTP call, the first one being the outermost.
+	Middlewares []Middleware
+	// AutoStop stops the server-side task of a stream when the context of the call is
+	// canceled, nil disables it.
+	AutoStop *AutoStop
+	// Budget counts the tokens and the price spent by the streams and enforces its
+	// limits, nil disables it.
+	Budget *Budget
 }
+
+// Logger is the logging interface used by Client, see client.Logger.
+type Logger = client.Logger
+
+// RetryPolicy configures automatic retries, see client.RetryPolicy.
+type RetryPolicy = client.RetryPolicy
+
+// DefaultRetryPolicy returns the default retry policy, see client.DefaultRetryPolicy.
+func DefaultRetryPolicy() *RetryPolicy {
+	return client.DefaultRetryPolicy()
+}
+
+// Middleware wraps every HTTP call made by a Client, see client.Middleware.
+type Middleware = client.Middleware
+
+// Handler sends a request and returns its response, see client.Handler.
+type Handler = client.Handler
+
+// AutoStop stops the tasks of canceled streams, see client.AutoStop.
+type AutoStop = client.AutoStop
+
+// StopResult is the outcome of a stop call made by AutoStop, see client.StopResult.
+type StopResult = client.StopResult
+
+// Budget counts and limits the tokens and the price spent by streams, see client.Budget.
+type Budget = client.Budget
+
+// BudgetLimit caps the tokens and the price spent, see client.BudgetLimit.
+type BudgetLimit = client.BudgetLimit
+
+// BudgetTotal is the usage counted by a Budget, see client.BudgetTotal.
+type BudgetTotal = client.BudgetTotal
+
+// BudgetExceededError is returned when a Budget limit is exceeded, see client.BudgetExceededError.
+type BudgetExceededError = client.BudgetExceededError
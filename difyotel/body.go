@@ -0,0 +1,240 @@
+package difyotel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxResponseBodySize limits the blocking response body kept for inspection.
+const maxResponseBodySize = 1 << 20
+
+// payload holds the fields of Dify responses and stream events that end up on spans.
+type payload struct {
+	Event          string `json:"event"`
+	TaskId         string `json:"task_id"`
+	WorkflowRunId  string `json:"workflow_run_id"`
+	MessageId      string `json:"message_id"`
+	Id             string `json:"id"`
+	ConversationId string `json:"conversation_id"`
+	Code           string `json:"code"`
+	Message        string `json:"message"`
+	Metadata       struct {
+		Usage *usage `json:"usage"`
+	} `json:"metadata"`
+	Data struct {
+		TotalTokens *int64 `json:"total_tokens"`
+	} `json:"data"`
+}
+
+type usage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// instrumentedBody observes a response body and ends the span once it is consumed or closed.
+// Close may be called from another goroutine than Read, e.g. when a stream is canceled.
+type instrumentedBody struct {
+	body      io.ReadCloser
+	ctx       context.Context
+	span      trace.Span
+	ins       *instruments
+	attrs     []attribute.KeyValue
+	start     time.Time
+	status    int
+	streaming bool
+
+	// mu guards the fields below, it is not held while reading the body so that
+	// Close can interrupt a pending Read
+	mu           sync.Mutex
+	buf          bytes.Buffer // blocking body, or the incomplete line of a stream
+	events       int
+	firstEventAt time.Time
+	ids          payload
+	usage        *usage
+	totalTokens  *int64
+	finished     bool
+}
+
+func (b *instrumentedBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.finished {
+		return n, err
+	}
+	if n > 0 {
+		if b.streaming {
+			b.scanStream(p[:n])
+		} else if b.buf.Len() < maxResponseBodySize {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err != nil {
+		var readErr error
+		if err != io.EOF {
+			readErr = err
+		}
+		b.finish(readErr)
+	}
+	return n, err
+}
+
+func (b *instrumentedBody) Close() error {
+	err := b.body.Close()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.finish(nil)
+	return err
+}
+
+// scanStream inspects every complete "data:" line of an event stream.
+func (b *instrumentedBody) scanStream(p []byte) {
+	b.buf.Write(p)
+	for {
+		line, err := b.buf.ReadBytes('\n')
+		if err != nil {
+			// keep the incomplete line for the next read
+			rest := append([]byte(nil), line...)
+			b.buf.Reset()
+			b.buf.Write(rest)
+			return
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		b.events++
+		if b.firstEventAt.IsZero() {
+			b.firstEventAt = time.Now()
+		}
+		var pl payload
+		if json.Unmarshal(bytes.TrimSpace(line[len("data:"):]), &pl) == nil {
+			b.merge(&pl)
+		}
+	}
+}
+
+// merge keeps the first IDs seen and the last usage reported.
+func (b *instrumentedBody) merge(pl *payload) {
+	setOnce := func(dst *string, v string) {
+		if *dst == "" {
+			*dst = v
+		}
+	}
+	setOnce(&b.ids.TaskId, pl.TaskId)
+	setOnce(&b.ids.WorkflowRunId, pl.WorkflowRunId)
+	setOnce(&b.ids.ConversationId, pl.ConversationId)
+	setOnce(&b.ids.MessageId, pl.MessageId)
+	if pl.Event == "" || pl.Event == "message" || pl.Event == "agent_message" || pl.Event == "message_end" {
+		// blocking chat responses and message events carry the message ID in "id"
+		if pl.WorkflowRunId == "" {
+			setOnce(&b.ids.MessageId, pl.Id)
+		}
+	}
+	if pl.Event == "error" || (pl.Code != "" && b.status >= 400) {
+		setOnce(&b.ids.Code, pl.Code)
+		setOnce(&b.ids.Message, pl.Message)
+	}
+	if pl.Metadata.Usage != nil {
+		b.usage = pl.Metadata.Usage
+	}
+	if pl.Data.TotalTokens != nil {
+		b.totalTokens = pl.Data.TotalTokens
+	}
+}
+
+// finish ends the span once, b.mu must be held.
+func (b *instrumentedBody) finish(readErr error) {
+	if b.finished {
+		return
+	}
+	b.finished = true
+	end := time.Now()
+	if !b.streaming {
+		var pl payload
+		if json.Unmarshal(b.buf.Bytes(), &pl) == nil {
+			b.merge(&pl)
+		}
+	}
+
+	var spanAttrs []attribute.KeyValue
+	addString := func(k attribute.Key, v string) {
+		if v != "" {
+			spanAttrs = append(spanAttrs, k.String(v))
+		}
+	}
+	addString(AttrTaskId, b.ids.TaskId)
+	addString(AttrWorkflowRunId, b.ids.WorkflowRunId)
+	addString(AttrMessageId, b.ids.MessageId)
+	addString(AttrConversationId, b.ids.ConversationId)
+	addString(AttrErrorCode, b.ids.Code)
+
+	if b.usage != nil {
+		spanAttrs = append(spanAttrs,
+			AttrPromptTokens.Int64(b.usage.PromptTokens),
+			AttrCompletionTokens.Int64(b.usage.CompletionTokens),
+			AttrTotalTokens.Int64(b.usage.TotalTokens))
+		b.addTokens("prompt", b.usage.PromptTokens)
+		b.addTokens("completion", b.usage.CompletionTokens)
+		b.addTokens("total", b.usage.TotalTokens)
+	} else if b.totalTokens != nil {
+		spanAttrs = append(spanAttrs, AttrTotalTokens.Int64(*b.totalTokens))
+		b.addTokens("total", *b.totalTokens)
+	}
+
+	metricAttrs := b.attrs
+	failed := b.status >= http.StatusBadRequest || b.ids.Code != "" || readErr != nil
+	if b.streaming {
+		spanAttrs = append(spanAttrs, AttrStreamEvents.Int(b.events))
+		if !b.firstEventAt.IsZero() {
+			ttfe := b.firstEventAt.Sub(b.start)
+			b.span.AddEvent("first_event", trace.WithTimestamp(b.firstEventAt))
+			b.ins.timeToFirstEvent.Record(b.ctx, ttfe.Seconds(), metric.WithAttributes(metricAttrs...))
+		}
+		b.ins.streamDuration.Record(b.ctx, end.Sub(b.start).Seconds(), metric.WithAttributes(metricAttrs...))
+	}
+
+	b.span.SetAttributes(spanAttrs...)
+	if failed {
+		msg := b.ids.Message
+		if readErr != nil {
+			b.span.RecordError(readErr)
+			msg = readErr.Error()
+		}
+		if msg == "" {
+			msg = http.StatusText(b.status)
+		}
+		b.span.SetStatus(codes.Error, msg)
+
+		errType := b.ids.Code
+		if errType == "" {
+			errType = http.StatusText(b.status)
+		}
+		if readErr != nil {
+			errType = "transport"
+		}
+		metricAttrs = append(metricAttrs, AttrErrorType.String(errType))
+		b.ins.errors.Add(b.ctx, 1, metric.WithAttributes(metricAttrs...))
+	}
+	b.ins.duration.Record(b.ctx, end.Sub(b.start).Seconds(), metric.WithAttributes(metricAttrs...))
+	b.span.End(trace.WithTimestamp(end))
+}
+
+func (b *instrumentedBody) addTokens(kind string, n int64) {
+	if n <= 0 {
+		return
+	}
+	attrs := append(append([]attribute.KeyValue(nil), b.attrs...), AttrTokenType.String(kind))
+	b.ins.tokens.Add(b.ctx, n, metric.WithAttributes(attrs...))
+}
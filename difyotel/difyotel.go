@@ -0,0 +1,105 @@
+// Package difyotel instruments Dify SDK clients with OpenTelemetry tracing and metrics.
+//
+// Install the middleware on any client configuration:
+//
+//	mw := difyotel.Middleware(difyotel.WithAppName("support-bot"))
+//	c := workflow.NewWorkflowClientWithConfig(&client.ClientConfig{
+//		BaseUrl:     client.DifyCloud,
+//		ApiKey:      apiKey,
+//		Middlewares: []client.Middleware{mw},
+//	})
+//
+// Every HTTP call gets a client span named after its endpoint, with the Dify
+// task, workflow run, message and conversation IDs and the token usage found
+// in the response. Streaming responses keep their span open until the body is
+// read to the end or closed, and also record the time to the first event and
+// the total stream duration.
+package difyotel
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name used for the tracer and meter.
+const ScopeName = "github.com/taadis/dify-sdk-go/difyotel"
+
+// Attribute keys set on spans and metrics.
+const (
+	AttrEndpoint         = attribute.Key("dify.endpoint")
+	AttrApp              = attribute.Key("dify.app")
+	AttrResponseMode     = attribute.Key("dify.response_mode")
+	AttrTaskId           = attribute.Key("dify.task_id")
+	AttrWorkflowRunId    = attribute.Key("dify.workflow_run_id")
+	AttrMessageId        = attribute.Key("dify.message_id")
+	AttrConversationId   = attribute.Key("dify.conversation_id")
+	AttrPromptTokens     = attribute.Key("dify.usage.prompt_tokens")
+	AttrCompletionTokens = attribute.Key("dify.usage.completion_tokens")
+	AttrTotalTokens      = attribute.Key("dify.usage.total_tokens")
+	AttrTokenType        = attribute.Key("dify.token.type")
+	AttrErrorCode        = attribute.Key("dify.error.code")
+	AttrStreamEvents     = attribute.Key("dify.stream.events")
+	AttrHTTPMethod       = attribute.Key("http.request.method")
+	AttrHTTPStatusCode   = attribute.Key("http.response.status_code")
+	AttrServerAddress    = attribute.Key("server.address")
+	AttrErrorType        = attribute.Key("error.type")
+)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagator     propagation.TextMapPropagator
+	appName        string
+}
+
+// Option configures the instrumentation.
+type Option func(*config)
+
+// WithTracerProvider sets the tracer provider, the global one is used by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithMeterProvider sets the meter provider, the global one is used by default.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = mp
+	}
+}
+
+// WithPropagator sets the propagator injecting the trace context into the request headers,
+// the global one is used by default.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = p
+	}
+}
+
+// WithAppName sets the dify.app attribute, since the API key alone does not name the app.
+func WithAppName(name string) Option {
+	return func(c *config) {
+		c.appName = name
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.tracerProvider == nil {
+		c.tracerProvider = otel.GetTracerProvider()
+	}
+	if c.meterProvider == nil {
+		c.meterProvider = otel.GetMeterProvider()
+	}
+	if c.propagator == nil {
+		c.propagator = otel.GetTextMapPropagator()
+	}
+	return c
+}
@@ -0,0 +1,25 @@
+module github.com/taadis/dify-sdk-go/difyotel
+
+go 1.22
+
+require (
+	github.com/taadis/dify-sdk-go v0.0.0-20261018105338-36e982c6be84
+	go.opentelemetry.io/otel v1.32.0
+	go.opentelemetry.io/otel/metric v1.32.0
+	go.opentelemetry.io/otel/sdk v1.32.0
+	go.opentelemetry.io/otel/sdk/metric v1.32.0
+	go.opentelemetry.io/otel/trace v1.32.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/taadis/http2curl v0.0.0-20250507153900-fe5b967aaa8f // indirect
+	golang.org/x/sys v0.27.0 // indirect
+)
+
+// Builds against the SDK of this repository during development. Replace directives
+// are ignored in the builds of the users of this module, who get the version required
+// above, the first one with client.Middleware, or a later one.
+replace github.com/taadis/dify-sdk-go => ../
@@ -0,0 +1,167 @@
+package difyotel
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/taadis/dify-sdk-go/client"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxRequestBodySize limits the request body inspected for the response mode.
+const maxRequestBodySize = 1 << 20
+
+type instruments struct {
+	duration         metric.Float64Histogram
+	errors           metric.Int64Counter
+	tokens           metric.Int64Counter
+	timeToFirstEvent metric.Float64Histogram
+	streamDuration   metric.Float64Histogram
+}
+
+func newInstruments(m metric.Meter) *instruments {
+	ins := &instruments{}
+	var err error
+	ins.duration, err = m.Float64Histogram("dify.client.request.duration",
+		metric.WithDescription("Duration of Dify API calls, until the response body is consumed."),
+		metric.WithUnit("s"))
+	handleErr(err)
+	ins.errors, err = m.Int64Counter("dify.client.request.errors",
+		metric.WithDescription("Number of Dify API calls that failed or returned an error status."),
+		metric.WithUnit("{request}"))
+	handleErr(err)
+	ins.tokens, err = m.Int64Counter("dify.client.token.usage",
+		metric.WithDescription("Number of tokens reported by Dify."),
+		metric.WithUnit("{token}"))
+	handleErr(err)
+	ins.timeToFirstEvent, err = m.Float64Histogram("dify.client.stream.time_to_first_event",
+		metric.WithDescription("Time from sending a streaming request to receiving its first event."),
+		metric.WithUnit("s"))
+	handleErr(err)
+	ins.streamDuration, err = m.Float64Histogram("dify.client.stream.duration",
+		metric.WithDescription("Total duration of streaming responses."),
+		metric.WithUnit("s"))
+	handleErr(err)
+	return ins
+}
+
+func handleErr(err error) {
+	if err != nil {
+		otel.Handle(err)
+	}
+}
+
+// Middleware returns a client.Middleware recording a span and metrics for every Dify call.
+// It can be used with client.ClientConfig and dify.ClientConfig alike.
+func Middleware(opts ...Option) client.Middleware {
+	cfg := newConfig(opts)
+	tracer := cfg.tracerProvider.Tracer(ScopeName)
+	ins := newInstruments(cfg.meterProvider.Meter(ScopeName))
+
+	return func(next client.Handler) client.Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			endpoint := Endpoint(req.URL.Path)
+			attrs := []attribute.KeyValue{
+				AttrEndpoint.String(endpoint),
+				AttrHTTPMethod.String(req.Method),
+			}
+			if cfg.appName != "" {
+				attrs = append(attrs, AttrApp.String(cfg.appName))
+			}
+			spanAttrs := append([]attribute.KeyValue{AttrServerAddress.String(req.URL.Hostname())}, attrs...)
+			if mode := responseMode(req); mode != "" {
+				spanAttrs = append(spanAttrs, AttrResponseMode.String(mode))
+			}
+
+			ctx, span := tracer.Start(req.Context(), "dify "+req.Method+" "+endpoint,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(spanAttrs...))
+			// clone the request, headers included, so that the caller's request, which
+			// is sent again on retries, does not carry the trace context of this attempt
+			req = req.Clone(ctx)
+			cfg.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+			start := time.Now()
+			resp, err := next(req)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				span.End()
+				errAttrs := append(attrs, AttrErrorType.String(errorType(err)))
+				ins.duration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(errAttrs...))
+				ins.errors.Add(ctx, 1, metric.WithAttributes(errAttrs...))
+				return nil, err
+			}
+
+			attrs = append(attrs, AttrHTTPStatusCode.Int(resp.StatusCode))
+			span.SetAttributes(AttrHTTPStatusCode.Int(resp.StatusCode))
+			resp.Body = &instrumentedBody{
+				body:      resp.Body,
+				ctx:       ctx,
+				span:      span,
+				ins:       ins,
+				attrs:     attrs,
+				start:     start,
+				status:    resp.StatusCode,
+				streaming: strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream"),
+			}
+			return resp, nil
+		}
+	}
+}
+
+var idSegment = regexp.MustCompile(`^[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}$|^[0-9]+$`)
+
+// Endpoint returns the low cardinality route of a request path: the "/v1" prefix is dropped
+// and ID segments are replaced by "{id}", e.g. "/v1/workflows/run/3c90c3cc-..." becomes "/workflows/run/{id}".
+func Endpoint(path string) string {
+	if i := strings.Index(path, "/v1/"); i >= 0 {
+		path = path[i+3:]
+	}
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if idSegment.MatchString(s) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// responseMode returns the response_mode of a JSON request body, if any.
+func responseMode(req *http.Request) string {
+	if req.GetBody == nil || req.ContentLength > maxRequestBodySize {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	var v struct {
+		ResponseMode string `json:"response_mode"`
+	}
+	if json.NewDecoder(io.LimitReader(body, maxRequestBodySize)).Decode(&v) != nil {
+		return ""
+	}
+	return v.ResponseMode
+}
+
+func errorType(err error) string {
+	if e, ok := client.AsAPIError(err); ok {
+		if e.Code != "" {
+			return e.Code
+		}
+		return strconv.Itoa(e.StatusCode)
+	}
+	return "transport"
+}
@@ -0,0 +1,300 @@
+package difyotel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taadis/dify-sdk-go/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type testSetup struct {
+	spans  *tracetest.SpanRecorder
+	reader *sdkmetric.ManualReader
+	client *client.Client
+}
+
+func newTestSetup(t *testing.T, handler http.HandlerFunc) *testSetup {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	mw := Middleware(
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		WithAppName("test-app"),
+	)
+	c := client.NewClientWithConfig(&client.ClientConfig{
+		BaseUrl:     srv.URL + "/v1",
+		ApiKey:      "app-test",
+		Middlewares: []client.Middleware{mw},
+	})
+	return &testSetup{spans: spans, reader: reader, client: c}
+}
+
+func (s *testSetup) metrics(t *testing.T) map[string]metricdata.Aggregation {
+	var rm metricdata.ResourceMetrics
+	if err := s.reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	ret := make(map[string]metricdata.Aggregation)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			ret[m.Name] = m.Data
+		}
+	}
+	return ret
+}
+
+func spanAttrs(attrs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	ret := make(map[attribute.Key]attribute.Value)
+	for _, kv := range attrs {
+		ret[kv.Key] = kv.Value
+	}
+	return ret
+}
+
+func TestBlockingCall(t *testing.T) {
+	s := newTestSetup(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"event":"message","task_id":"task-1","id":"msg-1","conversation_id":"conv-1",
+			"answer":"hi","metadata":{"usage":{"prompt_tokens":3,"completion_tokens":4,"total_tokens":7}}}`))
+	})
+
+	req, _ := s.client.CreateBaseRequest(context.Background(), http.MethodPost, "/chat-messages",
+		map[string]interface{}{"query": "hello", "response_mode": "blocking", "user": "u"})
+	if err := s.client.SendJSONRequest(req, &struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ended := s.spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("spans = %d, want 1", len(ended))
+	}
+	span := ended[0]
+	if span.Name() != "dify POST /chat-messages" {
+		t.Errorf("span name = %q", span.Name())
+	}
+	attrs := spanAttrs(span.Attributes())
+	want := map[attribute.Key]string{
+		AttrEndpoint:       "/chat-messages",
+		AttrApp:            "test-app",
+		AttrResponseMode:   "blocking",
+		AttrTaskId:         "task-1",
+		AttrMessageId:      "msg-1",
+		AttrConversationId: "conv-1",
+	}
+	for k, v := range want {
+		if got := attrs[k].AsString(); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+	if got := attrs[AttrTotalTokens].AsInt64(); got != 7 {
+		t.Errorf("total tokens = %d, want 7", got)
+	}
+	if got := attrs[AttrHTTPStatusCode].AsInt64(); got != 200 {
+		t.Errorf("status = %d, want 200", got)
+	}
+
+	m := s.metrics(t)
+	if _, ok := m["dify.client.request.duration"]; !ok {
+		t.Error("missing duration metric")
+	}
+	tokens, ok := m["dify.client.token.usage"].(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("missing token metric")
+	}
+	var total int64
+	for _, dp := range tokens.DataPoints {
+		if v, _ := dp.Attributes.Value(AttrTokenType); v.AsString() == "total" {
+			total += dp.Value
+		}
+	}
+	if total != 7 {
+		t.Errorf("total token metric = %d, want 7", total)
+	}
+}
+
+func TestStreamingCall(t *testing.T) {
+	events := []map[string]interface{}{
+		{"event": "workflow_started", "task_id": "task-2", "workflow_run_id": "run-2", "data": map[string]interface{}{"id": "run-2"}},
+		{"event": "node_finished", "task_id": "task-2", "workflow_run_id": "run-2", "data": map[string]interface{}{"id": "node-1"}},
+		{"event": "workflow_finished", "task_id": "task-2", "workflow_run_id": "run-2", "data": map[string]interface{}{"id": "run-2", "total_tokens": 42}},
+	}
+	s := newTestSetup(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		for _, e := range events {
+			bs, _ := json.Marshal(e)
+			fmt.Fprintf(w, "data: %s\n\n", bs)
+			flusher.Flush()
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+
+	req, _ := s.client.CreateBaseRequest(context.Background(), http.MethodPost, "/workflows/run",
+		map[string]interface{}{"inputs": map[string]interface{}{}, "response_mode": "streaming", "user": "u"})
+	resp, err := s.client.SendRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.spans.Ended()) != 0 {
+		t.Fatal("span ended before the stream was consumed")
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	ended := s.spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("spans = %d, want 1", len(ended))
+	}
+	attrs := spanAttrs(ended[0].Attributes())
+	if got := attrs[AttrWorkflowRunId].AsString(); got != "run-2" {
+		t.Errorf("workflow run id = %q", got)
+	}
+	if got := attrs[AttrTaskId].AsString(); got != "task-2" {
+		t.Errorf("task id = %q", got)
+	}
+	if got := attrs[AttrTotalTokens].AsInt64(); got != 42 {
+		t.Errorf("total tokens = %d, want 42", got)
+	}
+	if got := attrs[AttrStreamEvents].AsInt64(); got != 3 {
+		t.Errorf("events = %d, want 3", got)
+	}
+	if got := attrs[AttrResponseMode].AsString(); got != "streaming" {
+		t.Errorf("response mode = %q", got)
+	}
+
+	m := s.metrics(t)
+	ttfe, ok := m["dify.client.stream.time_to_first_event"].(metricdata.Histogram[float64])
+	if !ok || len(ttfe.DataPoints) != 1 || ttfe.DataPoints[0].Count != 1 {
+		t.Fatalf("unexpected time to first event metric: %+v", m["dify.client.stream.time_to_first_event"])
+	}
+	dur, ok := m["dify.client.stream.duration"].(metricdata.Histogram[float64])
+	if !ok || len(dur.DataPoints) != 1 {
+		t.Fatalf("unexpected stream duration metric: %+v", m["dify.client.stream.duration"])
+	}
+	if dur.DataPoints[0].Sum < ttfe.DataPoints[0].Sum {
+		t.Errorf("stream duration %v shorter than time to first event %v", dur.DataPoints[0].Sum, ttfe.DataPoints[0].Sum)
+	}
+}
+
+func TestErrorCall(t *testing.T) {
+	s := newTestSetup(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":"app_unavailable","message":"App unavailable","status":400}`))
+	})
+
+	req, _ := s.client.CreateBaseRequest(context.Background(), http.MethodGet, "/workflows/run/3c90c3cc-0d44-4b50-8888-8dd25736052a", nil)
+	if err := s.client.SendJSONRequest(req, &struct{}{}); !client.HasErrorCode(err, client.ErrCodeAppUnavailable) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ended := s.spans.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("spans = %d, want 1", len(ended))
+	}
+	span := ended[0]
+	if span.Name() != "dify GET /workflows/run/{id}" {
+		t.Errorf("span name = %q", span.Name())
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("span status = %v, want error", span.Status())
+	}
+	if got := spanAttrs(span.Attributes())[AttrErrorCode].AsString(); got != client.ErrCodeAppUnavailable {
+		t.Errorf("error code = %q", got)
+	}
+
+	errs, ok := s.metrics(t)["dify.client.request.errors"].(metricdata.Sum[int64])
+	if !ok || len(errs.DataPoints) != 1 || errs.DataPoints[0].Value != 1 {
+		t.Fatalf("unexpected error metric: %+v", errs)
+	}
+}
+
+func TestMiddlewareKeepsCallerHeaders(t *testing.T) {
+	mw := Middleware(
+		WithTracerProvider(sdktrace.NewTracerProvider()),
+		WithPropagator(propagation.TraceContext{}),
+	)
+	var sent []string
+	h := mw(func(req *http.Request) (*http.Response, error) {
+		sent = append(sent, req.Header.Get("traceparent"))
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/v1/info", nil)
+	for i := 0; i < 2; i++ {
+		resp, err := h(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if v := req.Header.Get("traceparent"); v != "" {
+		t.Errorf("the caller's request was modified: traceparent = %q", v)
+	}
+	if len(sent) != 2 || sent[0] == "" || sent[0] == sent[1] {
+		t.Errorf("traceparent headers sent = %q", sent)
+	}
+}
+
+func TestStreamingCloseDuringRead(t *testing.T) {
+	s := newTestSetup(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; ; i++ {
+			if _, err := fmt.Fprintf(w, "data: {\"event\":\"message\",\"task_id\":\"task-1\",\"answer\":\"%d\"}\n\n", i); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+		}
+	})
+
+	req, _ := s.client.CreateBaseRequest(context.Background(), http.MethodPost, "/chat-messages",
+		map[string]interface{}{"query": "hello", "response_mode": "streaming", "user": "u"})
+	stream, err := s.client.SendStreamRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for stream.Next() {
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	stream.Close()
+	<-done
+
+	if ended := s.spans.Ended(); len(ended) != 1 {
+		t.Fatalf("spans = %d, want 1", len(ended))
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	cases := map[string]string{
+		"/v1/chat-messages": "/chat-messages",
+		"/v1/chat-messages/3c90c3cc-0d44-4b50-8888-8dd25736052a/stop": "/chat-messages/{id}/stop",
+		"/api/v1/workflows/run": "/workflows/run",
+		"/messages":             "/messages",
+	}
+	for in, want := range cases {
+		if got := Endpoint(in); got != want {
+			t.Errorf("Endpoint(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package difytest
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type file struct {
+	Id        string
+	Name      string
+	Size      int64
+	Extension string
+	MimeType  string
+	CreatedBy string
+	CreatedAt int64
+	Content   []byte
+}
+
+// File returns the content of an uploaded file, and false if no file has the ID.
+func (s *Server) File(id string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.files[id]
+	if !ok {
+		return nil, false
+	}
+	return f.Content, true
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		WriteError(w, http.StatusBadRequest, "no_file_uploaded", "Please upload your file.")
+		return
+	}
+	src, header, err := r.FormFile("file")
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "no_file_uploaded", "Please upload your file.")
+		return
+	}
+	defer src.Close()
+	content, err := io.ReadAll(src)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_param", err.Error())
+		return
+	}
+
+	ext := strings.TrimPrefix(filepath.Ext(header.Filename), ".")
+	mimeType := mime.TypeByExtension(filepath.Ext(header.Filename))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	s.mu.Lock()
+	f := &file{
+		Id:        s.newIdLocked(),
+		Name:      header.Filename,
+		Size:      int64(len(content)),
+		Extension: ext,
+		MimeType:  mimeType,
+		CreatedBy: r.FormValue("user"),
+		CreatedAt: time.Now().Unix(),
+		Content:   content,
+	}
+	s.files[f.Id] = f
+	s.mu.Unlock()
+
+	WriteJSON(w, http.StatusCreated, map[string]interface{}{
+		"id":         f.Id,
+		"name":       f.Name,
+		"size":       f.Size,
+		"extension":  f.Extension,
+		"mime_type":  f.MimeType,
+		"created_by": f.CreatedBy,
+		"created_at": f.CreatedAt,
+	})
+}
+
+func defaultParameters() map[string]interface{} {
+	return map[string]interface{}{
+		"opening_statement":   "Hello, how can I help you?",
+		"suggested_questions": []interface{}{"What can you do?"},
+		"suggested_questions_after_answer": map[string]interface{}{
+			"enabled": false,
+		},
+		"speech_to_text":     map[string]interface{}{"enabled": false},
+		"text_to_speech":     map[string]interface{}{"enabled": false},
+		"retriever_resource": map[string]interface{}{"enabled": true},
+		"annotation_reply":   map[string]interface{}{"enabled": false},
+		"more_like_this":     map[string]interface{}{"enabled": false},
+		"user_input_form": []interface{}{
+			map[string]interface{}{
+				"text-input": map[string]interface{}{
+					"label":      "Query",
+					"variable":   "query",
+					"required":   true,
+					"max_length": 256,
+					"default":    "",
+				},
+			},
+		},
+		"file_upload": map[string]interface{}{
+			"image": map[string]interface{}{
+				"enabled":          false,
+				"number_limits":    3,
+				"detail":           "high",
+				"transfer_methods": []interface{}{"remote_url", "local_file"},
+			},
+		},
+		"system_parameters": map[string]interface{}{
+			"file_size_limit":       15,
+			"image_file_size_limit": 10,
+			"audio_file_size_limit": 50,
+			"video_file_size_limit": 100,
+		},
+	}
+}
+
+func defaultInfo() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        "difytest",
+		"description": "Fake Dify application",
+		"tags":        []interface{}{"test"},
+		"mode":        "advanced-chat",
+		"author_name": "difytest",
+	}
+}
+
+func defaultSite() map[string]interface{} {
+	return map[string]interface{}{
+		"title":                   "difytest",
+		"icon_type":               "emoji",
+		"icon":                    "🤖",
+		"icon_background":         "#FFEAD5",
+		"icon_url":                nil,
+		"description":             "Fake Dify application",
+		"copyright":               "",
+		"privacy_policy":          "",
+		"custom_disclaimer":       "",
+		"default_language":        "en-US",
+		"show_workflow_steps":     true,
+		"use_icon_as_answer_icon": false,
+	}
+}
+
+func (s *Server) registerRoutes() {
+	handle := func(method, pattern string, h http.HandlerFunc) {
+		s.routes = append(s.routes, newRoute(method, pattern, h))
+	}
+	handle(http.MethodPost, "/chat-messages", s.handleChatMessages)
+	handle(http.MethodPost, "/chat-messages/{task_id}/stop", s.handleStop)
+	handle(http.MethodPost, "/completion-messages", s.handleCompletionMessages)
+	handle(http.MethodPost, "/completion-messages/{task_id}/stop", s.handleStop)
+	handle(http.MethodPost, "/workflows/run", s.handleWorkflowRun)
+	handle(http.MethodPost, "/workflows/{workflow_id}/run", s.handleWorkflowRun)
+	handle(http.MethodGet, "/workflows/run/{workflow_run_id}", s.handleGetWorkflowRun)
+	handle(http.MethodPost, "/workflows/tasks/{task_id}/stop", s.handleStop)
+	handle(http.MethodGet, "/workflows/logs", s.handleWorkflowLogs)
+	handle(http.MethodPost, "/files/upload", s.handleUpload)
+	handle(http.MethodGet, "/conversations", s.handleConversations)
+	handle(http.MethodPost, "/conversations/{conversation_id}/name", s.handleRenameConversation)
+	handle(http.MethodDelete, "/conversations/{conversation_id}", s.handleDeleteConversation)
+	handle(http.MethodGet, "/conversations/{conversation_id}/variables", s.handleConversationVariables)
+	handle(http.MethodPut, "/conversations/{conversation_id}/variables/{variable_id}", s.handleUpdateConversationVariable)
+	handle(http.MethodGet, "/messages", s.handleMessages)
+	handle(http.MethodPost, "/messages/{message_id}/feedbacks", s.handleFeedbacks)
+	handle(http.MethodGet, "/messages/{message_id}/suggested", s.handleSuggested)
+	handle(http.MethodGet, "/parameters", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, s.parameters)
+	})
+	handle(http.MethodGet, "/info", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, s.info)
+	})
+	handle(http.MethodGet, "/site", func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, http.StatusOK, s.site)
+	})
+}
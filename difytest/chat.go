@@ -0,0 +1,645 @@
+package difytest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type conversation struct {
+	Id           string
+	Name         string
+	User         string
+	Inputs       map[string]interface{}
+	Introduction string
+	CreatedAt    int64
+	UpdatedAt    int64
+	messages     []*message
+	variables    []*conversationVariable
+}
+
+func (c *conversation) toJSON() map[string]interface{} {
+	inputs := c.Inputs
+	if inputs == nil {
+		inputs = map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		"id":           c.Id,
+		"name":         c.Name,
+		"inputs":       inputs,
+		"status":       "normal",
+		"introduction": c.Introduction,
+		"created_at":   c.CreatedAt,
+		"updated_at":   c.UpdatedAt,
+	}
+}
+
+type message struct {
+	Id              string
+	ConversationId  string
+	ParentMessageId string
+	Query           string
+	Answer          string
+	Inputs          map[string]interface{}
+	Rating          string
+	CreatedAt       int64
+}
+
+func (m *message) toJSON() map[string]interface{} {
+	var feedback interface{}
+	if m.Rating != "" {
+		feedback = map[string]interface{}{"rating": m.Rating}
+	}
+	var parent interface{}
+	if m.ParentMessageId != "" {
+		parent = m.ParentMessageId
+	}
+	inputs := m.Inputs
+	if inputs == nil {
+		inputs = map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		"id":                  m.Id,
+		"conversation_id":     m.ConversationId,
+		"parent_message_id":   parent,
+		"inputs":              inputs,
+		"query":               m.Query,
+		"answer":              m.Answer,
+		"message_files":       []interface{}{},
+		"feedback":            feedback,
+		"retriever_resources": []interface{}{},
+		"agent_thoughts":      []interface{}{},
+		"status":              "normal",
+		"error":               nil,
+		"created_at":          m.CreatedAt,
+	}
+}
+
+// task is a running generation that can be stopped.
+type task struct {
+	stop chan struct{}
+	once sync.Once
+}
+
+func (t *task) stopped() bool {
+	select {
+	case <-t.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Server) startTask(id string) *task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := &task{stop: make(chan struct{})}
+	s.tasks[id] = t
+	return t
+}
+
+func (s *Server) endTask(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tasks, id)
+}
+
+// StoppedTasks returns the task IDs received by the stop endpoints, in order.
+func (s *Server) StoppedTasks() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.stopped...)
+}
+
+// AddConversation stores a conversation for user and returns its ID.
+func (s *Server) AddConversation(user, name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().Unix()
+	c := &conversation{Id: s.newIdLocked(), Name: name, User: user, CreatedAt: now, UpdatedAt: now}
+	s.initVariablesLocked(c)
+	s.conversations = append(s.conversations, c)
+	return c.Id
+}
+
+// AddMessage appends a message to a stored conversation and returns its ID.
+// It returns "" if the conversation does not exist.
+func (s *Server) AddMessage(conversationId, query, answer string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.findConversationLocked(conversationId, "")
+	if c == nil {
+		return ""
+	}
+	return s.addMessageLocked(c, query, answer, nil, "").Id
+}
+
+func (s *Server) addMessageLocked(c *conversation, query, answer string, inputs map[string]interface{}, parentId string) *message {
+	now := time.Now().Unix()
+	if parentId == "" && len(c.messages) > 0 {
+		parentId = c.messages[len(c.messages)-1].Id
+	}
+	m := &message{
+		Id:              s.newIdLocked(),
+		ConversationId:  c.Id,
+		ParentMessageId: parentId,
+		Query:           query,
+		Answer:          answer,
+		Inputs:          inputs,
+		CreatedAt:       now,
+	}
+	// keep creation order even within the same second
+	if n := len(c.messages); n > 0 && c.messages[n-1].CreatedAt > now {
+		m.CreatedAt = c.messages[n-1].CreatedAt
+	}
+	c.messages = append(c.messages, m)
+	c.UpdatedAt = now
+	s.messages[m.Id] = m
+	return m
+}
+
+// findConversationLocked returns the conversation with id, owned by user when user is not empty.
+func (s *Server) findConversationLocked(id, user string) *conversation {
+	for _, c := range s.conversations {
+		if c.Id == id && (user == "" || c.User == user) {
+			return c
+		}
+	}
+	return nil
+}
+
+type chatRequest struct {
+	Inputs           map[string]interface{} `json:"inputs"`
+	Query            string                 `json:"query"`
+	ResponseMode     string                 `json:"response_mode"`
+	ConversationId   string                 `json:"conversation_id"`
+	ParentMessageId  string                 `json:"parent_message_id"`
+	User             string                 `json:"user"`
+	Files            []interface{}          `json:"files"`
+	AutoGenerateName *bool                  `json:"auto_generate_name"`
+}
+
+func usage(query, answer string) map[string]interface{} {
+	prompt := len(strings.Fields(query))
+	completion := len(strings.Fields(answer))
+	return map[string]interface{}{
+		"prompt_tokens":         prompt,
+		"prompt_unit_price":     "0.001",
+		"prompt_price_unit":     "0.001",
+		"prompt_price":          strconv.FormatFloat(float64(prompt)*0.000001, 'f', 7, 64),
+		"completion_tokens":     completion,
+		"completion_unit_price": "0.002",
+		"completion_price_unit": "0.001",
+		"completion_price":      strconv.FormatFloat(float64(completion)*0.000002, 'f', 7, 64),
+		"total_tokens":          prompt + completion,
+		"total_price":           strconv.FormatFloat(float64(prompt)*0.000001+float64(completion)*0.000002, 'f', 7, 64),
+		"currency":              "USD",
+		"latency":               0.01,
+	}
+}
+
+// chunks splits an answer into streamed pieces, one word with its trailing space each.
+func chunks(answer string) []string {
+	var ret []string
+	for len(answer) > 0 {
+		i := strings.Index(answer, " ")
+		if i < 0 {
+			ret = append(ret, answer)
+			break
+		}
+		ret = append(ret, answer[:i+1])
+		answer = answer[i+1:]
+	}
+	return ret
+}
+
+func (s *Server) handleChatMessages(w http.ResponseWriter, r *http.Request) {
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_param", err.Error())
+		return
+	}
+	if req.Query == "" || req.User == "" {
+		WriteError(w, http.StatusBadRequest, "invalid_param", "query and user are required")
+		return
+	}
+
+	s.mu.Lock()
+	var c *conversation
+	if req.ConversationId != "" {
+		c = s.findConversationLocked(req.ConversationId, req.User)
+		if c == nil {
+			s.mu.Unlock()
+			WriteError(w, http.StatusNotFound, "not_found", "Conversation Not Exists.")
+			return
+		}
+	} else {
+		now := time.Now().Unix()
+		c = &conversation{Id: s.newIdLocked(), Name: "New conversation", User: req.User, Inputs: req.Inputs, CreatedAt: now, UpdatedAt: now}
+		// the introduction is the opening statement of the app
+		c.Introduction, _ = s.parameters["opening_statement"].(string)
+		s.initVariablesLocked(c)
+		if req.AutoGenerateName == nil || *req.AutoGenerateName {
+			c.Name = generateName(req.Query)
+		}
+		s.conversations = append(s.conversations, c)
+	}
+	answer := s.answer(req.Query, req.Inputs)
+	m := s.addMessageLocked(c, req.Query, answer, req.Inputs, req.ParentMessageId)
+	taskId := s.newIdLocked()
+	s.mu.Unlock()
+
+	metadata := map[string]interface{}{
+		"usage":               usage(req.Query, answer),
+		"retriever_resources": []interface{}{},
+	}
+	mode := "chat"
+	if s.chatflow {
+		mode = "advanced-chat"
+	}
+	if req.ResponseMode != "streaming" {
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"event":           "message",
+			"task_id":         taskId,
+			"id":              m.Id,
+			"message_id":      m.Id,
+			"conversation_id": c.Id,
+			"mode":            mode,
+			"answer":          answer,
+			"metadata":        metadata,
+			"created_at":      m.CreatedAt,
+		})
+		return
+	}
+
+	if s.chatflow {
+		s.streamChatflow(w, r, taskId, m, c.Id, answer, metadata)
+		return
+	}
+	s.streamMessage(w, r, taskId, m, c.Id, answer, metadata)
+}
+
+// streamMessage streams an answer as "message" events followed by "message_end".
+func (s *Server) streamMessage(w http.ResponseWriter, r *http.Request, taskId string, m *message, conversationId, answer string, metadata map[string]interface{}) {
+	t := s.startTask(taskId)
+	defer s.endTask(taskId)
+
+	sw := NewStreamWriter(w)
+	sw.WritePing()
+	base := func(event string) map[string]interface{} {
+		e := map[string]interface{}{
+			"event":      event,
+			"task_id":    taskId,
+			"id":         m.Id,
+			"message_id": m.Id,
+			"created_at": m.CreatedAt,
+		}
+		if conversationId != "" {
+			e["conversation_id"] = conversationId
+		}
+		return e
+	}
+	for _, chunk := range chunks(answer) {
+		if !s.pause(r.Context(), t) {
+			break
+		}
+		e := base("message")
+		e["answer"] = chunk
+		sw.WriteEvent(e)
+	}
+	if r.Context().Err() != nil {
+		return
+	}
+	e := base("message_end")
+	e["metadata"] = metadata
+	sw.WriteEvent(e)
+}
+
+// pause waits between two streamed events, it returns false if the task was stopped
+// or ctx is done.
+func (s *Server) pause(ctx context.Context, t *task) bool {
+	if t.stopped() {
+		return false
+	}
+	if s.streamDelay <= 0 {
+		return ctx.Err() == nil
+	}
+	select {
+	case <-time.After(s.streamDelay):
+		return !t.stopped()
+	case <-t.stop:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func generateName(query string) string {
+	const maxLen = 30
+	rs := []rune(query)
+	if len(rs) > maxLen {
+		return string(rs[:maxLen]) + "..."
+	}
+	return query
+}
+
+func (s *Server) handleCompletionMessages(w http.ResponseWriter, r *http.Request) {
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_param", err.Error())
+		return
+	}
+	if req.User == "" {
+		WriteError(w, http.StatusBadRequest, "invalid_param", "user is required")
+		return
+	}
+	query := req.Query
+	if q, ok := req.Inputs["query"].(string); ok && query == "" {
+		query = q
+	}
+
+	s.mu.Lock()
+	answer := s.answer(query, req.Inputs)
+	m := &message{Id: s.newIdLocked(), Query: query, Answer: answer, Inputs: req.Inputs, CreatedAt: time.Now().Unix()}
+	s.messages[m.Id] = m
+	taskId := s.newIdLocked()
+	s.mu.Unlock()
+
+	metadata := map[string]interface{}{
+		"usage":               usage(query, answer),
+		"retriever_resources": []interface{}{},
+	}
+	if req.ResponseMode != "streaming" {
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"event":      "message",
+			"task_id":    taskId,
+			"id":         m.Id,
+			"message_id": m.Id,
+			"mode":       "completion",
+			"answer":     answer,
+			"metadata":   metadata,
+			"created_at": m.CreatedAt,
+		})
+		return
+	}
+
+	s.streamMessage(w, r, taskId, m, "", answer, metadata)
+}
+
+// handleStop serves the stop endpoints of chat, completion and workflow tasks.
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		User string `json:"user"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	if req.User == "" {
+		WriteError(w, http.StatusBadRequest, "invalid_param", "user is required")
+		return
+	}
+
+	taskId := PathParam(r, "task_id")
+	s.mu.Lock()
+	s.stopped = append(s.stopped, taskId)
+	if t, ok := s.tasks[taskId]; ok {
+		t.once.Do(func() { close(t.stop) })
+	}
+	s.mu.Unlock()
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{"result": "success"})
+}
+
+func (s *Server) handleConversations(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	user := q.Get("user")
+	if user == "" {
+		WriteError(w, http.StatusBadRequest, "invalid_param", "user is required")
+		return
+	}
+	limit := queryInt(q.Get("limit"), 20)
+	if limit < 1 || limit > 100 {
+		WriteError(w, http.StatusBadRequest, "invalid_param", "limit must be between 1 and 100")
+		return
+	}
+	sortBy := q.Get("sort_by")
+	if sortBy == "" {
+		sortBy = "-updated_at"
+	}
+
+	s.mu.Lock()
+	var list []*conversation
+	for _, c := range s.conversations {
+		if c.User == user {
+			list = append(list, c)
+		}
+	}
+	s.mu.Unlock()
+
+	field := strings.TrimPrefix(sortBy, "-")
+	desc := strings.HasPrefix(sortBy, "-")
+	if field != "created_at" && field != "updated_at" {
+		WriteError(w, http.StatusBadRequest, "invalid_param", "invalid sort_by")
+		return
+	}
+	// list is in creation order, a stable sort keeps ties in that order
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i].CreatedAt, list[j].CreatedAt
+		if field == "updated_at" {
+			a, b = list[i].UpdatedAt, list[j].UpdatedAt
+		}
+		return a < b
+	})
+	if desc {
+		for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+			list[i], list[j] = list[j], list[i]
+		}
+	}
+
+	if lastId := q.Get("last_id"); lastId != "" {
+		found := false
+		for i, c := range list {
+			if c.Id == lastId {
+				list = list[i+1:]
+				found = true
+				break
+			}
+		}
+		if !found {
+			WriteError(w, http.StatusNotFound, "not_found", "Last Conversation Not Exists.")
+			return
+		}
+	}
+	hasMore := len(list) > limit
+	if hasMore {
+		list = list[:limit]
+	}
+
+	data := make([]interface{}, 0, len(list))
+	for _, c := range list {
+		data = append(data, c.toJSON())
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"limit":    limit,
+		"has_more": hasMore,
+		"data":     data,
+	})
+}
+
+func (s *Server) handleRenameConversation(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name         string `json:"name"`
+		AutoGenerate bool   `json:"auto_generate"`
+		User         string `json:"user"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	if !req.AutoGenerate && req.Name == "" {
+		WriteError(w, http.StatusBadRequest, "invalid_param", "name is required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.findConversationLocked(PathParam(r, "conversation_id"), req.User)
+	if c == nil {
+		WriteError(w, http.StatusNotFound, "not_found", "Conversation Not Exists.")
+		return
+	}
+	if req.AutoGenerate {
+		c.Name = "New conversation"
+		if len(c.messages) > 0 {
+			c.Name = generateName(c.messages[0].Query)
+		}
+	} else {
+		c.Name = req.Name
+	}
+	c.UpdatedAt = time.Now().Unix()
+	WriteJSON(w, http.StatusOK, c.toJSON())
+}
+
+func (s *Server) handleDeleteConversation(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		User string `json:"user"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := PathParam(r, "conversation_id")
+	for i, c := range s.conversations {
+		if c.Id == id && (req.User == "" || c.User == req.User) {
+			s.conversations = append(s.conversations[:i:i], s.conversations[i+1:]...)
+			for _, m := range c.messages {
+				delete(s.messages, m.Id)
+			}
+			WriteJSON(w, http.StatusOK, map[string]interface{}{"result": "success"})
+			return
+		}
+	}
+	WriteError(w, http.StatusNotFound, "not_found", "Conversation Not Exists.")
+}
+
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := queryInt(q.Get("limit"), 20)
+	if limit < 1 || limit > 100 {
+		WriteError(w, http.StatusBadRequest, "invalid_param", "limit must be between 1 and 100")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.findConversationLocked(q.Get("conversation_id"), q.Get("user"))
+	if c == nil {
+		WriteError(w, http.StatusNotFound, "not_found", "Conversation Not Exists.")
+		return
+	}
+
+	// the page holds the latest messages older than first_id, in chronological order
+	end := len(c.messages)
+	if firstId := q.Get("first_id"); firstId != "" {
+		end = -1
+		for i, m := range c.messages {
+			if m.Id == firstId {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			WriteError(w, http.StatusNotFound, "not_found", "First Message Not Exists.")
+			return
+		}
+	}
+	start := end - limit
+	if start < 0 {
+		start = 0
+	}
+
+	data := make([]interface{}, 0, end-start)
+	for _, m := range c.messages[start:end] {
+		data = append(data, m.toJSON())
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"limit":    limit,
+		"has_more": start > 0,
+		"data":     data,
+	})
+}
+
+func (s *Server) handleFeedbacks(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Rating  *string `json:"rating"`
+		User    string  `json:"user"`
+		Content string  `json:"content"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.messages[PathParam(r, "message_id")]
+	if !ok {
+		WriteError(w, http.StatusNotFound, "not_found", "Message Not Exists.")
+		return
+	}
+	m.Rating = ""
+	if req.Rating != nil {
+		m.Rating = *req.Rating
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{"result": "success"})
+}
+
+func (s *Server) handleSuggested(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("user") == "" {
+		WriteError(w, http.StatusBadRequest, "invalid_param", "user is required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.messages[PathParam(r, "message_id")]; !ok {
+		WriteError(w, http.StatusNotFound, "not_found", "Message Not Exists.")
+		return
+	}
+	if s.suggested == nil {
+		WriteError(w, http.StatusBadRequest, "bad_request", "Suggested Questions Is Disabled.")
+		return
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"result": "success",
+		"data":   s.suggested,
+	})
+}
+
+func queryInt(v string, def int) int {
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return -1
+	}
+	return n
+}
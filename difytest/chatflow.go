@@ -0,0 +1,118 @@
+package difytest
+
+import (
+	"net/http"
+	"time"
+)
+
+// WithChatflow makes /chat-messages answer like a chatflow (advanced chat) app: the
+// stream carries the workflow and node events of a start, llm, answer graph around
+// the message events, and blocking responses have the "advanced-chat" mode.
+func WithChatflow() Option {
+	return func(s *Server) {
+		s.chatflow = true
+	}
+}
+
+var chatflowNodes = []workflowNode{
+	{id: "start", nodeType: "start", title: "Start"},
+	{id: "llm", nodeType: "llm", title: "LLM"},
+	{id: "answer", nodeType: "answer", title: "Answer"},
+}
+
+// streamChatflow streams an answer with the events of a chatflow run.
+func (s *Server) streamChatflow(w http.ResponseWriter, r *http.Request, taskId string, m *message, conversationId, answer string, metadata map[string]interface{}) {
+	t := s.startTask(taskId)
+	defer s.endTask(taskId)
+	start := time.Now()
+	runId := s.newId()
+
+	sw := NewStreamWriter(w)
+	sw.WritePing()
+	event := func(name string, fields map[string]interface{}) map[string]interface{} {
+		e := map[string]interface{}{
+			"event":           name,
+			"task_id":         taskId,
+			"workflow_run_id": runId,
+			"message_id":      m.Id,
+			"conversation_id": conversationId,
+			"created_at":      m.CreatedAt,
+		}
+		for k, v := range fields {
+			e[k] = v
+		}
+		return e
+	}
+	sw.WriteEvent(event("workflow_started", map[string]interface{}{"data": map[string]interface{}{
+		"id":              runId,
+		"workflow_id":     WorkflowId,
+		"sequence_number": 1,
+		"inputs":          m.Inputs,
+		"created_at":      m.CreatedAt,
+	}}))
+
+	status := "succeeded"
+	predecessor := interface{}(nil)
+	for i, node := range chatflowNodes {
+		if !s.pause(r.Context(), t) {
+			status = "stopped"
+			break
+		}
+		nodeData := map[string]interface{}{
+			"id":                  s.newId(),
+			"node_id":             node.id,
+			"node_type":           node.nodeType,
+			"title":               node.title,
+			"index":               i + 1,
+			"predecessor_node_id": predecessor,
+			"inputs":              m.Inputs,
+			"created_at":          time.Now().Unix(),
+		}
+		sw.WriteEvent(event("node_started", map[string]interface{}{"data": nodeData}))
+
+		finished := map[string]interface{}{}
+		for k, v := range nodeData {
+			finished[k] = v
+		}
+		finished["status"] = "succeeded"
+		finished["elapsed_time"] = 0.01
+		finished["execution_metadata"] = map[string]interface{}{}
+		finished["finished_at"] = time.Now().Unix()
+		if node.nodeType == "llm" {
+			for _, chunk := range chunks(answer) {
+				if !s.pause(r.Context(), t) {
+					status = "stopped"
+					break
+				}
+				sw.WriteEvent(event("message", map[string]interface{}{"id": m.Id, "answer": chunk}))
+			}
+			u := metadata["usage"].(map[string]interface{})
+			finished["outputs"] = map[string]interface{}{"text": answer}
+			finished["execution_metadata"] = map[string]interface{}{
+				"total_tokens": u["total_tokens"],
+				"total_price":  u["total_price"],
+				"currency":     u["currency"],
+			}
+		}
+		if status != "succeeded" {
+			break
+		}
+		sw.WriteEvent(event("node_finished", map[string]interface{}{"data": finished}))
+		predecessor = node.id
+	}
+	if r.Context().Err() != nil {
+		return
+	}
+	sw.WriteEvent(event("workflow_finished", map[string]interface{}{"data": map[string]interface{}{
+		"id":           runId,
+		"workflow_id":  WorkflowId,
+		"status":       status,
+		"outputs":      map[string]interface{}{"answer": answer},
+		"elapsed_time": time.Since(start).Seconds(),
+		"total_tokens": metadata["usage"].(map[string]interface{})["total_tokens"],
+		"total_steps":  len(chatflowNodes),
+		"created_at":   m.CreatedAt,
+		"finished_at":  time.Now().Unix(),
+	}}))
+	sw.WriteEvent(event("message_end", map[string]interface{}{"id": m.Id, "metadata": metadata}))
+}
@@ -0,0 +1,174 @@
+package difytest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ConversationVariable declares a variable of the chatflow, every new conversation
+// holds a copy of it, see WithConversationVariables.
+type ConversationVariable struct {
+	Name string
+	// string, number, object, secret, array[string], array[number] or array[object]
+	ValueType string
+	// Initial value, as decoded from JSON.
+	Value       interface{}
+	Description string
+}
+
+// WithConversationVariables declares the conversation variables of the app, served by
+// GET /conversations/{conversation_id}/variables.
+func WithConversationVariables(vars ...ConversationVariable) Option {
+	return func(s *Server) {
+		s.conversationVariables = append(s.conversationVariables, vars...)
+	}
+}
+
+type conversationVariable struct {
+	ConversationVariable
+	Id        string
+	CreatedAt int64
+	UpdatedAt int64
+}
+
+func (v *conversationVariable) toJSON() map[string]interface{} {
+	return map[string]interface{}{
+		"id":          v.Id,
+		"name":        v.Name,
+		"value_type":  v.ValueType,
+		"value":       v.Value,
+		"description": v.Description,
+		"created_at":  v.CreatedAt,
+		"updated_at":  v.UpdatedAt,
+	}
+}
+
+// initVariablesLocked gives c a copy of the declared conversation variables.
+func (s *Server) initVariablesLocked(c *conversation) {
+	now := time.Now().Unix()
+	for _, decl := range s.conversationVariables {
+		v := &conversationVariable{ConversationVariable: decl, Id: s.newIdLocked(), CreatedAt: now, UpdatedAt: now}
+		// copy the maps and slices of the value through JSON
+		if bs, err := json.Marshal(decl.Value); err == nil {
+			v.Value = nil
+			json.Unmarshal(bs, &v.Value)
+		}
+		c.variables = append(c.variables, v)
+	}
+}
+
+func (s *Server) handleConversationVariables(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := queryInt(q.Get("limit"), 20)
+	if limit < 1 || limit > 100 {
+		WriteError(w, http.StatusBadRequest, "invalid_param", "limit must be between 1 and 100")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.findConversationLocked(PathParam(r, "conversation_id"), q.Get("user"))
+	if c == nil {
+		WriteError(w, http.StatusNotFound, "not_found", "Conversation Not Exists.")
+		return
+	}
+
+	var list []*conversationVariable
+	for _, v := range c.variables {
+		if name := q.Get("variable_name"); name == "" || v.Name == name {
+			list = append(list, v)
+		}
+	}
+	if lastId := q.Get("last_id"); lastId != "" {
+		found := false
+		for i, v := range list {
+			if v.Id == lastId {
+				list = list[i+1:]
+				found = true
+				break
+			}
+		}
+		if !found {
+			WriteError(w, http.StatusNotFound, "not_found", "Last Variable Not Exists.")
+			return
+		}
+	}
+	hasMore := len(list) > limit
+	if hasMore {
+		list = list[:limit]
+	}
+
+	data := make([]interface{}, 0, len(list))
+	for _, v := range list {
+		data = append(data, v.toJSON())
+	}
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"limit":    limit,
+		"has_more": hasMore,
+		"data":     data,
+	})
+}
+
+func (s *Server) handleUpdateConversationVariable(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Value interface{} `json:"value"`
+		User  string      `json:"user"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_param", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.findConversationLocked(PathParam(r, "conversation_id"), req.User)
+	if c == nil {
+		WriteError(w, http.StatusNotFound, "not_found", "Conversation Not Exists.")
+		return
+	}
+	id := PathParam(r, "variable_id")
+	for _, v := range c.variables {
+		if v.Id != id {
+			continue
+		}
+		if !valueHasType(req.Value, v.ValueType) {
+			WriteError(w, http.StatusBadRequest, "invalid_param", "Type mismatch: variable expects "+v.ValueType+".")
+			return
+		}
+		v.Value = req.Value
+		v.UpdatedAt = time.Now().Unix()
+		WriteJSON(w, http.StatusOK, v.toJSON())
+		return
+	}
+	WriteError(w, http.StatusNotFound, "not_found", "Conversation Variable Not Exists.")
+}
+
+// valueHasType reports whether the JSON value v is of the variable type t.
+func valueHasType(v interface{}, t string) bool {
+	if strings.HasPrefix(t, "array[") && strings.HasSuffix(t, "]") {
+		items, ok := v.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range items {
+			if !valueHasType(item, t[len("array["):len(t)-1]) {
+				return false
+			}
+		}
+		return true
+	}
+	switch t {
+	case "string", "secret":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package difytest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// Request is a request received by the Server.
+type Request struct {
+	Method string
+	// Path without the "/v1" prefix, e.g. "/chat-messages".
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// JSON decodes the request body into v.
+func (r *Request) JSON(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Map decodes the request body into a map, it returns nil if the body is not a JSON object.
+func (r *Request) Map() map[string]interface{} {
+	var m map[string]interface{}
+	if json.Unmarshal(r.Body, &m) != nil {
+		return nil
+	}
+	return m
+}
+
+func (s *Server) record(r *http.Request, path string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, &Request{
+		Method: r.Method,
+		Path:   path,
+		Query:  r.URL.Query(),
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+}
+
+// Requests returns all the requests received so far.
+func (s *Server) Requests() []*Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Request(nil), s.requests...)
+}
+
+// RequestsTo returns the requests matching method and pattern, e.g. "/workflows/tasks/{task_id}/stop".
+func (s *Server) RequestsTo(method, pattern string) []*Request {
+	rt := newRoute(method, pattern, nil)
+	var ret []*Request
+	for _, r := range s.Requests() {
+		if _, ok := rt.match(r.Method, r.Path); ok {
+			ret = append(ret, r)
+		}
+	}
+	return ret
+}
+
+// AssertRequest fails the test if no request matched method and pattern, and returns the last match.
+func (s *Server) AssertRequest(t testing.TB, method, pattern string) *Request {
+	t.Helper()
+	reqs := s.RequestsTo(method, pattern)
+	if len(reqs) == 0 {
+		t.Fatalf("difytest: no %s %s request received", method, pattern)
+		return nil
+	}
+	return reqs[len(reqs)-1]
+}
+
+// AssertRequestCount fails the test if the number of requests matching method and pattern is not n.
+func (s *Server) AssertRequestCount(t testing.TB, method, pattern string, n int) {
+	t.Helper()
+	if got := len(s.RequestsTo(method, pattern)); got != n {
+		t.Errorf("difytest: %d %s %s requests received, want %d", got, method, pattern, n)
+	}
+}
+
+// AssertNoRequest fails the test if a request matched method and pattern.
+func (s *Server) AssertNoRequest(t testing.TB, method, pattern string) {
+	t.Helper()
+	s.AssertRequestCount(t, method, pattern, 0)
+}
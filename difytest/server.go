@@ -0,0 +1,452 @@
+// Package difytest provides an in-process fake of the Dify Service API for tests.
+//
+// The fake implements the endpoints covered by the SDK: chat, completion and workflow
+// messages in blocking and streaming modes, stop, file upload, conversations, messages,
+// feedbacks, conversation variables, parameters, info, site and workflow logs. Responses can be scripted per
+// route, errors injected, and every request is recorded for assertions:
+//
+//	srv := difytest.NewServer(difytest.WithAPIKey("app-test"))
+//	defer srv.Close()
+//
+//	c := workflow.NewWorkflowClient(srv.BaseUrl(), "app-test")
+//	srv.Inject(http.MethodPost, "/workflows/run", difytest.Fault{Status: 429, Code: "too_many_requests", Times: 1})
+//	...
+//	req := srv.AssertRequest(t, http.MethodPost, "/workflows/run")
+//
+// The root dify.Client uses srv.URL as its host, the service clients use srv.BaseUrl().
+package difytest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a fake Dify Service API backed by an httptest.Server.
+type Server struct {
+	*httptest.Server
+
+	apiKey          string
+	answer          func(query string, inputs map[string]interface{}) string
+	workflowOutputs func(inputs map[string]interface{}) map[string]interface{}
+	parameters      map[string]interface{}
+	info            map[string]interface{}
+	site            map[string]interface{}
+	legacyRunDetail bool
+	streamDelay     time.Duration
+	chatflow        bool
+	// suggested questions after answer, nil when disabled
+	suggested []string
+	// declared conversation variables, copied to every new conversation
+	conversationVariables []ConversationVariable
+	// published workflow versions by workflow ID
+	workflowVersions map[string]string
+
+	mu        sync.Mutex
+	seq       int
+	routes    []*route
+	overrides []*route
+	faults    []*fault
+	requests  []*Request
+
+	conversations []*conversation
+	messages      map[string]*message
+	runs          []*workflowRun
+	files         map[string]*file
+	tasks         map[string]*task
+	stopped       []string
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithAPIKey requires every request to carry "Authorization: Bearer <key>".
+func WithAPIKey(key string) Option {
+	return func(s *Server) {
+		s.apiKey = key
+	}
+}
+
+// WithAnswer sets how chat and completion answers are produced, the query is echoed by default.
+func WithAnswer(f func(query string, inputs map[string]interface{}) string) Option {
+	return func(s *Server) {
+		s.answer = f
+	}
+}
+
+// WithWorkflowOutputs sets how workflow outputs are produced, the inputs are echoed by default.
+func WithWorkflowOutputs(f func(inputs map[string]interface{}) map[string]interface{}) Option {
+	return func(s *Server) {
+		s.workflowOutputs = f
+	}
+}
+
+// WithParameters sets the response of GET /parameters.
+func WithParameters(v map[string]interface{}) Option {
+	return func(s *Server) {
+		s.parameters = v
+	}
+}
+
+// WithSuggestedQuestions enables the suggested questions after answer, served by
+// GET /messages/{message_id}/suggested. They are disabled by default, like in Dify.
+func WithSuggestedQuestions(questions ...string) Option {
+	return func(s *Server) {
+		s.suggested = questions
+		if s.suggested == nil {
+			s.suggested = []string{}
+		}
+		if m, ok := s.parameters["suggested_questions_after_answer"].(map[string]interface{}); ok {
+			m["enabled"] = true
+		}
+	}
+}
+
+// WithInfo sets the response of GET /info.
+func WithInfo(v map[string]interface{}) Option {
+	return func(s *Server) {
+		s.info = v
+	}
+}
+
+// WithSite sets the response of GET /site.
+func WithSite(v map[string]interface{}) Option {
+	return func(s *Server) {
+		s.site = v
+	}
+}
+
+// WithLegacyRunDetail makes GET /workflows/run/{id} encode inputs and outputs
+// as JSON strings, like older Dify versions, instead of objects.
+func WithLegacyRunDetail() Option {
+	return func(s *Server) {
+		s.legacyRunDetail = true
+	}
+}
+
+// WithWorkflowVersion publishes version under workflowId, so that it can be run with
+// POST /workflows/{workflow_id}/run. WorkflowId is published as version "1".
+func WithWorkflowVersion(workflowId, version string) Option {
+	return func(s *Server) {
+		s.workflowVersions[workflowId] = version
+	}
+}
+
+// WithStreamDelay sets the pause between two streamed events, so that tests can
+// act while a stream is in progress.
+func WithStreamDelay(d time.Duration) Option {
+	return func(s *Server) {
+		s.streamDelay = d
+	}
+}
+
+// NewServer starts a fake Dify server, callers must Close it.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		messages: make(map[string]*message),
+		files:    make(map[string]*file),
+		tasks:    make(map[string]*task),
+
+		workflowVersions: map[string]string{WorkflowId: "1"},
+	}
+	s.answer = func(query string, inputs map[string]interface{}) string {
+		return "echo: " + query
+	}
+	s.workflowOutputs = func(inputs map[string]interface{}) map[string]interface{} {
+		return inputs
+	}
+	s.parameters = defaultParameters()
+	s.info = defaultInfo()
+	s.site = defaultSite()
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.registerRoutes()
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// BaseUrl returns the Service API base URL, i.e. the server URL with the "/v1" prefix.
+func (s *Server) BaseUrl() string {
+	return s.URL + "/v1"
+}
+
+// Handle overrides the route matching method and pattern, e.g. "/chat-messages/{task_id}/stop".
+// The latest override wins. Use PathParam to read the pattern parameters.
+func (s *Server) Handle(method, pattern string, h http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overrides = append([]*route{newRoute(method, pattern, h)}, s.overrides...)
+}
+
+// Fault describes an error injected in place of the normal response.
+type Fault struct {
+	// HTTP status, 500 by default.
+	Status int
+	// Dify error code and message of the JSON error body.
+	Code    string
+	Message string
+	// Raw body sent instead of the JSON error, e.g. an HTML page.
+	Body string
+	// Extra response headers, e.g. Retry-After.
+	Header http.Header
+	// Delay before responding.
+	Delay time.Duration
+	// Number of requests affected, 0 means all of them.
+	Times int
+}
+
+type fault struct {
+	route *route
+	Fault
+	remaining int
+}
+
+// Inject makes the requests matching method and pattern fail with f.
+func (s *Server) Inject(method, pattern string, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &fault{route: newRoute(method, pattern, nil), Fault: f, remaining: f.Times})
+}
+
+// Reset removes the overrides, faults and recorded requests, and forgets the stored data.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overrides = nil
+	s.faults = nil
+	s.requests = nil
+	s.conversations = nil
+	s.messages = make(map[string]*message)
+	s.runs = nil
+	s.files = make(map[string]*file)
+	s.tasks = make(map[string]*task)
+	s.stopped = nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = io.NopCloser(strings.NewReader(string(body)))
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1")
+	s.record(r, path, body)
+
+	if s.apiKey != "" && r.Header.Get("Authorization") != "Bearer "+s.apiKey {
+		WriteError(w, http.StatusUnauthorized, "unauthorized", "Access token is invalid")
+		return
+	}
+
+	if f := s.takeFault(r.Method, path); f != nil {
+		s.writeFault(w, r, f)
+		return
+	}
+
+	h, params := s.match(r.Method, path)
+	if h == nil {
+		WriteError(w, http.StatusNotFound, "not_found", "The requested URL was not found on the server.")
+		return
+	}
+	r = r.WithContext(context.WithValue(r.Context(), paramsKey{}, params))
+	h(w, r)
+}
+
+func (s *Server) takeFault(method, path string) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range s.faults {
+		if _, ok := f.route.match(method, path); !ok {
+			continue
+		}
+		if f.Times > 0 {
+			f.remaining--
+			if f.remaining <= 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		ret := f.Fault
+		return &ret
+	}
+	return nil
+}
+
+func (s *Server) writeFault(w http.ResponseWriter, r *http.Request, f *Fault) {
+	if f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	for k, v := range f.Header {
+		w.Header()[k] = v
+	}
+	status := f.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	if f.Body != "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		io.WriteString(w, f.Body)
+		return
+	}
+	code, message := f.Code, f.Message
+	if code == "" {
+		code = "internal_server_error"
+	}
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	WriteError(w, status, code, message)
+}
+
+func (s *Server) match(method, path string) (http.HandlerFunc, map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, routes := range [][]*route{s.overrides, s.routes} {
+		for _, rt := range routes {
+			if params, ok := rt.match(method, path); ok {
+				return rt.handler, params
+			}
+		}
+	}
+	return nil, nil
+}
+
+// newId returns a fresh UUID shaped identifier.
+func (s *Server) newId() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.newIdLocked()
+}
+
+func (s *Server) newIdLocked() string {
+	s.seq++
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", s.seq)
+}
+
+type route struct {
+	method   string
+	segments []string
+	handler  http.HandlerFunc
+}
+
+func newRoute(method, pattern string, h http.HandlerFunc) *route {
+	return &route{method: method, segments: strings.Split(strings.Trim(pattern, "/"), "/"), handler: h}
+}
+
+func (rt *route) match(method, path string) (map[string]string, bool) {
+	if rt.method != method {
+		return nil, false
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) != len(rt.segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, seg := range rt.segments {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			params[seg[1:len(seg)-1]] = segments[i]
+			continue
+		}
+		if seg != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+type paramsKey struct{}
+
+// PathParam returns the value of a "{name}" pattern parameter of the matched route.
+func PathParam(r *http.Request, name string) string {
+	params, _ := r.Context().Value(paramsKey{}).(map[string]string)
+	return params[name]
+}
+
+// WriteJSON writes v as a JSON response with the given status.
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// WriteError writes a Dify JSON error response.
+func WriteError(w http.ResponseWriter, status int, code, message string) {
+	WriteJSON(w, status, map[string]interface{}{
+		"code":    code,
+		"message": message,
+		"status":  status,
+	})
+}
+
+// JSON returns a handler responding with v as JSON.
+func JSON(status int, v interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, status, v)
+	}
+}
+
+// Error returns a handler responding with a Dify JSON error.
+func Error(status int, code, message string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		WriteError(w, status, code, message)
+	}
+}
+
+// Stream returns a handler streaming events as server-sent events. Each event is
+// JSON encoded into a "data:" line, except strings which are written verbatim,
+// so raw lines such as "event: ping\n\n" can be scripted too.
+func Stream(events ...interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sw := NewStreamWriter(w)
+		for _, e := range events {
+			if s, ok := e.(string); ok {
+				sw.WriteRaw(s)
+				continue
+			}
+			sw.WriteEvent(e)
+		}
+	}
+}
+
+// StreamWriter writes server-sent events, flushing after each one.
+type StreamWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// NewStreamWriter writes the event stream headers and returns a StreamWriter.
+func NewStreamWriter(w http.ResponseWriter) *StreamWriter {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	return &StreamWriter{w: w, flusher: flusher}
+}
+
+// WriteEvent writes v as a "data:" event.
+func (sw *StreamWriter) WriteEvent(v interface{}) {
+	bs, _ := json.Marshal(v)
+	sw.WriteRaw("data: " + string(bs) + "\n\n")
+}
+
+// WritePing writes a ping event, as Dify does to keep connections alive.
+func (sw *StreamWriter) WritePing() {
+	sw.WriteRaw("event: ping\n\n")
+}
+
+// WriteRaw writes s as is.
+func (sw *StreamWriter) WriteRaw(s string) {
+	io.WriteString(sw.w, s)
+	if sw.flusher != nil {
+		sw.flusher.Flush()
+	}
+}
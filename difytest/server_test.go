@@ -0,0 +1,168 @@
+package difytest
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func do(t *testing.T, srv *Server, method, path, body string) (*http.Response, map[string]interface{}) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.BaseUrl()+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer app-test")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var m map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&m)
+	return resp, m
+}
+
+func TestAuthAndNotFound(t *testing.T) {
+	srv := NewServer(WithAPIKey("app-other"))
+	defer srv.Close()
+
+	resp, body := do(t, srv, http.MethodGet, "/info", "")
+	if resp.StatusCode != http.StatusUnauthorized || body["code"] != "unauthorized" {
+		t.Errorf("unexpected response: %d %v", resp.StatusCode, body)
+	}
+
+	srv = NewServer()
+	defer srv.Close()
+	resp, body = do(t, srv, http.MethodGet, "/unknown", "")
+	if resp.StatusCode != http.StatusNotFound || body["code"] != "not_found" {
+		t.Errorf("unexpected response: %d %v", resp.StatusCode, body)
+	}
+}
+
+func TestInjectAndHandle(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	srv.Inject(http.MethodGet, "/info", Fault{Status: http.StatusTooManyRequests, Code: "too_many_requests", Times: 1,
+		Header: http.Header{"Retry-After": []string{"1"}}})
+	resp, body := do(t, srv, http.MethodGet, "/info", "")
+	if resp.StatusCode != http.StatusTooManyRequests || body["code"] != "too_many_requests" || resp.Header.Get("Retry-After") != "1" {
+		t.Errorf("unexpected fault response: %d %v", resp.StatusCode, body)
+	}
+	if resp, _ = do(t, srv, http.MethodGet, "/info", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("fault applied more than once: %d", resp.StatusCode)
+	}
+
+	srv.Handle(http.MethodGet, "/info", JSON(http.StatusOK, map[string]interface{}{"name": "scripted"}))
+	if _, body = do(t, srv, http.MethodGet, "/info", ""); body["name"] != "scripted" {
+		t.Errorf("override not applied: %v", body)
+	}
+	srv.AssertRequestCount(t, http.MethodGet, "/info", 3)
+
+	srv.Reset()
+	if _, body = do(t, srv, http.MethodGet, "/info", ""); body["name"] != "difytest" {
+		t.Errorf("override not reset: %v", body)
+	}
+}
+
+func TestChatStreaming(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, srv.BaseUrl()+"/chat-messages",
+		strings.NewReader(`{"query":"hello there","user":"u","response_mode":"streaming","inputs":{}}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var answer strings.Builder
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var e struct {
+			Event  string `json:"event"`
+			Answer string `json:"answer"`
+		}
+		json.Unmarshal([]byte(line[6:]), &e)
+		events = append(events, e.Event)
+		answer.WriteString(e.Answer)
+	}
+	if answer.String() != "echo: hello there" {
+		t.Errorf("answer = %q", answer.String())
+	}
+	if len(events) == 0 || events[len(events)-1] != "message_end" {
+		t.Errorf("events = %v, want message_end last", events)
+	}
+}
+
+func TestConversationsAndMessagesPaging(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	var ids []string
+	for _, name := range []string{"a", "b", "c"} {
+		ids = append(ids, srv.AddConversation("u", name))
+	}
+	_, body := do(t, srv, http.MethodGet, "/conversations?user=u&limit=2&sort_by=created_at", "")
+	data := body["data"].([]interface{})
+	if len(data) != 2 || body["has_more"] != true || data[0].(map[string]interface{})["id"] != ids[0] {
+		t.Errorf("unexpected first page: %v", body)
+	}
+	_, body = do(t, srv, http.MethodGet, "/conversations?user=u&limit=2&sort_by=created_at&last_id="+ids[1], "")
+	data = body["data"].([]interface{})
+	if len(data) != 1 || body["has_more"] != false || data[0].(map[string]interface{})["id"] != ids[2] {
+		t.Errorf("unexpected second page: %v", body)
+	}
+
+	var msgs []string
+	for _, q := range []string{"1", "2", "3"} {
+		msgs = append(msgs, srv.AddMessage(ids[0], q, "ok"))
+	}
+	_, body = do(t, srv, http.MethodGet, "/messages?user=u&limit=2&conversation_id="+ids[0], "")
+	data = body["data"].([]interface{})
+	if len(data) != 2 || body["has_more"] != true || data[0].(map[string]interface{})["id"] != msgs[1] {
+		t.Errorf("unexpected latest messages: %v", body)
+	}
+	_, body = do(t, srv, http.MethodGet, "/messages?user=u&limit=2&conversation_id="+ids[0]+"&first_id="+msgs[1], "")
+	data = body["data"].([]interface{})
+	if len(data) != 1 || body["has_more"] != false || data[0].(map[string]interface{})["id"] != msgs[0] {
+		t.Errorf("unexpected older messages: %v", body)
+	}
+}
+
+func TestWorkflowRunAndLogs(t *testing.T) {
+	srv := NewServer(WithWorkflowOutputs(func(inputs map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"result": inputs["x"]}
+	}))
+	defer srv.Close()
+
+	_, body := do(t, srv, http.MethodPost, "/workflows/run", `{"inputs":{"x":"42"},"user":"u","response_mode":"blocking"}`)
+	data := body["data"].(map[string]interface{})
+	if data["status"] != "succeeded" || data["outputs"].(map[string]interface{})["result"] != "42" {
+		t.Fatalf("unexpected run: %v", body)
+	}
+
+	_, detail := do(t, srv, http.MethodGet, "/workflows/run/"+body["workflow_run_id"].(string), "")
+	if detail["inputs"].(map[string]interface{})["x"] != "42" {
+		t.Errorf("unexpected run detail: %v", detail)
+	}
+
+	_, logs := do(t, srv, http.MethodGet, "/workflows/logs?status=succeeded", "")
+	if logs["total"] != float64(1) {
+		t.Errorf("unexpected logs: %v", logs)
+	}
+	_, logs = do(t, srv, http.MethodGet, "/workflows/logs?status=failed", "")
+	if logs["total"] != float64(0) {
+		t.Errorf("unexpected logs: %v", logs)
+	}
+}
@@ -0,0 +1,376 @@
+package difytest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WorkflowId is the ID of the workflow executed by /workflows/run.
+const WorkflowId = "00000000-0000-4000-8000-0000000000f1"
+
+type workflowRun struct {
+	Id          string
+	TaskId      string
+	WorkflowId  string
+	Version     string
+	User        string
+	Status      string
+	Inputs      map[string]interface{}
+	Outputs     map[string]interface{}
+	Error       string
+	ElapsedTime float64
+	TotalTokens int
+	TotalSteps  int
+	CreatedAt   int64
+	FinishedAt  int64
+}
+
+func (run *workflowRun) dataJSON() map[string]interface{} {
+	var errMsg, finishedAt interface{}
+	if run.Error != "" {
+		errMsg = run.Error
+	}
+	if run.FinishedAt != 0 {
+		finishedAt = run.FinishedAt
+	}
+	return map[string]interface{}{
+		"id":           run.Id,
+		"workflow_id":  run.WorkflowId,
+		"status":       run.Status,
+		"outputs":      run.Outputs,
+		"error":        errMsg,
+		"elapsed_time": run.ElapsedTime,
+		"total_tokens": run.TotalTokens,
+		"total_steps":  run.TotalSteps,
+		"created_at":   run.CreatedAt,
+		"finished_at":  finishedAt,
+	}
+}
+
+type workflowRequest struct {
+	Inputs       map[string]interface{} `json:"inputs"`
+	ResponseMode string                 `json:"response_mode"`
+	User         string                 `json:"user"`
+	Files        []interface{}          `json:"files"`
+}
+
+// workflowNode is a node of the fake workflow graph: start, llm, end.
+type workflowNode struct {
+	id       string
+	nodeType string
+	title    string
+}
+
+var workflowNodes = []workflowNode{
+	{id: "start", nodeType: "start", title: "Start"},
+	{id: "llm", nodeType: "llm", title: "LLM"},
+	{id: "end", nodeType: "end", title: "End"},
+}
+
+func (s *Server) handleWorkflowRun(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	var req workflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_param", err.Error())
+		return
+	}
+	if req.User == "" {
+		WriteError(w, http.StatusBadRequest, "invalid_param", "user is required")
+		return
+	}
+	if req.Inputs == nil {
+		req.Inputs = map[string]interface{}{}
+	}
+
+	workflowId := PathParam(r, "workflow_id")
+	if workflowId == "" {
+		workflowId = WorkflowId
+	}
+
+	s.mu.Lock()
+	version, ok := s.workflowVersions[workflowId]
+	if !ok {
+		s.mu.Unlock()
+		WriteError(w, http.StatusNotFound, "not_found", "Workflow not found with id "+workflowId)
+		return
+	}
+	run := &workflowRun{
+		Id:         s.newIdLocked(),
+		TaskId:     s.newIdLocked(),
+		WorkflowId: workflowId,
+		Version:    version,
+		User:       req.User,
+		Status:     "running",
+		Inputs:     req.Inputs,
+		CreatedAt:  time.Now().Unix(),
+	}
+	s.runs = append(s.runs, run)
+	s.mu.Unlock()
+
+	if req.ResponseMode != "streaming" {
+		s.finishRun(run, "succeeded", s.workflowOutputs(req.Inputs), len(workflowNodes), start)
+		WriteJSON(w, http.StatusOK, map[string]interface{}{
+			"workflow_run_id": run.Id,
+			"task_id":         run.TaskId,
+			"data":            s.runData(run),
+		})
+		return
+	}
+
+	s.streamWorkflow(w, r, run, start)
+}
+
+// finishRun records the final state of a run.
+func (s *Server) finishRun(run *workflowRun, status string, outputs map[string]interface{}, steps int, start time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run.Status = status
+	run.Outputs = outputs
+	run.TotalSteps = steps
+	run.TotalTokens = tokensOf(run.Inputs)
+	if status == "stopped" {
+		run.Error = "Workflow execution stopped by user"
+	}
+	run.ElapsedTime = time.Since(start).Seconds()
+	run.FinishedAt = time.Now().Unix()
+}
+
+func (s *Server) runData(run *workflowRun) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return run.dataJSON()
+}
+
+// tokensOf counts the words of the string inputs, as the tokens used by the LLM node.
+func tokensOf(inputs map[string]interface{}) int {
+	n := 0
+	for _, v := range inputs {
+		if s, ok := v.(string); ok {
+			n += len(strings.Fields(s))
+		}
+	}
+	return n
+}
+
+func (s *Server) streamWorkflow(w http.ResponseWriter, r *http.Request, run *workflowRun, start time.Time) {
+	t := s.startTask(run.TaskId)
+	defer s.endTask(run.TaskId)
+
+	sw := NewStreamWriter(w)
+	sw.WritePing()
+	event := func(name string, data map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"event":           name,
+			"task_id":         run.TaskId,
+			"workflow_run_id": run.Id,
+			"data":            data,
+		}
+	}
+	sw.WriteEvent(event("workflow_started", map[string]interface{}{
+		"id":              run.Id,
+		"workflow_id":     run.WorkflowId,
+		"sequence_number": 1,
+		"inputs":          run.Inputs,
+		"created_at":      run.CreatedAt,
+	}))
+
+	// like Dify, the run goes on when the client disconnects, only a stop ends it early
+	outputs := s.workflowOutputs(run.Inputs)
+	status := "succeeded"
+	steps := 0
+	predecessor := interface{}(nil)
+	for i, node := range workflowNodes {
+		if !s.pause(context.Background(), t) {
+			status = "stopped"
+			break
+		}
+		execId := s.newId()
+		now := time.Now().Unix()
+		nodeData := map[string]interface{}{
+			"id":                  execId,
+			"node_id":             node.id,
+			"node_type":           node.nodeType,
+			"title":               node.title,
+			"index":               i + 1,
+			"predecessor_node_id": predecessor,
+			"inputs":              run.Inputs,
+			"created_at":          now,
+		}
+		sw.WriteEvent(event("node_started", nodeData))
+
+		var nodeOutputs map[string]interface{}
+		metadata := map[string]interface{}{}
+		switch node.nodeType {
+		case "start":
+			nodeOutputs = run.Inputs
+		case "llm":
+			tokens := tokensOf(run.Inputs)
+			nodeOutputs = map[string]interface{}{"text": "ok"}
+			metadata = map[string]interface{}{
+				"total_tokens": tokens,
+				"total_price":  float64(tokens) * 0.000002,
+				"currency":     "USD",
+			}
+			sw.WriteEvent(event("text_chunk", map[string]interface{}{"text": "ok", "from_variable_selector": []string{"llm", "text"}}))
+		case "end":
+			nodeOutputs = outputs
+		}
+		finished := map[string]interface{}{}
+		for k, v := range nodeData {
+			finished[k] = v
+		}
+		finished["process_data"] = nil
+		finished["outputs"] = nodeOutputs
+		finished["status"] = "succeeded"
+		finished["error"] = nil
+		finished["elapsed_time"] = 0.01
+		finished["execution_metadata"] = metadata
+		finished["finished_at"] = time.Now().Unix()
+		sw.WriteEvent(event("node_finished", finished))
+
+		steps++
+		predecessor = node.id
+	}
+	if status != "succeeded" {
+		outputs = nil
+	}
+	s.finishRun(run, status, outputs, steps, start)
+	sw.WriteEvent(event("workflow_finished", s.runData(run)))
+}
+
+func (s *Server) findRun(id string) *workflowRun {
+	for _, run := range s.runs {
+		if run.Id == id {
+			return run
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleGetWorkflowRun(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run := s.findRun(PathParam(r, "workflow_run_id"))
+	if run == nil {
+		WriteError(w, http.StatusNotFound, "not_found", "Workflow run not found")
+		return
+	}
+
+	data := run.dataJSON()
+	data["inputs"] = run.Inputs
+	data["version"] = run.Version
+	if s.legacyRunDetail {
+		inputs, _ := json.Marshal(run.Inputs)
+		data["inputs"] = string(inputs)
+		outputs := ""
+		if run.Outputs != nil {
+			bs, _ := json.Marshal(run.Outputs)
+			outputs = string(bs)
+		}
+		data["outputs"] = outputs
+	}
+	WriteJSON(w, http.StatusOK, data)
+}
+
+func (s *Server) handleWorkflowLogs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page := queryInt(q.Get("page"), 1)
+	limit := queryInt(q.Get("limit"), 20)
+	if page < 1 || limit < 1 || limit > 100 {
+		WriteError(w, http.StatusBadRequest, "invalid_param", "invalid page or limit")
+		return
+	}
+	var after, before time.Time
+	for name, dst := range map[string]*time.Time{"created_at__after": &after, "created_at__before": &before} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				WriteError(w, http.StatusBadRequest, "invalid_param", "Unused components in ISO string")
+				return
+			}
+			*dst = t
+		}
+	}
+
+	s.mu.Lock()
+	var list []*workflowRun
+	for i := len(s.runs) - 1; i >= 0; i-- {
+		run := s.runs[i]
+		if status := q.Get("status"); status != "" && run.Status != status {
+			continue
+		}
+		if keyword := q.Get("keyword"); keyword != "" {
+			bs, _ := json.Marshal(run.Inputs)
+			if !strings.Contains(string(bs), keyword) {
+				continue
+			}
+		}
+		if session := q.Get("created_by_end_user_session_id"); session != "" && run.User != session {
+			continue
+		}
+		if !after.IsZero() && run.CreatedAt < after.Unix() {
+			continue
+		}
+		if !before.IsZero() && run.CreatedAt > before.Unix() {
+			continue
+		}
+		list = append(list, run)
+	}
+
+	total := len(list)
+	from := (page - 1) * limit
+	if from > total {
+		from = total
+	}
+	to := from + limit
+	if to > total {
+		to = total
+	}
+	data := make([]interface{}, 0, to-from)
+	for _, run := range list[from:to] {
+		var errMsg interface{}
+		if run.Error != "" {
+			errMsg = run.Error
+		}
+		var finishedAt interface{}
+		if run.FinishedAt != 0 {
+			finishedAt = run.FinishedAt
+		}
+		data = append(data, map[string]interface{}{
+			"id": run.Id,
+			"workflow_run": map[string]interface{}{
+				"id":           run.Id,
+				"version":      run.Version,
+				"status":       run.Status,
+				"error":        errMsg,
+				"elapsed_time": run.ElapsedTime,
+				"total_tokens": run.TotalTokens,
+				"total_steps":  run.TotalSteps,
+				"created_at":   run.CreatedAt,
+				"finished_at":  finishedAt,
+			},
+			"created_from":       "service-api",
+			"created_by_role":    "end_user",
+			"created_by_account": nil,
+			"created_by_end_user": map[string]interface{}{
+				"id":           run.User,
+				"type":         "service_api",
+				"is_anonymous": false,
+				"session_id":   run.User,
+			},
+			"created_at": run.CreatedAt,
+		})
+	}
+	s.mu.Unlock()
+
+	WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"page":     page,
+		"limit":    limit,
+		"total":    total,
+		"has_more": to < total,
+		"data":     data,
+	})
+}
@@ -0,0 +1,65 @@
+package dify
+
+import "github.com/taadis/dify-sdk-go/client"
+
+// APIError is returned when the Dify API responds with a non-success status, see client.APIError.
+type APIError = client.APIError
+
+// Known Dify error codes, see the client package for the full catalogue.
+const (
+	ErrCodeInvalidParam             = client.ErrCodeInvalidParam
+	ErrCodeBadRequest               = client.ErrCodeBadRequest
+	ErrCodeUnauthorized             = client.ErrCodeUnauthorized
+	ErrCodeNotFound                 = client.ErrCodeNotFound
+	ErrCodeAppUnavailable           = client.ErrCodeAppUnavailable
+	ErrCodeNotChatApp               = client.ErrCodeNotChatApp
+	ErrCodeConversationNotExists    = client.ErrCodeConversationNotExists
+	ErrCodeConversationCompleted    = client.ErrCodeConversationCompleted
+	ErrCodeMessageNotExists         = client.ErrCodeMessageNotExists
+	ErrCodeProviderNotInitialize    = client.ErrCodeProviderNotInitialize
+	ErrCodeProviderQuotaExceeded    = client.ErrCodeProviderQuotaExceeded
+	ErrCodeModelCurrentlyNotSupport = client.ErrCodeModelCurrentlyNotSupport
+	ErrCodeCompletionRequestError   = client.ErrCodeCompletionRequestError
+	ErrCodeWorkflowRequestError     = client.ErrCodeWorkflowRequestError
+	ErrCodeTooManyRequests          = client.ErrCodeTooManyRequests
+	ErrCodeRateLimitError           = client.ErrCodeRateLimitError
+	ErrCodeInternalServerError      = client.ErrCodeInternalServerError
+)
+
+// ErrBudgetExceeded is matched by errors.Is on the errors of a Budget.
+var ErrBudgetExceeded = client.ErrBudgetExceeded
+
+// AsAPIError returns the *APIError in err's chain, if any.
+func AsAPIError(err error) (*APIError, bool) {
+	return client.AsAPIError(err)
+}
+
+// IsRateLimited reports whether err is a 429 or a Dify rate limit error.
+func IsRateLimited(err error) bool {
+	return client.IsRateLimited(err)
+}
+
+// IsNotFound reports whether err is a 404 or a Dify "not exists" error.
+func IsNotFound(err error) bool {
+	return client.IsNotFound(err)
+}
+
+// IsUnauthorized reports whether err is a 401 or 403, usually an invalid API key.
+func IsUnauthorized(err error) bool {
+	return client.IsUnauthorized(err)
+}
+
+// IsInvalidParam reports whether err is a Dify invalid_param error.
+func IsInvalidParam(err error) bool {
+	return client.IsInvalidParam(err)
+}
+
+// IsQuotaExceeded reports whether err is a Dify provider quota error.
+func IsQuotaExceeded(err error) bool {
+	return client.IsQuotaExceeded(err)
+}
+
+// IsServerError reports whether err is a 5xx response.
+func IsServerError(err error) bool {
+	return client.IsServerError(err)
+}
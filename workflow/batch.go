@@ -0,0 +1,378 @@
+package workflow
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// BatchItem is a row of a batch, run as one workflow run.
+type BatchItem struct {
+	// Key identifies the row in the results and the checkpoint, the index of the row in
+	// its source by default.
+	Key    string
+	Inputs map[string]interface{}
+	Files  []FileInput
+	// User runs the row on behalf of another user than BatchOptions.User.
+	User string
+}
+
+// BatchSource provides the rows of a batch.
+type BatchSource interface {
+	// Next returns the next row, io.EOF after the last one.
+	Next(ctx context.Context) (*BatchItem, error)
+}
+
+type sliceSource struct {
+	items []BatchItem
+}
+
+// SliceSource returns a source of the items.
+func SliceSource(items []BatchItem) BatchSource {
+	return &sliceSource{items: items}
+}
+
+func (s *sliceSource) Next(ctx context.Context) (*BatchItem, error) {
+	if len(s.items) == 0 {
+		return nil, io.EOF
+	}
+	item := s.items[0]
+	s.items = s.items[1:]
+	return &item, nil
+}
+
+type chanSource struct {
+	ch <-chan BatchItem
+}
+
+// ChanSource returns a source of the items received from ch, until ch is closed.
+func ChanSource(ch <-chan BatchItem) BatchSource {
+	return &chanSource{ch: ch}
+}
+
+func (s *chanSource) Next(ctx context.Context) (*BatchItem, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case item, ok := <-s.ch:
+		if !ok {
+			return nil, io.EOF
+		}
+		return &item, nil
+	}
+}
+
+type jsonlSource struct {
+	dec *json.Decoder
+}
+
+// JSONLSource returns a source of the JSON objects read from r, one per line, each
+// holding the inputs of a row.
+func JSONLSource(r io.Reader) BatchSource {
+	return &jsonlSource{dec: json.NewDecoder(r)}
+}
+
+func (s *jsonlSource) Next(ctx context.Context) (*BatchItem, error) {
+	var inputs map[string]interface{}
+	if err := s.dec.Decode(&inputs); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, fmt.Errorf("workflow: invalid batch row: %w", err)
+	}
+	return &BatchItem{Inputs: inputs}, nil
+}
+
+type csvSource struct {
+	r      *csv.Reader
+	header []string
+}
+
+// CSVSource returns a source of the records read from r. The first record names the
+// inputs, the values of the following ones are passed as strings.
+func CSVSource(r io.Reader) BatchSource {
+	return &csvSource{r: csv.NewReader(r)}
+}
+
+func (s *csvSource) Next(ctx context.Context) (*BatchItem, error) {
+	if s.header == nil {
+		header, err := s.r.Read()
+		if err != nil {
+			return nil, err
+		}
+		s.header = header
+	}
+	record, err := s.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	inputs := make(map[string]interface{}, len(s.header))
+	for i, name := range s.header {
+		inputs[name] = record[i]
+	}
+	return &BatchItem{Inputs: inputs}, nil
+}
+
+// BatchOptions configures RunBatch.
+type BatchOptions struct {
+	// Concurrency is the number of rows run at the same time, 4 by default.
+	Concurrency int
+	// RequestsPerSecond spaces the runs to at most this rate, unlimited when 0.
+	RequestsPerSecond float64
+	// User runs the rows on behalf of this user, see BatchItem.User.
+	User string
+	// Results receives a BatchResult per row as JSON lines, in completion order.
+	Results io.Writer
+	// OnResult is called with the result of every row, one call at a time.
+	OnResult func(r *BatchResult)
+	// Checkpoint is the path of a file recording the keys of the completed rows. A
+	// batch run again with the same checkpoint skips them, e.g. after a crash.
+	Checkpoint string
+}
+
+// BatchResult is the result of a row of a batch.
+type BatchResult struct {
+	Key           string         `json:"key"`
+	WorkflowRunId string         `json:"workflow_run_id,omitempty"`
+	Status        WorkflowStatus `json:"status,omitempty"`
+	Outputs       Variables      `json:"outputs,omitempty"`
+	// Error is the error of the run, or of the request when Err is set.
+	Error       string  `json:"error,omitempty"`
+	ElapsedTime float64 `json:"elapsed_time,omitempty"`
+	// Err is the error of the request, e.g. a *client.APIError.
+	Err error `json:"-"`
+}
+
+// Succeeded reports whether the row ran successfully.
+func (r *BatchResult) Succeeded() bool {
+	return r.Err == nil && r.Status == WorkflowStatusSucceeded
+}
+
+// BatchSummary counts the rows of a batch.
+type BatchSummary struct {
+	// Total is the number of rows read from the source.
+	Total int
+	// Succeeded and Failed count the rows run, Skipped the ones already completed
+	// according to the checkpoint.
+	Succeeded int
+	Failed    int
+	Skipped   int
+}
+
+// RunBatch runs the rows of src through the workflow and reports their results as they
+// complete:
+//
+//	f, _ := os.Open("rows.csv")
+//	out, _ := os.OpenFile("results.jsonl", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+//	summary, err := workflow.RunBatch(ctx, c, workflow.CSVSource(f), &workflow.BatchOptions{
+//		Concurrency:       8,
+//		RequestsPerSecond: 5,
+//		User:              "batch",
+//		Results:           out,
+//		Checkpoint:        "rows.checkpoint",
+//	})
+//
+// A row is completed once Dify answered, whatever the status of its run; a row whose
+// request failed, e.g. on a network error, is reported but not checkpointed, so it is
+// run again when the batch resumes. Rows do not stop the batch, RunBatch returns the
+// error of the source, of the results or of the checkpoint, and ctx.Err() when ctx is
+// done; the rows interrupted by ctx are not reported.
+func RunBatch(ctx context.Context, c WorkflowClient, src BatchSource, opts *BatchOptions) (*BatchSummary, error) {
+	var o BatchOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 4
+	}
+
+	var done map[string]bool
+	var checkpoint *os.File
+	if o.Checkpoint != "" {
+		var err error
+		if done, checkpoint, err = openCheckpoint(o.Checkpoint); err != nil {
+			return nil, err
+		}
+		defer checkpoint.Close()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var tick <-chan time.Time
+	if o.RequestsPerSecond > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / o.RequestsPerSecond))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	summary := new(BatchSummary)
+	jobs := make(chan *BatchItem)
+	srcDone := make(chan struct{})
+	var srcErr error
+	go func() {
+		defer close(srcDone)
+		defer close(jobs)
+		for i := 0; ; i++ {
+			item, err := src.Next(ctx)
+			if err != nil {
+				if err != io.EOF && ctx.Err() == nil {
+					srcErr = err
+				}
+				return
+			}
+			if item.Key == "" {
+				item.Key = strconv.Itoa(i)
+			}
+			summary.Total++
+			if done[item.Key] {
+				summary.Skipped++
+				continue
+			}
+			select {
+			case jobs <- item:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make(chan *BatchResult)
+	var wg sync.WaitGroup
+	for i := 0; i < o.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				if tick != nil {
+					select {
+					case <-tick:
+					case <-ctx.Done():
+						return
+					}
+				}
+				r := runBatchItem(ctx, c, item, o.User)
+				if r.Err != nil && ctx.Err() != nil {
+					return
+				}
+				results <- r
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var err error
+	for r := range results {
+		if err != nil {
+			continue
+		}
+		if err = writeBatchResult(&o, checkpoint, r); err != nil {
+			cancel()
+			continue
+		}
+		if r.Succeeded() {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+	}
+	<-srcDone
+	if err != nil {
+		return summary, err
+	}
+	if srcErr != nil {
+		return summary, srcErr
+	}
+	return summary, ctx.Err()
+}
+
+func runBatchItem(ctx context.Context, c WorkflowClient, item *BatchItem, user string) *BatchResult {
+	if item.User != "" {
+		user = item.User
+	}
+	r := &BatchResult{Key: item.Key}
+	rsp, err := c.Run(ctx, &RunRequest{Inputs: item.Inputs, Files: item.Files, User: user})
+	if err != nil {
+		r.Err = err
+		r.Error = err.Error()
+		return r
+	}
+	r.WorkflowRunId = rsp.WorkflowRunId
+	r.Status = WorkflowStatus(rsp.Data.Status)
+	r.Outputs = rsp.Data.Outputs
+	r.ElapsedTime = rsp.Data.ElapsedTime
+	if rsp.Data.Error != nil {
+		r.Error = *rsp.Data.Error
+	}
+	return r
+}
+
+// writeBatchResult reports r, then checkpoints its row so a crash in between runs the
+// row again rather than losing its result.
+func writeBatchResult(o *BatchOptions, checkpoint *os.File, r *BatchResult) error {
+	if o.Results != nil {
+		bs, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		if _, err := o.Results.Write(append(bs, '\n')); err != nil {
+			return err
+		}
+	}
+	if o.OnResult != nil {
+		o.OnResult(r)
+	}
+	if checkpoint != nil && r.Err == nil {
+		bs, _ := json.Marshal(r.Key)
+		if _, err := checkpoint.Write(append(bs, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openCheckpoint reads the keys recorded in the checkpoint file and opens it to append
+// new ones. The file holds a JSON string per line, a line cut by a crash is ignored.
+func openCheckpoint(path string) (map[string]bool, *os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	done := make(map[string]bool)
+	var last byte = '\n'
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var key string
+		if json.Unmarshal(sc.Bytes(), &key) == nil {
+			done[key] = true
+		}
+	}
+	if err := sc.Err(); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		b := make([]byte, 1)
+		if _, err := f.ReadAt(b, info.Size()-1); err == nil {
+			last = b[0]
+		}
+	}
+	if last != '\n' {
+		// end the cut line so the next key starts on its own
+		if _, err := f.Write([]byte("\n")); err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+	}
+	return done, f, nil
+}
@@ -0,0 +1,120 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/taadis/dify-sdk-go/difytest"
+)
+
+func TestRunBatchResume(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey))
+	defer srv.Close()
+	c := NewWorkflowClient(srv.BaseUrl(), testApiKey)
+	rows := "query\nrow 0\nrow 1\nrow 2\nrow 3\nrow 4\n"
+	checkpoint := filepath.Join(t.TempDir(), "batch.checkpoint")
+
+	srv.Inject(http.MethodPost, "/workflows/run", difytest.Fault{Status: 400, Code: "invalid_param", Times: 1})
+	var results bytes.Buffer
+	var failed []string
+	summary, err := RunBatch(context.Background(), c, CSVSource(strings.NewReader(rows)), &BatchOptions{
+		Concurrency: 2,
+		User:        "test-user-batch",
+		Results:     &results,
+		Checkpoint:  checkpoint,
+		OnResult: func(r *BatchResult) {
+			if !r.Succeeded() {
+				failed = append(failed, r.Key)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *summary != (BatchSummary{Total: 5, Succeeded: 4, Failed: 1}) || len(failed) != 1 {
+		t.Fatalf("summary = %+v, failed = %v", summary, failed)
+	}
+	if lines := strings.Count(results.String(), "\n"); lines != 5 {
+		t.Errorf("%d results written, want 5", lines)
+	}
+
+	// a crash cut the last line of the checkpoint
+	f, err := os.OpenFile(checkpoint, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`"ro`)
+	f.Close()
+
+	results.Reset()
+	summary, err = RunBatch(context.Background(), c, CSVSource(strings.NewReader(rows)), &BatchOptions{
+		User:       "test-user-batch",
+		Results:    &results,
+		Checkpoint: checkpoint,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *summary != (BatchSummary{Total: 5, Succeeded: 1, Skipped: 4}) {
+		t.Errorf("summary = %+v", summary)
+	}
+	if !strings.Contains(results.String(), `"key":"`+failed[0]+`"`) {
+		t.Errorf("row %s was not run again: %s", failed[0], results.String())
+	}
+	srv.AssertRequestCount(t, http.MethodPost, "/workflows/run", 6)
+
+	summary, err = RunBatch(context.Background(), c, CSVSource(strings.NewReader(rows)), &BatchOptions{Checkpoint: checkpoint})
+	if err != nil || summary.Skipped != 5 {
+		t.Errorf("summary = %+v, err = %v", summary, err)
+	}
+}
+
+func TestRunBatchRateLimit(t *testing.T) {
+	c := NewWorkflowClient(testBaseUrl, testApiKey)
+	items := make([]BatchItem, 5)
+	for i := range items {
+		items[i] = BatchItem{Key: string(rune('a' + i)), Inputs: map[string]interface{}{"query": "rate"}, User: "test-user-batch"}
+	}
+	start := time.Now()
+	summary, err := RunBatch(context.Background(), c, SliceSource(items), &BatchOptions{Concurrency: 5, RequestsPerSecond: 50})
+	if err != nil || summary.Succeeded != 5 {
+		t.Fatalf("summary = %+v, err = %v", summary, err)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("5 runs at 50 rps took %s", elapsed)
+	}
+}
+
+func TestRunBatchSources(t *testing.T) {
+	c := NewWorkflowClient(testBaseUrl, testApiKey)
+	var keys []string
+	summary, err := RunBatch(context.Background(), c, JSONLSource(strings.NewReader(`{"query":"a"}
+{"query":"b"}`)), &BatchOptions{User: "test-user-batch", Concurrency: 1, OnResult: func(r *BatchResult) {
+		keys = append(keys, r.Key)
+	}})
+	if err != nil || summary.Succeeded != 2 || strings.Join(keys, ",") != "0,1" {
+		t.Errorf("summary = %+v, keys = %v, err = %v", summary, keys, err)
+	}
+
+	_, err = RunBatch(context.Background(), c, JSONLSource(strings.NewReader(`{"query":"a"} [`)), &BatchOptions{User: "test-user-batch"})
+	if err == nil || !strings.Contains(err.Error(), "invalid batch row") {
+		t.Errorf("err = %v, want an invalid row", err)
+	}
+
+	ch := make(chan BatchItem)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ch <- BatchItem{Inputs: map[string]interface{}{"query": "chan"}, User: "test-user-batch"}
+		cancel()
+	}()
+	summary, err = RunBatch(ctx, c, ChanSource(ch), nil)
+	if err != context.Canceled || summary.Total != 1 {
+		t.Errorf("summary = %+v, err = %v, want context.Canceled", summary, err)
+	}
+}
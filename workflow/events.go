@@ -0,0 +1,449 @@
+package workflow
+
+import (
+	"encoding/json"
+
+	"github.com/taadis/dify-sdk-go/client"
+)
+
+// Stream event types, see https://docs.dify.ai/en/openapi-api-access-readme
+const (
+	EventWorkflowStarted        = "workflow_started"
+	EventNodeStarted            = "node_started"
+	EventNodeFinished           = "node_finished"
+	EventNodeRetry              = "node_retry"
+	EventTextChunk              = "text_chunk"
+	EventIterationStarted       = "iteration_started"
+	EventIterationNext          = "iteration_next"
+	EventIterationCompleted     = "iteration_completed"
+	EventLoopStarted            = "loop_started"
+	EventLoopNext               = "loop_next"
+	EventLoopCompleted          = "loop_completed"
+	EventParallelBranchStarted  = "parallel_branch_started"
+	EventParallelBranchFinished = "parallel_branch_finished"
+	EventAgentLog               = "agent_log"
+	EventWorkflowFinished       = "workflow_finished"
+	EventTTSMessage             = "tts_message"
+	EventTTSMessageEnd          = "tts_message_end"
+	EventError                  = "error"
+	EventPing                   = "ping" // keep-alive, sent while the run is idle
+)
+
+// Event is a workflow stream event. Its dynamic type is one of the *...Event types of
+// this package, *UnknownEvent for the events this SDK does not know yet.
+type Event interface {
+	EventType() string
+}
+
+// EventBase holds the fields shared by the workflow stream events.
+type EventBase struct {
+	Event         string `json:"event"`
+	TaskId        string `json:"task_id"`
+	WorkflowRunId string `json:"workflow_run_id"`
+}
+
+func (e *EventBase) EventType() string {
+	return e.Event
+}
+
+// ExecutionMetadata is the usage of a node.
+type ExecutionMetadata struct {
+	TotalTokens int            `json:"total_tokens"`
+	TotalPrice  client.Decimal `json:"total_price"`
+	Currency    string         `json:"currency"`
+}
+
+// NodeData holds the fields describing a node execution.
+type NodeData struct {
+	// Node execution ID
+	Id                        string                 `json:"id"`
+	NodeId                    string                 `json:"node_id"`
+	NodeType                  string                 `json:"node_type"`
+	Title                     string                 `json:"title"`
+	Index                     int                    `json:"index"`
+	PredecessorNodeId         string                 `json:"predecessor_node_id"`
+	Inputs                    map[string]interface{} `json:"inputs"`
+	Extras                    map[string]interface{} `json:"extras,omitempty"`
+	ParallelId                string                 `json:"parallel_id,omitempty"`
+	ParallelStartNodeId       string                 `json:"parallel_start_node_id,omitempty"`
+	ParentParallelId          string                 `json:"parent_parallel_id,omitempty"`
+	ParentParallelStartNodeId string                 `json:"parent_parallel_start_node_id,omitempty"`
+	IterationId               string                 `json:"iteration_id,omitempty"`
+	LoopId                    string                 `json:"loop_id,omitempty"`
+	CreatedAt                 int64                  `json:"created_at"`
+}
+
+// NodeResultData holds the fields describing a finished node execution.
+type NodeResultData struct {
+	NodeData
+	ProcessData map[string]interface{} `json:"process_data"`
+	Outputs     map[string]interface{} `json:"outputs"`
+	// running, succeeded, failed, exception, retry or stopped
+	Status            string                   `json:"status"`
+	Error             string                   `json:"error"`
+	ElapsedTime       float64                  `json:"elapsed_time"`
+	ExecutionMetadata ExecutionMetadata        `json:"execution_metadata"`
+	Files             []map[string]interface{} `json:"files,omitempty"`
+	FinishedAt        int64                    `json:"finished_at"`
+}
+
+// WorkflowStartedEvent starts the stream.
+type WorkflowStartedEvent struct {
+	EventBase
+	Data struct {
+		// Workflow run ID
+		Id             string                 `json:"id"`
+		WorkflowId     string                 `json:"workflow_id"`
+		SequenceNumber int                    `json:"sequence_number"`
+		Inputs         map[string]interface{} `json:"inputs"`
+		CreatedAt      int64                  `json:"created_at"`
+	} `json:"data"`
+}
+
+// NodeStartedEvent is sent when a node starts.
+type NodeStartedEvent struct {
+	EventBase
+	Data NodeData `json:"data"`
+}
+
+// NodeFinishedEvent is sent when a node succeeds or fails.
+type NodeFinishedEvent struct {
+	EventBase
+	Data NodeResultData `json:"data"`
+}
+
+// NodeRetryEvent is sent when a failed node is retried.
+type NodeRetryEvent struct {
+	EventBase
+	Data struct {
+		NodeResultData
+		RetryIndex int `json:"retry_index"`
+	} `json:"data"`
+}
+
+// TextChunkEvent is a chunk of text streamed to an output variable.
+type TextChunkEvent struct {
+	EventBase
+	Data struct {
+		Text                 string   `json:"text"`
+		FromVariableSelector []string `json:"from_variable_selector"`
+	} `json:"data"`
+}
+
+// ContainerData holds the fields shared by the iteration and loop events.
+type ContainerData struct {
+	// Node execution ID
+	Id                  string                 `json:"id"`
+	NodeId              string                 `json:"node_id"`
+	NodeType            string                 `json:"node_type"`
+	Title               string                 `json:"title"`
+	Inputs              map[string]interface{} `json:"inputs,omitempty"`
+	Extras              map[string]interface{} `json:"extras,omitempty"`
+	Metadata            map[string]interface{} `json:"metadata,omitempty"`
+	ParallelId          string                 `json:"parallel_id,omitempty"`
+	ParallelStartNodeId string                 `json:"parallel_start_node_id,omitempty"`
+	CreatedAt           int64                  `json:"created_at"`
+}
+
+// ContainerResultData holds the fields of a completed iteration or loop.
+type ContainerResultData struct {
+	ContainerData
+	Outputs           map[string]interface{} `json:"outputs"`
+	Status            string                 `json:"status"`
+	Error             string                 `json:"error"`
+	ElapsedTime       float64                `json:"elapsed_time"`
+	TotalTokens       int                    `json:"total_tokens"`
+	ExecutionMetadata ExecutionMetadata      `json:"execution_metadata"`
+	Steps             int                    `json:"steps"`
+	FinishedAt        int64                  `json:"finished_at"`
+}
+
+// IterationStartedEvent is sent when an iteration node starts.
+type IterationStartedEvent struct {
+	EventBase
+	Data ContainerData `json:"data"`
+}
+
+// IterationNextEvent is sent when an iteration moves to its next item.
+type IterationNextEvent struct {
+	EventBase
+	Data struct {
+		ContainerData
+		Index              int         `json:"index"`
+		PreIterationOutput interface{} `json:"pre_iteration_output"`
+	} `json:"data"`
+}
+
+// IterationCompletedEvent is sent when an iteration node ends.
+type IterationCompletedEvent struct {
+	EventBase
+	Data ContainerResultData `json:"data"`
+}
+
+// LoopStartedEvent is sent when a loop node starts.
+type LoopStartedEvent struct {
+	EventBase
+	Data ContainerData `json:"data"`
+}
+
+// LoopNextEvent is sent when a loop starts its next round.
+type LoopNextEvent struct {
+	EventBase
+	Data struct {
+		ContainerData
+		Index         int         `json:"index"`
+		PreLoopOutput interface{} `json:"pre_loop_output"`
+	} `json:"data"`
+}
+
+// LoopCompletedEvent is sent when a loop node ends.
+type LoopCompletedEvent struct {
+	EventBase
+	Data ContainerResultData `json:"data"`
+}
+
+// ParallelBranchData describes a branch of a parallel execution.
+type ParallelBranchData struct {
+	ParallelId                string `json:"parallel_id"`
+	ParallelStartNodeId       string `json:"parallel_start_node_id"`
+	ParentParallelId          string `json:"parent_parallel_id,omitempty"`
+	ParentParallelStartNodeId string `json:"parent_parallel_start_node_id,omitempty"`
+	IterationId               string `json:"iteration_id,omitempty"`
+	LoopId                    string `json:"loop_id,omitempty"`
+	CreatedAt                 int64  `json:"created_at"`
+}
+
+// ParallelBranchStartedEvent is sent when a parallel branch starts.
+type ParallelBranchStartedEvent struct {
+	EventBase
+	Data ParallelBranchData `json:"data"`
+}
+
+// ParallelBranchFinishedEvent is sent when a parallel branch ends.
+type ParallelBranchFinishedEvent struct {
+	EventBase
+	Data struct {
+		ParallelBranchData
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	} `json:"data"`
+}
+
+// AgentLogEvent is a step of an agent node.
+type AgentLogEvent struct {
+	EventBase
+	Data struct {
+		NodeExecutionId string                 `json:"node_execution_id"`
+		Id              string                 `json:"id"`
+		Label           string                 `json:"label"`
+		ParentId        string                 `json:"parent_id"`
+		Error           string                 `json:"error"`
+		Status          string                 `json:"status"`
+		Data            map[string]interface{} `json:"data"`
+		Metadata        map[string]interface{} `json:"metadata"`
+		NodeId          string                 `json:"node_id"`
+	} `json:"data"`
+}
+
+// WorkflowFinishedEvent ends the stream with the result of the run.
+type WorkflowFinishedEvent struct {
+	EventBase
+	Data struct {
+		// Workflow run ID
+		Id              string                   `json:"id"`
+		WorkflowId      string                   `json:"workflow_id"`
+		SequenceNumber  int                      `json:"sequence_number"`
+		Status          WorkflowStatus           `json:"status"`
+		Outputs         Variables                `json:"outputs"`
+		Error           string                   `json:"error"`
+		ElapsedTime     float64                  `json:"elapsed_time"`
+		TotalTokens     int                      `json:"total_tokens"`
+		TotalSteps      int                      `json:"total_steps"`
+		ExceptionsCount int                      `json:"exceptions_count"`
+		CreatedBy       map[string]interface{}   `json:"created_by,omitempty"`
+		Files           []map[string]interface{} `json:"files,omitempty"`
+		CreatedAt       int64                    `json:"created_at"`
+		FinishedAt      int64                    `json:"finished_at"`
+	} `json:"data"`
+}
+
+// TTSMessageEvent is a chunk of base64 encoded audio, tts_message_end has no audio.
+type TTSMessageEvent struct {
+	Event     string `json:"event"`
+	TaskId    string `json:"task_id"`
+	MessageId string `json:"message_id"`
+	Audio     string `json:"audio"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+func (e *TTSMessageEvent) EventType() string {
+	return e.Event
+}
+
+// ErrorEvent reports an error that ended the stream.
+type ErrorEvent struct {
+	EventBase
+	MessageId string `json:"message_id,omitempty"`
+	Status    int    `json:"status"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+}
+
+// PingEvent keeps the connection alive.
+type PingEvent struct {
+	EventBase
+}
+
+// UnknownEvent is an event type this SDK does not decode, Raw holds its JSON.
+type UnknownEvent struct {
+	EventBase
+	Raw json.RawMessage `json:"-"`
+}
+
+// DecodeEvent decodes the JSON data of a workflow stream event.
+func DecodeEvent(data []byte) (Event, error) {
+	var base EventBase
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, err
+	}
+
+	var e Event
+	switch base.Event {
+	case EventWorkflowStarted:
+		e = new(WorkflowStartedEvent)
+	case EventNodeStarted:
+		e = new(NodeStartedEvent)
+	case EventNodeFinished:
+		e = new(NodeFinishedEvent)
+	case EventNodeRetry:
+		e = new(NodeRetryEvent)
+	case EventTextChunk:
+		e = new(TextChunkEvent)
+	case EventIterationStarted:
+		e = new(IterationStartedEvent)
+	case EventIterationNext:
+		e = new(IterationNextEvent)
+	case EventIterationCompleted:
+		e = new(IterationCompletedEvent)
+	case EventLoopStarted:
+		e = new(LoopStartedEvent)
+	case EventLoopNext:
+		e = new(LoopNextEvent)
+	case EventLoopCompleted:
+		e = new(LoopCompletedEvent)
+	case EventParallelBranchStarted:
+		e = new(ParallelBranchStartedEvent)
+	case EventParallelBranchFinished:
+		e = new(ParallelBranchFinishedEvent)
+	case EventAgentLog:
+		e = new(AgentLogEvent)
+	case EventWorkflowFinished:
+		e = new(WorkflowFinishedEvent)
+	case EventTTSMessage, EventTTSMessageEnd:
+		e = new(TTSMessageEvent)
+	case EventError:
+		e = new(ErrorEvent)
+	case EventPing:
+		return &PingEvent{EventBase: base}, nil
+	default:
+		return &UnknownEvent{EventBase: base, Raw: append(json.RawMessage(nil), data...)}, nil
+	}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// EventHandler receives the events of a workflow stream, one method per event type.
+// Embed BaseEventHandler to only implement the methods of interest.
+type EventHandler interface {
+	OnWorkflowStarted(e *WorkflowStartedEvent)
+	OnNodeStarted(e *NodeStartedEvent)
+	OnNodeFinished(e *NodeFinishedEvent)
+	OnNodeRetry(e *NodeRetryEvent)
+	OnTextChunk(e *TextChunkEvent)
+	OnIterationStarted(e *IterationStartedEvent)
+	OnIterationNext(e *IterationNextEvent)
+	OnIterationCompleted(e *IterationCompletedEvent)
+	OnLoopStarted(e *LoopStartedEvent)
+	OnLoopNext(e *LoopNextEvent)
+	OnLoopCompleted(e *LoopCompletedEvent)
+	OnParallelBranchStarted(e *ParallelBranchStartedEvent)
+	OnParallelBranchFinished(e *ParallelBranchFinishedEvent)
+	OnAgentLog(e *AgentLogEvent)
+	OnWorkflowFinished(e *WorkflowFinishedEvent)
+	OnTTSMessage(e *TTSMessageEvent)
+	OnError(e *ErrorEvent)
+	// OnPing receives the keep-alive pings Dify sends while a run is idle.
+	OnPing(e *PingEvent)
+	// OnEvent receives the events without a dedicated method, e.g. the ones
+	// added to Dify after this SDK.
+	OnEvent(e Event)
+}
+
+// BaseEventHandler implements EventHandler by ignoring every event.
+type BaseEventHandler struct{}
+
+func (BaseEventHandler) OnWorkflowStarted(e *WorkflowStartedEvent)               {}
+func (BaseEventHandler) OnNodeStarted(e *NodeStartedEvent)                       {}
+func (BaseEventHandler) OnNodeFinished(e *NodeFinishedEvent)                     {}
+func (BaseEventHandler) OnNodeRetry(e *NodeRetryEvent)                           {}
+func (BaseEventHandler) OnTextChunk(e *TextChunkEvent)                           {}
+func (BaseEventHandler) OnIterationStarted(e *IterationStartedEvent)             {}
+func (BaseEventHandler) OnIterationNext(e *IterationNextEvent)                   {}
+func (BaseEventHandler) OnIterationCompleted(e *IterationCompletedEvent)         {}
+func (BaseEventHandler) OnLoopStarted(e *LoopStartedEvent)                       {}
+func (BaseEventHandler) OnLoopNext(e *LoopNextEvent)                             {}
+func (BaseEventHandler) OnLoopCompleted(e *LoopCompletedEvent)                   {}
+func (BaseEventHandler) OnParallelBranchStarted(e *ParallelBranchStartedEvent)   {}
+func (BaseEventHandler) OnParallelBranchFinished(e *ParallelBranchFinishedEvent) {}
+func (BaseEventHandler) OnAgentLog(e *AgentLogEvent)                             {}
+func (BaseEventHandler) OnWorkflowFinished(e *WorkflowFinishedEvent)             {}
+func (BaseEventHandler) OnTTSMessage(e *TTSMessageEvent)                         {}
+func (BaseEventHandler) OnError(e *ErrorEvent)                                   {}
+func (BaseEventHandler) OnPing(e *PingEvent)                                     {}
+func (BaseEventHandler) OnEvent(e Event)                                         {}
+
+// DispatchEvent calls the method of h matching the type of e.
+func DispatchEvent(h EventHandler, e Event) {
+	switch e := e.(type) {
+	case *WorkflowStartedEvent:
+		h.OnWorkflowStarted(e)
+	case *NodeStartedEvent:
+		h.OnNodeStarted(e)
+	case *NodeFinishedEvent:
+		h.OnNodeFinished(e)
+	case *NodeRetryEvent:
+		h.OnNodeRetry(e)
+	case *TextChunkEvent:
+		h.OnTextChunk(e)
+	case *IterationStartedEvent:
+		h.OnIterationStarted(e)
+	case *IterationNextEvent:
+		h.OnIterationNext(e)
+	case *IterationCompletedEvent:
+		h.OnIterationCompleted(e)
+	case *LoopStartedEvent:
+		h.OnLoopStarted(e)
+	case *LoopNextEvent:
+		h.OnLoopNext(e)
+	case *LoopCompletedEvent:
+		h.OnLoopCompleted(e)
+	case *ParallelBranchStartedEvent:
+		h.OnParallelBranchStarted(e)
+	case *ParallelBranchFinishedEvent:
+		h.OnParallelBranchFinished(e)
+	case *AgentLogEvent:
+		h.OnAgentLog(e)
+	case *WorkflowFinishedEvent:
+		h.OnWorkflowFinished(e)
+	case *TTSMessageEvent:
+		h.OnTTSMessage(e)
+	case *ErrorEvent:
+		h.OnError(e)
+	case *PingEvent:
+		h.OnPing(e)
+	default:
+		h.OnEvent(e)
+	}
+}
@@ -0,0 +1,158 @@
+package workflow
+
+import (
+	"testing"
+)
+
+func TestDecodeEvent(t *testing.T) {
+	tests := []struct {
+		data  string
+		check func(t *testing.T, e Event)
+	}{
+		{`{"event":"workflow_started","task_id":"t","workflow_run_id":"r","data":{"id":"r","workflow_id":"w","inputs":{"q":"hi"}}}`, func(t *testing.T, e Event) {
+			ev := e.(*WorkflowStartedEvent)
+			if ev.TaskId != "t" || ev.WorkflowRunId != "r" || ev.Data.WorkflowId != "w" || ev.Data.Inputs["q"] != "hi" {
+				t.Errorf("%+v", ev)
+			}
+		}},
+		{`{"event":"node_started","data":{"id":"n","node_id":"llm","node_type":"llm","index":2,"inputs":{"a":1},"iteration_id":"it"}}`, func(t *testing.T, e Event) {
+			ev := e.(*NodeStartedEvent)
+			if ev.Data.NodeId != "llm" || ev.Data.Index != 2 || ev.Data.IterationId != "it" || ev.Data.Inputs["a"] != float64(1) {
+				t.Errorf("%+v", ev)
+			}
+		}},
+		{`{"event":"node_finished","data":{"node_id":"llm","status":"succeeded","outputs":{"text":"ok"},"elapsed_time":0.5,"execution_metadata":{"total_tokens":12,"total_price":"0.0001","currency":"USD"}}}`, func(t *testing.T, e Event) {
+			ev := e.(*NodeFinishedEvent)
+			if ev.Data.Status != "succeeded" || ev.Data.Outputs["text"] != "ok" || ev.Data.ExecutionMetadata.TotalTokens != 12 || ev.Data.ExecutionMetadata.TotalPrice.Float64() != 0.0001 {
+				t.Errorf("%+v", ev)
+			}
+		}},
+		{`{"event":"node_retry","data":{"node_id":"http","status":"retry","error":"timeout","retry_index":1}}`, func(t *testing.T, e Event) {
+			ev := e.(*NodeRetryEvent)
+			if ev.Data.NodeId != "http" || ev.Data.Error != "timeout" || ev.Data.RetryIndex != 1 {
+				t.Errorf("%+v", ev)
+			}
+		}},
+		{`{"event":"text_chunk","data":{"text":"he","from_variable_selector":["llm","text"]}}`, func(t *testing.T, e Event) {
+			ev := e.(*TextChunkEvent)
+			if ev.Data.Text != "he" || len(ev.Data.FromVariableSelector) != 2 {
+				t.Errorf("%+v", ev)
+			}
+		}},
+		{`{"event":"iteration_started","data":{"id":"i","node_id":"iter","metadata":{"iterator_length":3}}}`, func(t *testing.T, e Event) {
+			ev := e.(*IterationStartedEvent)
+			if ev.Data.NodeId != "iter" || ev.Data.Metadata["iterator_length"] != float64(3) {
+				t.Errorf("%+v", ev)
+			}
+		}},
+		{`{"event":"iteration_next","data":{"node_id":"iter","index":1,"pre_iteration_output":"a"}}`, func(t *testing.T, e Event) {
+			ev := e.(*IterationNextEvent)
+			if ev.Data.Index != 1 || ev.Data.PreIterationOutput != "a" {
+				t.Errorf("%+v", ev)
+			}
+		}},
+		{`{"event":"iteration_completed","data":{"node_id":"iter","steps":3,"status":"succeeded","outputs":{"output":["a","b","c"]}}}`, func(t *testing.T, e Event) {
+			ev := e.(*IterationCompletedEvent)
+			if ev.Data.Steps != 3 || ev.Data.Status != "succeeded" {
+				t.Errorf("%+v", ev)
+			}
+		}},
+		{`{"event":"loop_started","data":{"node_id":"loop"}}`, func(t *testing.T, e Event) {
+			if ev := e.(*LoopStartedEvent); ev.Data.NodeId != "loop" {
+				t.Errorf("%+v", ev)
+			}
+		}},
+		{`{"event":"loop_next","data":{"node_id":"loop","index":2,"pre_loop_output":{"n":1}}}`, func(t *testing.T, e Event) {
+			if ev := e.(*LoopNextEvent); ev.Data.Index != 2 || ev.Data.PreLoopOutput == nil {
+				t.Errorf("%+v", ev)
+			}
+		}},
+		{`{"event":"loop_completed","data":{"node_id":"loop","steps":2}}`, func(t *testing.T, e Event) {
+			if ev := e.(*LoopCompletedEvent); ev.Data.Steps != 2 {
+				t.Errorf("%+v", ev)
+			}
+		}},
+		{`{"event":"parallel_branch_started","data":{"parallel_id":"p","parallel_start_node_id":"a"}}`, func(t *testing.T, e Event) {
+			if ev := e.(*ParallelBranchStartedEvent); ev.Data.ParallelId != "p" || ev.Data.ParallelStartNodeId != "a" {
+				t.Errorf("%+v", ev)
+			}
+		}},
+		{`{"event":"parallel_branch_finished","data":{"parallel_id":"p","status":"failed","error":"boom"}}`, func(t *testing.T, e Event) {
+			if ev := e.(*ParallelBranchFinishedEvent); ev.Data.ParallelId != "p" || ev.Data.Status != "failed" || ev.Data.Error != "boom" {
+				t.Errorf("%+v", ev)
+			}
+		}},
+		{`{"event":"agent_log","data":{"node_execution_id":"n","id":"l","label":"ROUND 1","status":"success","data":{"output":"x"}}}`, func(t *testing.T, e Event) {
+			if ev := e.(*AgentLogEvent); ev.Data.Label != "ROUND 1" || ev.Data.Data["output"] != "x" {
+				t.Errorf("%+v", ev)
+			}
+		}},
+		{`{"event":"workflow_finished","data":{"id":"r","status":"succeeded","outputs":{"text":"ok"},"total_tokens":12,"total_steps":3,"elapsed_time":1.5}}`, func(t *testing.T, e Event) {
+			ev := e.(*WorkflowFinishedEvent)
+			if ev.Data.Status != WorkflowStatusSucceeded || ev.Data.Outputs["text"] != "ok" || ev.Data.TotalSteps != 3 || ev.Data.ElapsedTime != 1.5 {
+				t.Errorf("%+v", ev)
+			}
+		}},
+		{`{"event":"tts_message","task_id":"t","message_id":"m","audio":"SUQz"}`, func(t *testing.T, e Event) {
+			if ev := e.(*TTSMessageEvent); ev.Audio != "SUQz" || ev.MessageId != "m" {
+				t.Errorf("%+v", ev)
+			}
+		}},
+		{`{"event":"error","status":400,"code":"invalid_param","message":"bad input"}`, func(t *testing.T, e Event) {
+			ev := e.(*ErrorEvent)
+			if ev.Code != "invalid_param" || ev.Message != "bad input" {
+				t.Errorf("%+v", ev)
+			}
+		}},
+		{`{"event":"ping"}`, func(t *testing.T, e Event) {
+			_ = e.(*PingEvent)
+		}},
+		{`{"event":"node_paused","task_id":"t","data":{"x":1}}`, func(t *testing.T, e Event) {
+			ev := e.(*UnknownEvent)
+			if ev.Event != "node_paused" || ev.TaskId != "t" || string(ev.Raw) != `{"event":"node_paused","task_id":"t","data":{"x":1}}` {
+				t.Errorf("%+v", ev)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		e, err := DecodeEvent([]byte(tt.data))
+		if err != nil {
+			t.Errorf("DecodeEvent(%s): %v", tt.data, err)
+			continue
+		}
+		tt.check(t, e)
+	}
+
+	if _, err := DecodeEvent([]byte(`{"event":"node_finished","data":{"execution_metadata":{"total_price":"n/a"}}}`)); err == nil {
+		t.Error("expected an error for an invalid price")
+	}
+}
+
+type countingHandler struct {
+	BaseEventHandler
+	started, chunks, other int
+}
+
+func (h *countingHandler) OnWorkflowStarted(e *WorkflowStartedEvent) { h.started++ }
+func (h *countingHandler) OnTextChunk(e *TextChunkEvent)             { h.chunks++ }
+func (h *countingHandler) OnEvent(e Event)                           { h.other++ }
+
+func TestDispatchEvent(t *testing.T) {
+	h := &countingHandler{}
+	for _, data := range []string{
+		`{"event":"workflow_started","data":{}}`,
+		`{"event":"text_chunk","data":{"text":"a"}}`,
+		`{"event":"text_chunk","data":{"text":"b"}}`,
+		`{"event":"loop_next","data":{}}`,
+		`{"event":"node_paused"}`,
+	} {
+		e, err := DecodeEvent([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		DispatchEvent(h, e)
+	}
+	if h.started != 1 || h.chunks != 2 || h.other != 1 {
+		t.Errorf("started=%d chunks=%d other=%d", h.started, h.chunks, h.other)
+	}
+}
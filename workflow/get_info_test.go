@@ -3,9 +3,6 @@ package workflow
 import (
 	"context"
 	"testing"
-
-	"github.com/taadis/dify-sdk-go/client"
-	"github.com/taadis/dify-sdk-go/env"
 )
 
 func TestGetInfo(t *testing.T) {
@@ -14,11 +11,13 @@ func TestGetInfo(t *testing.T) {
 	// messages
 	req := &GetInfoRequest{}
 
-	client := NewWorkflowClient(client.DifyCloud, env.GetDifyApiKey())
+	client := NewWorkflowClient(testBaseUrl, testApiKey)
 	rsp, err := client.GetInfo(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	t.Log(rsp.String())
+	if rsp.Name == "" || len(rsp.Tags) == 0 {
+		t.Errorf("unexpected info: %s", rsp.String())
+	}
 }
@@ -3,20 +3,19 @@ package workflow
 import (
 	"context"
 	"testing"
-
-	"github.com/taadis/dify-sdk-go/client"
-	"github.com/taadis/dify-sdk-go/env"
 )
 
 func TestGetParamaters(t *testing.T) {
 	ctx := context.Background()
 
 	req := &GetParametersRequest{}
-	client := NewWorkflowClient(client.DifyCloud, env.GetDifyApiKey())
+	client := NewWorkflowClient(testBaseUrl, testApiKey)
 	rsp, err := client.GetParameters(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	t.Log(rsp.MarshalIndent())
+	if len(rsp.UserInputForm) == 0 || rsp.UserInputForm[0]["text-input"].Variable != "query" {
+		t.Errorf("unexpected parameters: %s", rsp.MarshalIndent())
+	}
 }
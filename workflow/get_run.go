@@ -29,12 +29,15 @@ type GetRunResponse struct {
 	Id string `json:"id"`
 	//
 	WorkflowId string `json:"workflow_id"`
+	// Version of the workflow that executed, "draft" for the draft; empty on Dify
+	// versions that do not report it.
+	Version string `json:"version,omitempty"`
 	// Available options: running, succeeded, failed, stopped
 	Status string `json:"status"`
-	// JSON string of input content.
-	Inputs string `json:"inputs"`
-	// JSON object of output content.(also is string)
-	Outputs string `json:"outputs,omitempty"`
+	// Input variables, sent as an object or a JSON string depending on the Dify version.
+	Inputs Variables `json:"inputs"`
+	// Output variables, see Variables.Decode and Variables.Files.
+	Outputs Variables `json:"outputs,omitempty"`
 	// error
 	Error *string `json:"error,omitempty"`
 	//
@@ -3,22 +3,61 @@ package workflow
 import (
 	"context"
 	"testing"
+
+	"github.com/taadis/dify-sdk-go/client"
+	"github.com/taadis/dify-sdk-go/difytest"
 )
 
 func TestGetRun(t *testing.T) {
 	ctx := context.Background()
 
-	workflowRunId := "your-workflow-run-id"
-	if workflowRunId == "your-workflow-run-id" {
-		t.Skip("Set a valid workflow_run_id to run this test.")
+	client := NewWorkflowClient(testBaseUrl, testApiKey)
+	run, err := client.Run(ctx, &RunRequest{
+		Inputs:       map[string]interface{}{"query": "hello"},
+		ResponseMode: "blocking",
+		User:         "test-user",
+	})
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	req := &GetRunRequest{WorkflowRunId: workflowRunId}
-	client := NewWorkflowClient(testBaseUrl, testApiKey)
+	req := &GetRunRequest{WorkflowRunId: run.WorkflowRunId}
 	rsp, err := client.GetRun(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	t.Log(rsp.MarshalIndent())
+	if rsp.Id != run.WorkflowRunId || rsp.Status != "succeeded" {
+		t.Errorf("unexpected run: %s", rsp.MarshalIndent())
+	}
+	if rsp.Inputs["query"] != "hello" || rsp.Outputs["query"] != "hello" {
+		t.Errorf("unexpected inputs %v and outputs %v", rsp.Inputs, rsp.Outputs)
+	}
+}
+
+func TestGetRunNotFound(t *testing.T) {
+	c := NewWorkflowClient(testBaseUrl, testApiKey)
+	_, err := c.GetRun(context.Background(), &GetRunRequest{WorkflowRunId: "unknown"})
+	if !client.IsNotFound(err) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
+
+func TestGetRunLegacyDetail(t *testing.T) {
+	ctx := context.Background()
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey), difytest.WithLegacyRunDetail())
+	defer srv.Close()
+	c := NewWorkflowClient(srv.BaseUrl(), testApiKey)
+
+	run, err := c.Run(ctx, &RunRequest{Inputs: map[string]interface{}{"query": "hello"}, User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp, err := c.GetRun(ctx, &GetRunRequest{WorkflowRunId: run.WorkflowRunId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Inputs["query"] != "hello" || rsp.Outputs["query"] != "hello" {
+		t.Errorf("unexpected inputs %v and outputs %v", rsp.Inputs, rsp.Outputs)
+	}
 }
@@ -0,0 +1,330 @@
+package workflow
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// LogFormat is the output format of a LogExporter.
+type LogFormat string
+
+const (
+	// LogFormatJSONL writes one LogRecord per line, as JSON.
+	LogFormatJSONL LogFormat = "jsonl"
+	// LogFormatCSV writes a header then one row per log with the columns id,
+	// workflow_run_id, version, status, error, elapsed_time, total_tokens, total_steps,
+	// created_at, finished_at, created_from, created_by_role, created_by_account,
+	// end_user_id and end_user_session_id, followed by workflow_id, inputs and outputs
+	// with LogExportOptions.WithRunDetail. Times are RFC 3339 in UTC, inputs and
+	// outputs are JSON.
+	LogFormatCSV LogFormat = "csv"
+)
+
+// LogRecord is a log written by a LogExporter.
+type LogRecord struct {
+	*WorkflowsLogItem
+	// Run holds the details of the run with LogExportOptions.WithRunDetail.
+	Run *GetRunResponse `json:"run,omitempty"`
+}
+
+// LogCursor is the position of a tailing LogExporter: the created_at of the last
+// exported log and the IDs of the logs exported within that second, created_at having
+// a resolution of one second.
+type LogCursor struct {
+	CreatedAt int64    `json:"created_at"`
+	Ids       []string `json:"ids"`
+}
+
+// LogCursorStore persists the cursor of a tailing LogExporter.
+type LogCursorStore interface {
+	// Load returns the saved cursor, nil when none was saved.
+	Load() (*LogCursor, error)
+	// Save replaces the saved cursor.
+	Save(cursor *LogCursor) error
+}
+
+// FileCursorStore stores the cursor as JSON in the file at Path.
+type FileCursorStore struct {
+	Path string
+}
+
+// Load returns the cursor saved in the file, nil when the file does not exist.
+func (s *FileCursorStore) Load() (*LogCursor, error) {
+	bs, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cursor := new(LogCursor)
+	if err := json.Unmarshal(bs, cursor); err != nil {
+		return nil, fmt.Errorf("workflow: invalid log cursor %s: %w", s.Path, err)
+	}
+	return cursor, nil
+}
+
+// Save writes the cursor to a temporary file renamed over the file, so an interrupted
+// save leaves the previous cursor.
+func (s *FileCursorStore) Save(cursor *LogCursor) error {
+	bs, err := json.Marshal(cursor)
+	if err != nil {
+		return err
+	}
+	tmp := s.Path + ".tmp"
+	if err := os.WriteFile(tmp, bs, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.Path)
+}
+
+// LogExportOptions configures a LogExporter.
+type LogExportOptions struct {
+	// Format of the output, LogFormatJSONL by default.
+	Format LogFormat
+	// Filter selects the logs by Keyword, Status, CreatedByEndUserSessionId,
+	// CreatedByAccount, CreatedAtAfter and CreatedAtBefore. Limit is the page size,
+	// Page is ignored.
+	Filter GetWorkflowLogsRequest
+	// WithRunDetail fetches the details of every run with GetRun, e.g. its inputs and
+	// outputs.
+	WithRunDetail bool
+	// OmitHeader leaves out the CSV header, e.g. when appending to a previous export.
+	OmitHeader bool
+	// Cursor persists the position of Tail, so a restarted Tail resumes after the
+	// last exported log.
+	Cursor LogCursorStore
+	// PollInterval is the time between two polls of Tail, 5s by default.
+	PollInterval time.Duration
+	// WaitFinished makes Tail hold back the runs still running, and the ones after them,
+	// until they finish.
+	WaitFinished bool
+}
+
+// LogExporter writes workflow logs to JSONL or CSV, either a time window with Export or
+// the new logs as they appear with Tail:
+//
+//	after := time.Now().Add(-24 * time.Hour)
+//	e := workflow.NewLogExporter(c, f, &workflow.LogExportOptions{
+//		Format:        workflow.LogFormatCSV,
+//		Filter:        workflow.GetWorkflowLogsRequest{Status: workflow.WorkflowStatusFailed, CreatedAtAfter: &after},
+//		WithRunDetail: true,
+//	})
+//	n, err := e.Export(ctx)
+type LogExporter struct {
+	c      WorkflowClient
+	opts   LogExportOptions
+	enc    *json.Encoder
+	csv    *csv.Writer
+	header bool
+}
+
+// NewLogExporter returns an exporter of the logs of c to w.
+func NewLogExporter(c WorkflowClient, w io.Writer, opts *LogExportOptions) *LogExporter {
+	e := &LogExporter{c: c}
+	if opts != nil {
+		e.opts = *opts
+	}
+	if e.opts.PollInterval <= 0 {
+		e.opts.PollInterval = 5 * time.Second
+	}
+	e.opts.Filter.Page = 0
+	if e.opts.Format == LogFormatCSV {
+		e.csv = csv.NewWriter(w)
+		e.header = e.opts.OmitHeader
+	} else {
+		e.enc = json.NewEncoder(w)
+	}
+	return e
+}
+
+// Export writes the logs matching the filter, newest first as listed by Dify, and
+// returns the number of logs written.
+func (e *LogExporter) Export(ctx context.Context) (int, error) {
+	req := e.opts.Filter
+	it := NewLogIterator(ctx, e.c, &req)
+	n := 0
+	for it.Next() {
+		if err := e.write(ctx, it.Current()); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := e.flush(); err != nil {
+		return n, err
+	}
+	return n, it.Err()
+}
+
+// Tail writes the logs matching the filter as they appear, oldest first, polling Dify
+// every PollInterval until ctx is done or an error occurs. Without a saved cursor the
+// first poll writes the logs created since Filter.CreatedAtAfter, all of them when it
+// is nil.
+//
+// The cursor is saved after each log is written, a restart writes at most the log
+// whose cursor was not saved again. Tail returns the error that stopped it, ctx.Err()
+// when ctx is done.
+func (e *LogExporter) Tail(ctx context.Context) error {
+	cursor := new(LogCursor)
+	if e.opts.Cursor != nil {
+		saved, err := e.opts.Cursor.Load()
+		if err != nil {
+			return err
+		}
+		if saved != nil {
+			cursor = saved
+		}
+	}
+	if cursor.CreatedAt == 0 && e.opts.Filter.CreatedAtAfter != nil {
+		cursor.CreatedAt = e.opts.Filter.CreatedAtAfter.Unix()
+	}
+
+	t := time.NewTimer(0)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+		if err := e.poll(ctx, cursor); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			return err
+		}
+		t.Reset(e.opts.PollInterval)
+	}
+}
+
+// poll writes the logs created since the cursor and moves it past them.
+func (e *LogExporter) poll(ctx context.Context, cursor *LogCursor) error {
+	req := e.opts.Filter
+	if cursor.CreatedAt > 0 {
+		after := time.Unix(cursor.CreatedAt, 0)
+		req.CreatedAtAfter = &after
+	}
+	seen := make(map[string]bool, len(cursor.Ids))
+	for _, id := range cursor.Ids {
+		seen[id] = true
+	}
+
+	// the logs are listed newest first and paged by offset, collect them to write them
+	// oldest first and drop the ones shifted to the next page by new runs
+	var items []*WorkflowsLogItem
+	it := NewLogIterator(ctx, e.c, &req)
+	for it.Next() {
+		item := it.Current()
+		if seen[item.Id] || item.CreatedAt < cursor.CreatedAt {
+			continue
+		}
+		seen[item.Id] = true
+		items = append(items, item)
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].CreatedAt < items[j].CreatedAt
+	})
+
+	for _, item := range items {
+		if e.opts.WaitFinished && !item.WorkflowRun.Status.IsTerminal() {
+			break
+		}
+		if err := e.write(ctx, item); err != nil {
+			return err
+		}
+		if err := e.flush(); err != nil {
+			return err
+		}
+		if item.CreatedAt > cursor.CreatedAt {
+			cursor.CreatedAt = item.CreatedAt
+			cursor.Ids = nil
+		}
+		cursor.Ids = append(cursor.Ids, item.Id)
+		if e.opts.Cursor != nil {
+			if err := e.opts.Cursor.Save(cursor); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *LogExporter) write(ctx context.Context, item *WorkflowsLogItem) error {
+	record := &LogRecord{WorkflowsLogItem: item}
+	if e.opts.WithRunDetail {
+		run, err := e.c.GetRun(ctx, &GetRunRequest{WorkflowRunId: item.WorkflowRun.Id})
+		if err != nil {
+			return fmt.Errorf("workflow: get run %s: %w", item.WorkflowRun.Id, err)
+		}
+		record.Run = run
+	}
+	if e.enc != nil {
+		return e.enc.Encode(record)
+	}
+
+	if !e.header {
+		header := []string{"id", "workflow_run_id", "version", "status", "error", "elapsed_time",
+			"total_tokens", "total_steps", "created_at", "finished_at", "created_from",
+			"created_by_role", "created_by_account", "end_user_id", "end_user_session_id"}
+		if e.opts.WithRunDetail {
+			header = append(header, "workflow_id", "inputs", "outputs")
+		}
+		if err := e.csv.Write(header); err != nil {
+			return err
+		}
+		e.header = true
+	}
+	run := &item.WorkflowRun
+	row := []string{
+		item.Id,
+		run.Id,
+		run.Version,
+		string(run.Status),
+		run.Error,
+		strconv.FormatFloat(run.ElapsedTime, 'f', -1, 64),
+		strconv.FormatInt(run.TotalTokens, 10),
+		strconv.FormatInt(run.TotalSteps, 10),
+		formatLogTime(item.CreatedAt),
+		formatLogTime(run.FinishedAt),
+		item.CreatedFrom,
+		item.CreatedByRole,
+		item.CreatedByAccount,
+		item.CreatedByEndUser.Id,
+		item.CreatedByEndUser.SessionId,
+	}
+	if record.Run != nil {
+		inputs, _ := json.Marshal(record.Run.Inputs)
+		outputs, _ := json.Marshal(record.Run.Outputs)
+		row = append(row, record.Run.WorkflowId, string(inputs), string(outputs))
+	}
+	return e.csv.Write(row)
+}
+
+func (e *LogExporter) flush() error {
+	if e.csv == nil {
+		return nil
+	}
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+func formatLogTime(t int64) string {
+	if t == 0 {
+		return ""
+	}
+	return time.Unix(t, 0).UTC().Format(time.RFC3339)
+}
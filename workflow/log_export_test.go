@@ -0,0 +1,130 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/taadis/dify-sdk-go/difytest"
+)
+
+// syncBuffer is a bytes.Buffer written by a tailing exporter while the test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Split(strings.TrimSpace(b.buf.String()), "\n")
+}
+
+func runQueries(t *testing.T, c WorkflowClient, user string, queries ...string) []string {
+	var ids []string
+	for _, q := range queries {
+		rsp, err := c.Run(context.Background(), &RunRequest{Inputs: map[string]interface{}{"query": q}, User: user})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, rsp.WorkflowRunId)
+	}
+	return ids
+}
+
+func TestLogExporterExport(t *testing.T) {
+	ctx := context.Background()
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey))
+	defer srv.Close()
+	c := NewWorkflowClient(srv.BaseUrl(), testApiKey)
+	runQueries(t, c, "test-user-export", "export 1", "export 2", "export 3")
+	filter := GetWorkflowLogsRequest{Keyword: "export", CreatedByEndUserSessionId: "test-user-export", Limit: 2}
+
+	var buf bytes.Buffer
+	n, err := NewLogExporter(c, &buf, &LogExportOptions{Filter: filter, WithRunDetail: true}).Export(ctx)
+	if err != nil || n != 3 {
+		t.Fatalf("n = %d, err = %v", n, err)
+	}
+	dec := json.NewDecoder(&buf)
+	for i := 0; i < 3; i++ {
+		var record LogRecord
+		if err := dec.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+		if record.Run == nil || record.Run.Id != record.WorkflowRun.Id || !strings.HasPrefix(record.Run.Inputs["query"].(string), "export") {
+			t.Errorf("unexpected record %+v", record)
+		}
+	}
+
+	buf.Reset()
+	n, err = NewLogExporter(c, &buf, &LogExportOptions{Format: LogFormatCSV, Filter: filter}).Export(ctx)
+	if err != nil || n != 3 {
+		t.Fatalf("n = %d, err = %v", n, err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || rows[0][0] != "id" || len(rows[0]) != 15 || rows[1][3] != string(WorkflowStatusSucceeded) {
+		t.Errorf("unexpected rows %v", rows)
+	}
+}
+
+func TestLogExporterTail(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey))
+	defer srv.Close()
+	c := NewWorkflowClient(srv.BaseUrl(), testApiKey)
+	opts := &LogExportOptions{
+		Cursor:       &FileCursorStore{Path: filepath.Join(t.TempDir(), "cursor.json")},
+		PollInterval: 10 * time.Millisecond,
+	}
+
+	tail := func(want int) []string {
+		var buf syncBuffer
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- NewLogExporter(c, &buf, opts).Tail(ctx) }()
+
+		var ids []string
+		deadline := time.Now().Add(5 * time.Second)
+		for len(ids) < want && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+			ids = ids[:0]
+			for _, line := range buf.lines() {
+				var record LogRecord
+				if line != "" && json.Unmarshal([]byte(line), &record) == nil {
+					ids = append(ids, record.WorkflowRun.Id)
+				}
+			}
+		}
+		// let a few more polls run, they must not write the logs again
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		if err := <-done; err != context.Canceled {
+			t.Errorf("err = %v, want context.Canceled", err)
+		}
+		return buf.lines()
+	}
+
+	first := runQueries(t, c, "test-user-tail", "tail 1", "tail 2")
+	if lines := tail(2); len(lines) != 2 || !strings.Contains(lines[0], first[0]) || !strings.Contains(lines[1], first[1]) {
+		t.Fatalf("first tail wrote %v, want %v in order", lines, first)
+	}
+
+	second := runQueries(t, c, "test-user-tail", "tail 3")
+	if lines := tail(1); len(lines) != 1 || !strings.Contains(lines[0], second[0]) {
+		t.Errorf("resumed tail wrote %v, want %v", lines, second)
+	}
+}
@@ -4,16 +4,19 @@ import (
 	"os"
 	"testing"
 
-	"github.com/taadis/dify-sdk-go/env"
+	"github.com/taadis/dify-sdk-go/difytest"
 )
 
 var (
 	testBaseUrl = ""
-	testApiKey  = ""
+	testApiKey  = "app-test"
+	testServer  *difytest.Server
 )
 
 func TestMain(m *testing.M) {
-	testBaseUrl = env.GetDifyBaseUrl()
-	testApiKey = env.GetDifyApiKey()
-	os.Exit(m.Run())
+	testServer = difytest.NewServer(difytest.WithAPIKey(testApiKey))
+	testBaseUrl = testServer.BaseUrl()
+	code := m.Run()
+	testServer.Close()
+	os.Exit(code)
 }
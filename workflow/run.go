@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+
+	"github.com/taadis/dify-sdk-go/client"
 )
 
 // FileInput 结构体
@@ -15,27 +17,34 @@ type FileInput struct {
 	UploadFileID   string `json:"upload_file_id,omitempty"` // 当 transfer_method 为 local_file 时使用
 }
 
+// Response modes of RunRequest.
+const (
+	ResponseModeBlocking  = "blocking"
+	ResponseModeStreaming = "streaming"
+)
+
 type RunRequest struct {
-	Inputs       map[string]interface{} `json:"inputs"`
-	ResponseMode string                 `json:"response_mode"`
-	User         string                 `json:"user"`
-	Files        []FileInput            `json:"files,omitempty"`
+	Inputs map[string]interface{} `json:"inputs"`
+	// Set by Run and RunStream, any value given here is ignored.
+	ResponseMode string      `json:"response_mode"`
+	User         string      `json:"user"`
+	Files        []FileInput `json:"files,omitempty"`
 }
 
 type RunResponse struct {
 	WorkflowRunId string `json:"workflow_run_id"`
 	TaskId        string `json:"task_id"`
 	Data          struct {
-		Id          string                 `json:"id"`
-		WorkflowId  string                 `json:"workflow_id"`
-		Status      string                 `json:"status"`
-		Outputs     map[string]interface{} `json:"outputs"`
-		Error       *string                `json:"error,omitempty"`
-		ElapsedTime float64                `json:"elapsed_time"`
-		TotalTokens int                    `json:"total_tokens"`
-		TotalSteps  int                    `json:"total_steps"`
-		CreatedAt   int64                  `json:"created_at"`
-		FinishedAt  int64                  `json:"finished_at"`
+		Id          string    `json:"id"`
+		WorkflowId  string    `json:"workflow_id"`
+		Status      string    `json:"status"`
+		Outputs     Variables `json:"outputs"`
+		Error       *string   `json:"error,omitempty"`
+		ElapsedTime float64   `json:"elapsed_time"`
+		TotalTokens int       `json:"total_tokens"`
+		TotalSteps  int       `json:"total_steps"`
+		CreatedAt   int64     `json:"created_at"`
+		FinishedAt  int64     `json:"finished_at"`
 	} `json:"data"`
 }
 
@@ -50,8 +59,33 @@ func (r *RunResponse) String() string {
 	return string(bs)
 }
 
+// RunByIdRequest runs a specific published version of the workflow.
+type RunByIdRequest struct {
+	// ID of the published workflow version, see GetRunResponse.WorkflowId.
+	WorkflowId string `json:"-"`
+	RunRequest
+}
+
+// Run executes the workflow in blocking mode, req.ResponseMode is ignored.
 func (c *workflowClient) Run(ctx context.Context, req *RunRequest) (*RunResponse, error) {
-	r, err := c.CreateBaseRequest(ctx, http.MethodPost, "/workflows/run", req)
+	if req == nil {
+		return nil, fmt.Errorf("request is required")
+	}
+	return c.run(ctx, "/workflows/run", req)
+}
+
+// RunById executes the published workflow version req.WorkflowId in blocking mode.
+func (c *workflowClient) RunById(ctx context.Context, req *RunByIdRequest) (*RunResponse, error) {
+	if req == nil || req.WorkflowId == "" {
+		return nil, fmt.Errorf("workflow_id is required")
+	}
+	return c.run(ctx, fmt.Sprintf("/workflows/%s/run", req.WorkflowId), &req.RunRequest)
+}
+
+func (c *workflowClient) run(ctx context.Context, url string, req *RunRequest) (*RunResponse, error) {
+	body := *req
+	body.ResponseMode = ResponseModeBlocking
+	r, err := c.CreateBaseRequest(ctx, http.MethodPost, url, &body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create base request: %w", err)
 	}
@@ -63,7 +97,7 @@ func (c *workflowClient) Run(ctx context.Context, req *RunRequest) (*RunResponse
 	defer rsp.Body.Close()
 
 	if rsp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status %s: %s", rsp.Status, c.ReadResponseBody(rsp.Body))
+		return nil, client.NewAPIError(rsp)
 	}
 
 	var workflowResp RunResponse
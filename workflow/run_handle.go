@@ -0,0 +1,331 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/taadis/dify-sdk-go/client"
+)
+
+// RunHandleOptions configures StartRun. Zero fields take default values.
+type RunHandleOptions struct {
+	// Delay before the first GetRun poll, 1s by default.
+	PollInterval time.Duration
+	// Upper bound of the delay between two polls, 30s by default.
+	MaxPollInterval time.Duration
+	// Growth factor of the delay between two polls, 1.5 by default.
+	Multiplier float64
+	// Number of consecutive failed polls after which Wait gives up, 5 by default.
+	MaxPollErrors int
+	// Close the stream once the run started and only poll for the result, instead of
+	// reading the stream to the end and polling only if it drops.
+	DetachStream bool
+}
+
+func (o *RunHandleOptions) withDefaults() RunHandleOptions {
+	var ret RunHandleOptions
+	if o != nil {
+		ret = *o
+	}
+	if ret.PollInterval <= 0 {
+		ret.PollInterval = time.Second
+	}
+	if ret.MaxPollInterval <= 0 {
+		ret.MaxPollInterval = 30 * time.Second
+	}
+	if ret.MaxPollInterval < ret.PollInterval {
+		ret.MaxPollInterval = ret.PollInterval
+	}
+	if ret.Multiplier < 1 {
+		ret.Multiplier = 1.5
+	}
+	if ret.MaxPollErrors <= 0 {
+		ret.MaxPollErrors = 5
+	}
+	return ret
+}
+
+// RunResult is the final state of a workflow run.
+type RunResult struct {
+	WorkflowRunId string         `json:"workflow_run_id"`
+	TaskId        string         `json:"task_id"`
+	WorkflowId    string         `json:"workflow_id"`
+	Status        WorkflowStatus `json:"status"`
+	Outputs       Variables      `json:"outputs"`
+	Error         string         `json:"error,omitempty"`
+	ElapsedTime   float64        `json:"elapsed_time"`
+	TotalTokens   int            `json:"total_tokens"`
+	TotalSteps    int            `json:"total_steps"`
+	CreatedAt     int64          `json:"created_at"`
+	FinishedAt    int64          `json:"finished_at"`
+}
+
+func (r *RunResult) String() string {
+	if r == nil {
+		return ""
+	}
+	bs, err := json.Marshal(r)
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+// IsTerminal reports whether a run in this status is over.
+func (s WorkflowStatus) IsTerminal() bool {
+	switch s {
+	case WorkflowStatusSucceeded, WorkflowStatusFailed, WorkflowStatusStopped, WorkflowStatusPartialSucceeded:
+		return true
+	}
+	return false
+}
+
+// ErrRunHandleClosed is returned by Wait once the handle was closed before the run ended.
+var ErrRunHandleClosed = errors.New("run handle closed")
+
+// RunHandle tracks a workflow run started by StartRun:
+//
+//	h, err := workflow.StartRun(ctx, c, req, nil)
+//	if err != nil {
+//		return err
+//	}
+//	defer h.Close()
+//	res, err := h.Wait(ctx)
+//
+// The result is read from the stream of the run, or polled with GetRun when the stream
+// is detached or drops before workflow_finished.
+type RunHandle struct {
+	c    WorkflowClient
+	user string
+	opts RunHandleOptions
+
+	taskId        string
+	workflowRunId string
+
+	// stream of the run, nil once detached
+	stream *Stream
+	// cancel stops polling
+	cancel context.CancelFunc
+	done   chan struct{}
+	result *RunResult
+	err    error
+
+	closeOnce sync.Once
+}
+
+// StartRun starts req in streaming mode and returns once the run started, i.e. when
+// workflow_started was received. ctx only bounds the start, the run is tracked until
+// it ends or Close is called; the context values, such as trace spans, are kept.
+func StartRun(ctx context.Context, c WorkflowClient, req *RunRequest, opts *RunHandleOptions) (*RunHandle, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request is required")
+	}
+	runCtx, cancel := context.WithCancel(client.WithoutCancel(ctx))
+	// cancel the start along with ctx
+	started := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-started:
+		}
+	}()
+
+	stream, err := c.RunStream(runCtx, req)
+	if err != nil {
+		close(started)
+		cancel()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+	var first *WorkflowStartedEvent
+	for first == nil && stream.Next() {
+		first, _ = stream.Current().(*WorkflowStartedEvent)
+	}
+	close(started)
+	if first == nil || runCtx.Err() != nil {
+		stream.Close()
+		cancel()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := stream.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("stream ended before workflow_started")
+	}
+
+	h := &RunHandle{
+		c:             c,
+		user:          req.User,
+		opts:          opts.withDefaults(),
+		taskId:        first.TaskId,
+		workflowRunId: first.WorkflowRunId,
+		cancel:        cancel,
+		done:          make(chan struct{}),
+	}
+	if h.workflowRunId == "" {
+		h.workflowRunId = first.Data.Id
+	}
+	if h.opts.DetachStream {
+		stream.Close()
+	} else {
+		h.stream = stream
+	}
+	go h.track(runCtx)
+	return h, nil
+}
+
+// TaskId returns the task ID of the run, used to stop it.
+func (h *RunHandle) TaskId() string {
+	return h.taskId
+}
+
+// WorkflowRunId returns the ID of the run, used to get its details.
+func (h *RunHandle) WorkflowRunId() string {
+	return h.workflowRunId
+}
+
+// Done is closed once the result of the run is known, or the handle was closed.
+func (h *RunHandle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Wait waits for the end of the run. A run that failed or was stopped is not an error,
+// check RunResult.Status; an error is returned when the result could not be read,
+// ErrRunHandleClosed after Close, or ctx.Err() when ctx is done first.
+func (h *RunHandle) Wait(ctx context.Context) (*RunResult, error) {
+	select {
+	case <-h.done:
+		return h.result, h.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Cancel stops the run with StopTask, Wait then reports it as stopped. Canceling a
+// run that is over is a no-op.
+func (h *RunHandle) Cancel(ctx context.Context) error {
+	select {
+	case <-h.done:
+		return nil
+	default:
+	}
+	_, err := h.c.StopTask(ctx, &StopTaskRequest{TaskId: h.taskId, User: h.user})
+	return err
+}
+
+// Close stops tracking the run, without stopping it, and releases its connection.
+func (h *RunHandle) Close() error {
+	h.closeOnce.Do(func() {
+		// a stream closed by the caller is left alone by AutoStop, even once its context is canceled
+		if h.stream != nil {
+			h.stream.Close()
+		}
+		h.cancel()
+	})
+	return nil
+}
+
+func (h *RunHandle) track(ctx context.Context) {
+	defer h.Close()
+	if stream := h.stream; stream != nil {
+		for stream.Next() {
+			if e, ok := stream.Current().(*WorkflowFinishedEvent); ok {
+				stream.stream.End()
+				h.finish(resultFromEvent(e, h.taskId), nil)
+				return
+			}
+		}
+		stream.Close()
+		// the connection dropped before the end of the run, poll for its result
+	}
+	h.finish(h.poll(ctx))
+}
+
+func (h *RunHandle) poll(ctx context.Context) (*RunResult, error) {
+	interval := h.opts.PollInterval
+	errs := 0
+	for {
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ErrRunHandleClosed
+		case <-timer.C:
+		}
+
+		run, err := h.c.GetRun(ctx, &GetRunRequest{WorkflowRunId: h.workflowRunId})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ErrRunHandleClosed
+			}
+			if apiErr, ok := client.AsAPIError(err); ok && !client.IsRateLimited(err) && !client.IsServerError(err) && !client.IsNotFound(err) {
+				return nil, apiErr
+			}
+			if errs++; errs >= h.opts.MaxPollErrors {
+				return nil, fmt.Errorf("failed to poll workflow run %s: %w", h.workflowRunId, err)
+			}
+		} else {
+			errs = 0
+			if status := WorkflowStatus(run.Status); status.IsTerminal() {
+				return resultFromRun(run, h.taskId), nil
+			}
+		}
+
+		interval = time.Duration(float64(interval) * h.opts.Multiplier)
+		if interval > h.opts.MaxPollInterval {
+			interval = h.opts.MaxPollInterval
+		}
+	}
+}
+
+func (h *RunHandle) finish(result *RunResult, err error) {
+	h.result, h.err = result, err
+	close(h.done)
+}
+
+func resultFromEvent(e *WorkflowFinishedEvent, taskId string) *RunResult {
+	return &RunResult{
+		WorkflowRunId: e.WorkflowRunId,
+		TaskId:        taskId,
+		WorkflowId:    e.Data.WorkflowId,
+		Status:        e.Data.Status,
+		Outputs:       e.Data.Outputs,
+		Error:         e.Data.Error,
+		ElapsedTime:   e.Data.ElapsedTime,
+		TotalTokens:   e.Data.TotalTokens,
+		TotalSteps:    e.Data.TotalSteps,
+		CreatedAt:     e.Data.CreatedAt,
+		FinishedAt:    e.Data.FinishedAt,
+	}
+}
+
+func resultFromRun(run *GetRunResponse, taskId string) *RunResult {
+	ret := &RunResult{
+		WorkflowRunId: run.Id,
+		TaskId:        taskId,
+		WorkflowId:    run.WorkflowId,
+		Status:        WorkflowStatus(run.Status),
+		TotalTokens:   run.TotalTokens,
+		TotalSteps:    run.TotalSteps,
+		Outputs:       run.Outputs,
+		CreatedAt:     run.CreatedAt,
+	}
+	if run.Error != nil {
+		ret.Error = *run.Error
+	}
+	if run.ElapsedTime != nil {
+		ret.ElapsedTime = *run.ElapsedTime
+	}
+	if run.FinishedAt != nil {
+		ret.FinishedAt = *run.FinishedAt
+	}
+	return ret
+}
@@ -0,0 +1,183 @@
+package workflow
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/taadis/dify-sdk-go/client"
+	"github.com/taadis/dify-sdk-go/difytest"
+)
+
+func TestStartRunWait(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c := NewWorkflowClient(testBaseUrl, testApiKey)
+
+	h, err := StartRun(ctx, c, &RunRequest{Inputs: map[string]interface{}{"query": "hello"}, User: "test-user"}, nil)
+	if err != nil {
+		t.Fatalf("StartRun encountered an error: %v", err)
+	}
+	defer h.Close()
+	if h.TaskId() == "" || h.WorkflowRunId() == "" {
+		t.Fatalf("task_id = %q, workflow_run_id = %q", h.TaskId(), h.WorkflowRunId())
+	}
+
+	res, err := h.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != WorkflowStatusSucceeded || res.WorkflowRunId != h.WorkflowRunId() || res.Outputs["query"] != "hello" {
+		t.Errorf("unexpected result %s", res.String())
+	}
+}
+
+func TestStartRunCancel(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey), difytest.WithLegacyRunDetail(), difytest.WithStreamDelay(50*time.Millisecond))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	h, err := StartRun(ctx, NewWorkflowClient(srv.BaseUrl(), testApiKey), &RunRequest{User: "test-user"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	if err := h.Cancel(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res, err := h.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != WorkflowStatusStopped {
+		t.Errorf("status = %s, want stopped", res.Status)
+	}
+	if stopped := srv.StoppedTasks(); len(stopped) != 1 || stopped[0] != h.TaskId() {
+		t.Errorf("stopped tasks = %v", stopped)
+	}
+	if err := h.Cancel(ctx); err != nil {
+		t.Errorf("Cancel after the end: %v", err)
+	}
+}
+
+func TestStartRunDetachStream(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey), difytest.WithLegacyRunDetail(), difytest.WithStreamDelay(20*time.Millisecond))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	h, err := StartRun(ctx, NewWorkflowClient(srv.BaseUrl(), testApiKey), &RunRequest{
+		Inputs: map[string]interface{}{"query": "hello"},
+		User:   "test-user",
+	}, &RunHandleOptions{DetachStream: true, PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	res, err := h.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != WorkflowStatusSucceeded || res.Outputs["query"] != "hello" || res.FinishedAt == 0 {
+		t.Errorf("unexpected result %s", res.String())
+	}
+	if n := len(srv.RequestsTo(http.MethodGet, "/workflows/run/{workflow_run_id}")); n < 2 {
+		t.Errorf("%d polls, want the run to be polled while running", n)
+	}
+}
+
+func TestStartRunStreamDrop(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey), difytest.WithLegacyRunDetail())
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c := NewWorkflowClient(srv.BaseUrl(), testApiKey)
+
+	// the run goes on server side while the connection drops after workflow_started
+	run, err := c.Run(ctx, &RunRequest{Inputs: map[string]interface{}{"query": "hello"}, User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Handle(http.MethodPost, "/workflows/run", difytest.Stream(
+		map[string]interface{}{"event": "workflow_started", "task_id": run.TaskId, "workflow_run_id": run.WorkflowRunId,
+			"data": map[string]interface{}{"id": run.WorkflowRunId}},
+		map[string]interface{}{"event": "node_started", "task_id": run.TaskId, "workflow_run_id": run.WorkflowRunId,
+			"data": map[string]interface{}{"node_id": "start"}},
+	))
+
+	h, err := StartRun(ctx, c, &RunRequest{User: "test-user"}, &RunHandleOptions{PollInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	res, err := h.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != WorkflowStatusSucceeded || res.WorkflowRunId != run.WorkflowRunId || res.TaskId != run.TaskId {
+		t.Errorf("unexpected result %s", res.String())
+	}
+	srv.AssertRequest(t, http.MethodGet, "/workflows/run/"+run.WorkflowRunId)
+}
+
+func TestStartRunClose(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey), difytest.WithLegacyRunDetail(), difytest.WithStreamDelay(200*time.Millisecond))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	h, err := StartRun(ctx, NewWorkflowClient(srv.BaseUrl(), testApiKey), &RunRequest{User: "test-user"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Close()
+	if _, err := h.Wait(ctx); err != ErrRunHandleClosed {
+		t.Errorf("Wait after Close: %v", err)
+	}
+	if len(srv.StoppedTasks()) != 0 {
+		t.Error("Close stopped the run")
+	}
+}
+
+func TestStartRunCloseAutoStop(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey), difytest.WithLegacyRunDetail(), difytest.WithStreamDelay(200*time.Millisecond))
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stops := make(chan client.StopResult, 1)
+	c := NewWorkflowClientWithConfig(&client.ClientConfig{BaseUrl: srv.BaseUrl(), ApiKey: testApiKey, AutoStop: &client.AutoStop{
+		OnStop: func(r client.StopResult) { stops <- r },
+	}})
+
+	h, err := StartRun(ctx, c, &RunRequest{User: "test-user"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Close()
+	cancel()
+
+	select {
+	case r := <-stops:
+		t.Errorf("task %s was stopped", r.TaskId)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if len(srv.StoppedTasks()) != 0 {
+		t.Error("Close stopped the run")
+	}
+}
+
+func TestStartRunError(t *testing.T) {
+	_, err := StartRun(context.Background(), NewWorkflowClient(testBaseUrl, "app-invalid"), &RunRequest{User: "test-user"}, nil)
+	if !client.IsUnauthorized(err) {
+		t.Errorf("expected an unauthorized error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := StartRun(ctx, NewWorkflowClient(testBaseUrl, testApiKey), &RunRequest{User: "test-user"}, nil); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/taadis/dify-sdk-go/client"
+)
+
+// Stream iterates over the events of a streaming workflow run:
+//
+//	stream, err := c.RunStream(ctx, req)
+//	if err != nil {
+//		return err
+//	}
+//	defer stream.Close()
+//	for stream.Next() {
+//		switch e := stream.Current().(type) {
+//		case *TextChunkEvent:
+//			fmt.Print(e.Data.Text)
+//		case *WorkflowFinishedEvent:
+//			fmt.Println(e.Data.Outputs)
+//		}
+//	}
+//	return stream.Err()
+//
+// Events are decoded by DecodeEvent, keep-alive pings are skipped unless KeepPings is
+// called. The response body
+// is closed at the end of the stream, after an error event, by Close, or when ctx is done.
+type Stream struct {
+	stream  *client.EventStream
+	current Event
+	err     error
+	done    bool
+}
+
+// NewStream returns a Stream decoding the events of stream.
+func NewStream(stream *client.EventStream) *Stream {
+	return &Stream{stream: stream}
+}
+
+// KeepPings makes Next return the keep-alive pings as *PingEvent, from the next event on.
+func (s *Stream) KeepPings() {
+	s.stream.KeepPings()
+}
+
+// Next advances to the next event, it returns false when the stream is over.
+// After an error event, Current returns the *ErrorEvent and Err its *client.APIError.
+func (s *Stream) Next() bool {
+	if s.done {
+		return false
+	}
+	if !s.stream.Next() {
+		s.done = true
+		return false
+	}
+
+	e, err := DecodeEvent(s.stream.Data())
+	if err != nil {
+		s.err = fmt.Errorf("error decoding %s event: %w", s.stream.Event(), err)
+		s.Close()
+		return false
+	}
+	s.current = e
+	if _, ok := e.(*ErrorEvent); ok {
+		s.err = client.StreamEventError(s.stream.Data())
+		s.stream.End()
+		return false
+	}
+	return true
+}
+
+// Current returns the current event.
+func (s *Stream) Current() Event {
+	return s.current
+}
+
+// Data returns the JSON data of the current event.
+func (s *Stream) Data() []byte {
+	return s.stream.Data()
+}
+
+// Err returns the error that ended the stream, nil for a complete stream.
+func (s *Stream) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.stream.Err()
+}
+
+// Close ends the stream and releases the connection, it may be called from another goroutine.
+func (s *Stream) Close() error {
+	return s.stream.Close()
+}
+
+// RunStream executes the workflow in streaming mode, req.ResponseMode is ignored.
+// Error responses are returned as a *client.APIError.
+func (c *workflowClient) RunStream(ctx context.Context, req *RunRequest) (*Stream, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request is required")
+	}
+	return c.runStream(ctx, "/workflows/run", req)
+}
+
+// RunByIdStream executes the published workflow version req.WorkflowId in streaming mode.
+func (c *workflowClient) RunByIdStream(ctx context.Context, req *RunByIdRequest) (*Stream, error) {
+	if req == nil || req.WorkflowId == "" {
+		return nil, fmt.Errorf("workflow_id is required")
+	}
+	return c.runStream(ctx, fmt.Sprintf("/workflows/%s/run", req.WorkflowId), &req.RunRequest)
+}
+
+func (c *workflowClient) runStream(ctx context.Context, url string, req *RunRequest) (*Stream, error) {
+	body := *req
+	body.ResponseMode = ResponseModeStreaming
+	r, err := c.CreateBaseRequest(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create base request: %w", err)
+	}
+
+	stream, err := c.SendStoppableStreamRequest(r, func(ctx context.Context, taskId string) error {
+		_, err := c.StopTask(ctx, &StopTaskRequest{TaskId: taskId, User: req.User})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return NewStream(stream), nil
+}
+
+// RunStreamWithHandler executes the workflow in streaming mode and calls the method of
+// h matching each event. An error event is passed to OnError, then returned as a
+// *client.APIError.
+func (c *workflowClient) RunStreamWithHandler(ctx context.Context, req *RunRequest, h EventHandler) error {
+	stream, err := c.RunStream(ctx, req)
+	if err != nil {
+		return err
+	}
+	return HandleStream(stream, h)
+}
+
+// HandleStream reads stream to the end, dispatching each event to h, pings included,
+// then closes it.
+func HandleStream(stream *Stream, h EventHandler) error {
+	defer stream.Close()
+	stream.KeepPings()
+
+	for stream.Next() {
+		DispatchEvent(h, stream.Current())
+	}
+	if e, ok := stream.Current().(*ErrorEvent); ok {
+		h.OnError(e)
+	}
+	return stream.Err()
+}
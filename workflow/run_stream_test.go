@@ -0,0 +1,116 @@
+package workflow
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/taadis/dify-sdk-go/client"
+	"github.com/taadis/dify-sdk-go/difytest"
+)
+
+func TestRunStream(t *testing.T) {
+	ctx := context.Background()
+	c := NewWorkflowClient(testBaseUrl, testApiKey)
+
+	req := &RunRequest{
+		Inputs:       map[string]interface{}{"query": "hello"},
+		ResponseMode: ResponseModeBlocking,
+		User:         "test-user",
+	}
+	stream, err := c.RunStream(ctx, req)
+	if err != nil {
+		t.Fatalf("RunStream encountered an error: %v", err)
+	}
+	defer stream.Close()
+
+	var (
+		started  *WorkflowStartedEvent
+		finished *WorkflowFinishedEvent
+		text     string
+		nodes    int
+	)
+	for stream.Next() {
+		switch e := stream.Current().(type) {
+		case *WorkflowStartedEvent:
+			started = e
+		case *NodeFinishedEvent:
+			nodes++
+		case *TextChunkEvent:
+			text += e.Data.Text
+		case *WorkflowFinishedEvent:
+			finished = e
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if started == nil || started.TaskId == "" || started.WorkflowRunId == "" {
+		t.Errorf("unexpected workflow_started %+v", started)
+	}
+	if nodes == 0 || text == "" {
+		t.Errorf("nodes = %d, text = %q", nodes, text)
+	}
+	if finished == nil || finished.Data.Status != WorkflowStatusSucceeded || finished.Data.Outputs["query"] != "hello" {
+		t.Errorf("unexpected workflow_finished %+v", finished)
+	}
+	if req.ResponseMode != ResponseModeBlocking {
+		t.Errorf("RunStream modified the request: %q", req.ResponseMode)
+	}
+	if mode := testServer.AssertRequest(t, http.MethodPost, "/workflows/run").Map()["response_mode"]; mode != ResponseModeStreaming {
+		t.Errorf("response_mode = %v", mode)
+	}
+}
+
+type recordingHandler struct {
+	BaseEventHandler
+	events []string
+	err    *ErrorEvent
+}
+
+func (h *recordingHandler) OnWorkflowStarted(e *WorkflowStartedEvent) {
+	h.events = append(h.events, e.Event)
+}
+
+func (h *recordingHandler) OnLoopCompleted(e *LoopCompletedEvent) {
+	h.events = append(h.events, e.Event)
+}
+
+func (h *recordingHandler) OnPing(e *PingEvent) {
+	h.events = append(h.events, e.Event)
+}
+
+func (h *recordingHandler) OnError(e *ErrorEvent) {
+	h.err = e
+}
+
+func TestRunStreamWithHandler(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey))
+	defer srv.Close()
+	srv.Handle(http.MethodPost, "/workflows/run", difytest.Stream(
+		map[string]interface{}{"event": "workflow_started", "task_id": "task-1", "workflow_run_id": "run-1", "data": map[string]interface{}{"id": "run-1"}},
+		"event: ping\n\n",
+		map[string]interface{}{"event": "loop_completed", "task_id": "task-1", "workflow_run_id": "run-1", "data": map[string]interface{}{"node_id": "loop", "steps": 2}},
+		map[string]interface{}{"event": "error", "task_id": "task-1", "status": 500, "code": "internal_server_error", "message": "boom"},
+	))
+
+	h := &recordingHandler{}
+	err := NewWorkflowClient(srv.BaseUrl(), testApiKey).RunStreamWithHandler(context.Background(), &RunRequest{User: "test-user"}, h)
+	if apiErr, ok := client.AsAPIError(err); !ok || apiErr.Code != "internal_server_error" || !strings.Contains(string(apiErr.Body), "boom") {
+		t.Fatalf("expected an internal_server_error, got %v", err)
+	}
+	if len(h.events) != 3 || h.events[1] != EventPing || h.events[2] != EventLoopCompleted {
+		t.Errorf("events = %v", h.events)
+	}
+	if h.err == nil || h.err.Message != "boom" {
+		t.Errorf("error event = %+v", h.err)
+	}
+}
+
+func TestRunStreamHTTPError(t *testing.T) {
+	_, err := NewWorkflowClient(testBaseUrl, "app-invalid").RunStream(context.Background(), &RunRequest{User: "test-user"})
+	if !client.IsUnauthorized(err) {
+		t.Fatalf("expected an unauthorized error, got %v", err)
+	}
+}
@@ -2,7 +2,11 @@ package workflow
 
 import (
 	"context"
+	"net/http"
 	"testing"
+
+	"github.com/taadis/dify-sdk-go/client"
+	"github.com/taadis/dify-sdk-go/difytest"
 )
 
 func TestRun(t *testing.T) {
@@ -54,3 +58,66 @@ func TestRun(t *testing.T) {
 
 	t.Logf("Received workflow response: %+v", resp)
 }
+
+func TestRunById(t *testing.T) {
+	const canaryId = "00000000-0000-4000-8000-0000000000f2"
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey), difytest.WithLegacyRunDetail(),
+		difytest.WithWorkflowVersion(canaryId, "2"))
+	defer srv.Close()
+	ctx := context.Background()
+	c := NewWorkflowClient(srv.BaseUrl(), testApiKey)
+
+	req := &RunByIdRequest{
+		WorkflowId: canaryId,
+		RunRequest: RunRequest{Inputs: map[string]interface{}{"query": "hello"}, User: "test-user"},
+	}
+	resp, err := c.RunById(ctx, req)
+	if err != nil {
+		t.Fatalf("RunById encountered an error: %v", err)
+	}
+	if resp.Data.WorkflowId != canaryId || resp.Data.Status != string(WorkflowStatusSucceeded) {
+		t.Errorf("unexpected response %s", resp.String())
+	}
+	srv.AssertRequest(t, http.MethodPost, "/workflows/"+canaryId+"/run")
+
+	run, err := c.GetRun(ctx, &GetRunRequest{WorkflowRunId: resp.WorkflowRunId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Version != "2" {
+		t.Errorf("version = %q, want 2", run.Version)
+	}
+
+	stream, err := c.RunByIdStream(ctx, req)
+	if err != nil {
+		t.Fatalf("RunByIdStream encountered an error: %v", err)
+	}
+	defer stream.Close()
+	var finished *WorkflowFinishedEvent
+	for stream.Next() {
+		if e, ok := stream.Current().(*WorkflowFinishedEvent); ok {
+			finished = e
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if finished == nil || finished.Data.WorkflowId != canaryId {
+		t.Errorf("unexpected workflow_finished %+v", finished)
+	}
+
+	logs, err := c.GetWorkflowLogs(ctx, &GetWorkflowLogsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range logs.Data {
+		if item.WorkflowRun.Version != "2" {
+			t.Errorf("log %s: version = %q, want 2", item.Id, item.WorkflowRun.Version)
+		}
+	}
+
+	_, err = c.RunById(ctx, &RunByIdRequest{WorkflowId: "00000000-0000-4000-8000-0000000000ff", RunRequest: RunRequest{User: "test-user"}})
+	if !client.IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
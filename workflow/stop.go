@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+
+	"github.com/taadis/dify-sdk-go/client"
 )
 
 // StopTaskRequest is the request struct for stopping a workflow task generation.
@@ -46,7 +48,7 @@ func (c *workflowClient) StopTask(ctx context.Context, req *StopTaskRequest) (*S
 		return nil, fmt.Errorf("task_id and user are required")
 	}
 	url := fmt.Sprintf("/workflows/tasks/%s/stop", req.TaskId)
-	httpReq, err := c.CreateBaseRequest(ctx, http.MethodPost, url, map[string]string{"user": req.User})
+	httpReq, err := c.CreateBaseRequest(client.WithIdempotent(ctx), http.MethodPost, url, map[string]string{"user": req.User})
 	if err != nil {
 		return nil, err
 	}
@@ -1,28 +1,189 @@
 package workflow
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/taadis/dify-sdk-go/client"
-	"github.com/taadis/dify-sdk-go/env"
+	"github.com/taadis/dify-sdk-go/difytest"
 )
 
 func TestStopTask(t *testing.T) {
 	ctx := context.Background()
 
-	taskId := "your-task-id"
-	user := "your-user"
-	if taskId == "your-task-id" || user == "your-user" {
-		t.Skip("Set a valid task_id and user to run this test.")
+	srv := difytest.NewServer(difytest.WithStreamDelay(20 * time.Millisecond))
+	defer srv.Close()
+	c := NewWorkflowClient(srv.BaseUrl(), testApiKey)
+
+	// start a streaming run and read its task id from the first event
+	core := client.NewClient(srv.BaseUrl(), testApiKey)
+	httpReq, err := core.CreateBaseRequest(ctx, http.MethodPost, "/workflows/run", &RunRequest{
+		Inputs:       map[string]interface{}{"query": "hello"},
+		ResponseMode: "streaming",
+		User:         "test-user",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := core.SendRequest(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+
+	var taskId, lastEvent, status string
+	scanner := bufio.NewScanner(stream.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var event struct {
+			Event  string `json:"event"`
+			TaskId string `json:"task_id"`
+			Data   struct {
+				Status string `json:"status"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(line[6:]), &event); err != nil {
+			t.Fatal(err)
+		}
+		lastEvent, status = event.Event, event.Data.Status
+		if taskId != "" {
+			continue
+		}
+		taskId = event.TaskId
+
+		req := &StopTaskRequest{TaskId: taskId, User: "test-user"}
+		rsp, err := c.StopTask(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Result != "success" {
+			t.Errorf("unexpected response: %s", rsp.String())
+		}
+	}
+
+	if stopped := srv.StoppedTasks(); len(stopped) != 1 || stopped[0] != taskId {
+		t.Errorf("stopped tasks = %v, want [%s]", stopped, taskId)
+	}
+	if lastEvent != "workflow_finished" || status != "stopped" {
+		t.Errorf("last event = %s with status %q, want a stopped workflow_finished", lastEvent, status)
+	}
+}
+
+func TestRunStreamAutoStop(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey), difytest.WithLegacyRunDetail(), difytest.WithStreamDelay(20*time.Millisecond))
+	defer srv.Close()
+	results := make(chan client.StopResult, 1)
+	c := NewWorkflowClientWithConfig(&client.ClientConfig{BaseUrl: srv.BaseUrl(), ApiKey: testApiKey, AutoStop: &client.AutoStop{
+		OnStop: func(r client.StopResult) { results <- r },
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := c.RunStream(ctx, &RunRequest{User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if !stream.Next() {
+		t.Fatal(stream.Err())
+	}
+	started := stream.Current().(*WorkflowStartedEvent)
+	cancel()
+
+	select {
+	case r := <-results:
+		if r.Err != nil || r.TaskId != started.TaskId {
+			t.Errorf("unexpected stop result %+v, want task %s", r, started.TaskId)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the task was not stopped")
+	}
+	// the fake server ends the run shortly after the stop call
+	var run *GetRunResponse
+	for i := 0; i < 50; i++ {
+		if run, err = c.GetRun(context.Background(), &GetRunRequest{WorkflowRunId: started.WorkflowRunId}); err != nil {
+			t.Fatal(err)
+		}
+		if run.Status != string(WorkflowStatusRunning) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if run.Status != string(WorkflowStatusStopped) {
+		t.Errorf("status = %s, want stopped", run.Status)
+	}
+
+	// closing a run handle stops tracking the run, not the run itself
+	h, err := StartRun(context.Background(), c, &RunRequest{User: "test-user"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Close()
+	select {
+	case r := <-results:
+		t.Errorf("Close stopped task %s", r.TaskId)
+	case <-time.After(100 * time.Millisecond):
 	}
+}
+
+func TestRunStreamBudget(t *testing.T) {
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey), difytest.WithStreamDelay(20*time.Millisecond))
+	defer srv.Close()
+	budget := &client.Budget{PerRun: client.BudgetLimit{Tokens: 3}, PerUser: client.BudgetLimit{Tokens: 8}}
+	c := NewWorkflowClientWithConfig(&client.ClientConfig{BaseUrl: srv.BaseUrl(), ApiKey: testApiKey, Budget: budget})
+	ctx := context.Background()
 
-	req := &StopTaskRequest{TaskId: taskId, User: user}
-	client := NewWorkflowClient(client.DifyCloud, env.GetDifyApiKey())
-	rsp, err := client.StopTask(ctx, req)
+	// the llm node of the fake workflow spends a token per word of the inputs
+	stream, err := c.RunStream(ctx, &RunRequest{Inputs: map[string]interface{}{"query": "one two three four"}, User: "test-user-budget"})
 	if err != nil {
 		t.Fatal(err)
 	}
+	var started *WorkflowStartedEvent
+	for stream.Next() {
+		if e, ok := stream.Current().(*WorkflowStartedEvent); ok {
+			started = e
+		}
+	}
+	var budgetErr *client.BudgetExceededError
+	if !errors.As(stream.Err(), &budgetErr) || budgetErr.Scope != client.BudgetScopeRun || budgetErr.TaskId != started.TaskId {
+		t.Fatalf("err = %v, want a run budget error", stream.Err())
+	}
+	for i := 0; i < 50 && len(srv.StoppedTasks()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stopped := srv.StoppedTasks(); len(stopped) != 1 || stopped[0] != started.TaskId {
+		t.Errorf("stopped tasks = %v, want %s", stopped, started.TaskId)
+	}
 
-	t.Log(rsp.String())
+	if _, err := c.Run(ctx, &RunRequest{Inputs: map[string]interface{}{"query": "one two"}, User: "test-user-budget"}); err != nil {
+		t.Fatal(err)
+	}
+	// blocking runs are not counted
+	if total := budget.UserTotal("test-user-budget"); total.Tokens != 4 || total.Currency != "USD" {
+		t.Errorf("user total = %+v", total)
+	}
+	for i := 0; i < 2; i++ {
+		stream, err = c.RunStream(ctx, &RunRequest{Inputs: map[string]interface{}{"query": "one two"}, User: "test-user-budget"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for stream.Next() {
+		}
+		if err := stream.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 8 tokens spent, the user has no budget left
+	_, err = c.RunStream(ctx, &RunRequest{User: "test-user-budget"})
+	if !errors.Is(err, client.ErrBudgetExceeded) {
+		t.Errorf("err = %v, want ErrBudgetExceeded", err)
+	}
 }
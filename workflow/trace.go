@@ -0,0 +1,393 @@
+package workflow
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/taadis/dify-sdk-go/client"
+)
+
+// Trace is the execution tree of a workflow run, built by a TraceRecorder.
+type Trace struct {
+	WorkflowRunId string         `json:"workflow_run_id"`
+	WorkflowId    string         `json:"workflow_id"`
+	TaskId        string         `json:"task_id"`
+	Status        WorkflowStatus `json:"status"`
+	Error         string         `json:"error,omitempty"`
+	// ElapsedTime of the run in seconds, as reported by Dify.
+	ElapsedTime float64 `json:"elapsed_time"`
+	TotalTokens int     `json:"total_tokens"`
+	TotalSteps  int     `json:"total_steps"`
+	// End is the offset of workflow_finished from workflow_started, as received.
+	End time.Duration `json:"end"`
+	// Nodes are the executions of the top-level nodes, in start order.
+	Nodes []*TraceNode `json:"nodes"`
+	// Branches are the parallel branches, in start order.
+	Branches []*TraceBranch `json:"branches,omitempty"`
+}
+
+// TraceNode is a node execution of a Trace.
+type TraceNode struct {
+	// Node execution ID
+	Id                string `json:"id"`
+	NodeId            string `json:"node_id"`
+	NodeType          string `json:"node_type"`
+	Title             string `json:"title"`
+	Index             int    `json:"index"`
+	PredecessorNodeId string `json:"predecessor_node_id,omitempty"`
+	// running, succeeded, failed, exception or stopped
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Start and End are the offsets of the start and finish events from
+	// workflow_started, as received; End is 0 while the node runs.
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+	// ElapsedTime in seconds, as reported by Dify.
+	ElapsedTime float64                `json:"elapsed_time"`
+	TotalTokens int                    `json:"total_tokens,omitempty"`
+	TotalPrice  client.Decimal         `json:"total_price,omitempty"`
+	Currency    string                 `json:"currency,omitempty"`
+	Inputs      map[string]interface{} `json:"inputs,omitempty"`
+	Outputs     map[string]interface{} `json:"outputs,omitempty"`
+	// Retries counts the node_retry events of the node.
+	Retries             int    `json:"retries,omitempty"`
+	ParallelId          string `json:"parallel_id,omitempty"`
+	ParallelStartNodeId string `json:"parallel_start_node_id,omitempty"`
+	// Round is the iteration or loop round of a node nested in a container, from 1.
+	Round int `json:"round,omitempty"`
+	// Rounds counts the rounds of an iteration or loop node.
+	Rounds int `json:"rounds,omitempty"`
+	// Children are the executions nested in an iteration or loop node, in start order.
+	Children []*TraceNode `json:"children,omitempty"`
+}
+
+// Duration returns the duration of the node, the elapsed time reported by Dify when
+// known, the time between its events otherwise.
+func (n *TraceNode) Duration() time.Duration {
+	if n.ElapsedTime > 0 {
+		return time.Duration(n.ElapsedTime * float64(time.Second))
+	}
+	if n.End > n.Start {
+		return n.End - n.Start
+	}
+	return 0
+}
+
+// TraceBranch is a parallel branch of a Trace.
+type TraceBranch struct {
+	ParallelId          string        `json:"parallel_id"`
+	ParallelStartNodeId string        `json:"parallel_start_node_id"`
+	ParentParallelId    string        `json:"parent_parallel_id,omitempty"`
+	Status              string        `json:"status"`
+	Error               string        `json:"error,omitempty"`
+	Start               time.Duration `json:"start"`
+	End                 time.Duration `json:"end"`
+}
+
+func (t *Trace) String() string {
+	if t == nil {
+		return ""
+	}
+	bs, err := json.Marshal(t)
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+func (t *Trace) MarshalIndent() string {
+	if t == nil {
+		return ""
+	}
+	bs, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
+
+// Walk calls fn for every node execution, parents before their children, with the
+// nesting depth of the node.
+func (t *Trace) Walk(fn func(n *TraceNode, depth int)) {
+	var walk func(nodes []*TraceNode, depth int)
+	walk = func(nodes []*TraceNode, depth int) {
+		for _, n := range nodes {
+			fn(n, depth)
+			walk(n.Children, depth+1)
+		}
+	}
+	walk(t.Nodes, 0)
+}
+
+// Slowest returns the n longest node executions, containers excepted, longest first.
+func (t *Trace) Slowest(n int) []*TraceNode {
+	var nodes []*TraceNode
+	t.Walk(func(node *TraceNode, depth int) {
+		if !isContainer(node.NodeType) {
+			nodes = append(nodes, node)
+		}
+	})
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Duration() > nodes[j].Duration()
+	})
+	if n < len(nodes) {
+		nodes = nodes[:n]
+	}
+	return nodes
+}
+
+// TraceRecorder builds the Trace of a run from its stream events. It is an
+// EventHandler, or events can be given to Add:
+//
+//	rec := workflow.NewTraceRecorder()
+//	err := c.RunStreamWithHandler(ctx, req, rec)
+//	trace := rec.Trace()
+//	for _, n := range trace.Slowest(3) {
+//		log.Println(n.Title, n.Duration())
+//	}
+//	fmt.Println(trace.Gantt())
+//
+// Timings come from the elapsed times reported by Dify and from the time the events
+// are received, a recorder should see them as they are streamed.
+type TraceRecorder struct {
+	BaseEventHandler
+
+	mu    sync.Mutex
+	now   func() time.Time
+	start time.Time
+	trace Trace
+	// node executions by ID
+	nodes map[string]*TraceNode
+	// running executions by node ID, for the container events and the retries
+	running map[string]*TraceNode
+	// current round of the running containers, by node ID
+	rounds   map[string]int
+	branches map[string]*TraceBranch
+}
+
+// NewTraceRecorder returns a recorder for a single run.
+func NewTraceRecorder() *TraceRecorder {
+	return &TraceRecorder{
+		now:      time.Now,
+		nodes:    make(map[string]*TraceNode),
+		running:  make(map[string]*TraceNode),
+		rounds:   make(map[string]int),
+		branches: make(map[string]*TraceBranch),
+	}
+}
+
+// Trace returns a copy of the trace recorded so far.
+func (r *TraceRecorder) Trace() *Trace {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.trace
+	t.Nodes = cloneTraceNodes(r.trace.Nodes)
+	t.Branches = make([]*TraceBranch, len(r.trace.Branches))
+	for i, b := range r.trace.Branches {
+		copied := *b
+		t.Branches[i] = &copied
+	}
+	return &t
+}
+
+func cloneTraceNodes(nodes []*TraceNode) []*TraceNode {
+	if nodes == nil {
+		return nil
+	}
+	ret := make([]*TraceNode, len(nodes))
+	for i, n := range nodes {
+		copied := *n
+		copied.Children = cloneTraceNodes(n.Children)
+		ret[i] = &copied
+	}
+	return ret
+}
+
+// Add records e, the events that do not describe the execution are ignored.
+func (r *TraceRecorder) Add(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if r.start.IsZero() {
+		r.start = now
+	}
+	at := now.Sub(r.start)
+
+	switch e := e.(type) {
+	case *WorkflowStartedEvent:
+		r.trace.WorkflowRunId = e.Data.Id
+		r.trace.WorkflowId = e.Data.WorkflowId
+		r.trace.TaskId = e.TaskId
+		r.trace.Status = WorkflowStatusRunning
+	case *NodeStartedEvent:
+		r.startNode(&e.Data, at)
+	case *NodeFinishedEvent:
+		r.finishNode(&e.Data, at)
+	case *NodeRetryEvent:
+		n := r.nodes[e.Data.Id]
+		if n == nil {
+			n = r.running[e.Data.NodeId]
+		}
+		if n != nil {
+			n.Retries++
+		}
+	case *IterationStartedEvent:
+		r.startContainer(&e.Data, "iteration", at)
+	case *IterationNextEvent:
+		r.nextRound(&e.Data.ContainerData, e.Data.Index)
+	case *IterationCompletedEvent:
+		r.finishContainer(&e.Data, at)
+	case *LoopStartedEvent:
+		r.startContainer(&e.Data, "loop", at)
+	case *LoopNextEvent:
+		r.nextRound(&e.Data.ContainerData, e.Data.Index)
+	case *LoopCompletedEvent:
+		r.finishContainer(&e.Data, at)
+	case *ParallelBranchStartedEvent:
+		b := &TraceBranch{
+			ParallelId:          e.Data.ParallelId,
+			ParallelStartNodeId: e.Data.ParallelStartNodeId,
+			ParentParallelId:    e.Data.ParentParallelId,
+			Status:              string(WorkflowStatusRunning),
+			Start:               at,
+		}
+		r.branches[b.ParallelId+"/"+b.ParallelStartNodeId] = b
+		r.trace.Branches = append(r.trace.Branches, b)
+	case *ParallelBranchFinishedEvent:
+		if b := r.branches[e.Data.ParallelId+"/"+e.Data.ParallelStartNodeId]; b != nil {
+			b.Status, b.Error, b.End = e.Data.Status, e.Data.Error, at
+		}
+	case *WorkflowFinishedEvent:
+		r.trace.WorkflowRunId = e.Data.Id
+		r.trace.Status = e.Data.Status
+		r.trace.Error = e.Data.Error
+		r.trace.ElapsedTime = e.Data.ElapsedTime
+		r.trace.TotalTokens = e.Data.TotalTokens
+		r.trace.TotalSteps = e.Data.TotalSteps
+		r.trace.End = at
+	case *ErrorEvent:
+		r.trace.Status = WorkflowStatusFailed
+		r.trace.Error = e.Message
+		r.trace.End = at
+	}
+}
+
+func (r *TraceRecorder) OnWorkflowStarted(e *WorkflowStartedEvent)               { r.Add(e) }
+func (r *TraceRecorder) OnNodeStarted(e *NodeStartedEvent)                       { r.Add(e) }
+func (r *TraceRecorder) OnNodeFinished(e *NodeFinishedEvent)                     { r.Add(e) }
+func (r *TraceRecorder) OnNodeRetry(e *NodeRetryEvent)                           { r.Add(e) }
+func (r *TraceRecorder) OnIterationStarted(e *IterationStartedEvent)             { r.Add(e) }
+func (r *TraceRecorder) OnIterationNext(e *IterationNextEvent)                   { r.Add(e) }
+func (r *TraceRecorder) OnIterationCompleted(e *IterationCompletedEvent)         { r.Add(e) }
+func (r *TraceRecorder) OnLoopStarted(e *LoopStartedEvent)                       { r.Add(e) }
+func (r *TraceRecorder) OnLoopNext(e *LoopNextEvent)                             { r.Add(e) }
+func (r *TraceRecorder) OnLoopCompleted(e *LoopCompletedEvent)                   { r.Add(e) }
+func (r *TraceRecorder) OnParallelBranchStarted(e *ParallelBranchStartedEvent)   { r.Add(e) }
+func (r *TraceRecorder) OnParallelBranchFinished(e *ParallelBranchFinishedEvent) { r.Add(e) }
+func (r *TraceRecorder) OnWorkflowFinished(e *WorkflowFinishedEvent)             { r.Add(e) }
+func (r *TraceRecorder) OnError(e *ErrorEvent)                                   { r.Add(e) }
+
+func isContainer(nodeType string) bool {
+	return nodeType == "iteration" || nodeType == "loop"
+}
+
+// startNode records a node execution under its container, if any.
+func (r *TraceRecorder) startNode(d *NodeData, at time.Duration) *TraceNode {
+	n := r.nodes[d.Id]
+	if n == nil {
+		n = &TraceNode{Id: d.Id, Start: at}
+		r.nodes[d.Id] = n
+		containerId := d.IterationId
+		if containerId == "" {
+			containerId = d.LoopId
+		}
+		if parent := r.running[containerId]; containerId != "" && parent != nil {
+			n.Round = r.rounds[containerId]
+			parent.Children = append(parent.Children, n)
+		} else {
+			r.trace.Nodes = append(r.trace.Nodes, n)
+		}
+	}
+	n.NodeId = d.NodeId
+	n.NodeType = d.NodeType
+	n.Title = d.Title
+	n.Index = d.Index
+	n.PredecessorNodeId = d.PredecessorNodeId
+	n.Inputs = d.Inputs
+	n.ParallelId = d.ParallelId
+	n.ParallelStartNodeId = d.ParallelStartNodeId
+	n.Status = string(WorkflowStatusRunning)
+	r.running[d.NodeId] = n
+	return n
+}
+
+func (r *TraceRecorder) finishNode(d *NodeResultData, at time.Duration) {
+	n := r.nodes[d.Id]
+	if n == nil {
+		n = r.startNode(&d.NodeData, at)
+	}
+	n.Status = d.Status
+	n.Error = d.Error
+	n.End = at
+	n.ElapsedTime = d.ElapsedTime
+	n.TotalTokens = d.ExecutionMetadata.TotalTokens
+	n.TotalPrice = d.ExecutionMetadata.TotalPrice
+	n.Currency = d.ExecutionMetadata.Currency
+	n.Outputs = d.Outputs
+	if r.running[d.NodeId] == n {
+		delete(r.running, d.NodeId)
+	}
+}
+
+// startContainer records an iteration or a loop, merged with the node_started event of
+// the same node sent by some Dify versions.
+func (r *TraceRecorder) startContainer(d *ContainerData, nodeType string, at time.Duration) {
+	if d.NodeType != "" {
+		nodeType = d.NodeType
+	}
+	n := r.running[d.NodeId]
+	if n == nil {
+		n = r.startNode(&NodeData{Id: d.Id, NodeId: d.NodeId, NodeType: nodeType, Title: d.Title, Inputs: d.Inputs,
+			ParallelId: d.ParallelId, ParallelStartNodeId: d.ParallelStartNodeId}, at)
+	}
+	r.rounds[d.NodeId] = 1
+	n.Rounds = 1
+}
+
+func (r *TraceRecorder) nextRound(d *ContainerData, index int) {
+	// index is the round starting, from 0
+	r.rounds[d.NodeId] = index + 1
+	if n := r.running[d.NodeId]; n != nil && index+1 > n.Rounds {
+		n.Rounds = index + 1
+	}
+}
+
+func (r *TraceRecorder) finishContainer(d *ContainerResultData, at time.Duration) {
+	n := r.running[d.NodeId]
+	if n == nil {
+		n = r.nodes[d.Id]
+	}
+	if n == nil {
+		return
+	}
+	n.Status = d.Status
+	n.Error = d.Error
+	n.End = at
+	n.ElapsedTime = d.ElapsedTime
+	n.TotalTokens = d.TotalTokens
+	if d.ExecutionMetadata.TotalTokens > 0 {
+		n.TotalTokens = d.ExecutionMetadata.TotalTokens
+	}
+	n.TotalPrice = d.ExecutionMetadata.TotalPrice
+	n.Currency = d.ExecutionMetadata.Currency
+	n.Outputs = d.Outputs
+	if d.Steps > n.Rounds {
+		n.Rounds = d.Steps
+	}
+	if r.running[d.NodeId] == n {
+		delete(r.running, d.NodeId)
+	}
+	delete(r.rounds, d.NodeId)
+}
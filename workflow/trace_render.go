@@ -0,0 +1,161 @@
+package workflow
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Table renders the trace as a text table, one row per node execution, the nested
+// ones indented under their container:
+//
+//	NODE        TYPE       STATUS     START   DURATION  TOKENS  ERROR
+//	Start       start      succeeded  0s      12ms      0
+//	Iteration   iteration  succeeded  15ms    2.1s      120
+//	  LLM #1    llm        succeeded  16ms    1s        60
+func (t *Trace) Table() string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tTYPE\tSTATUS\tSTART\tDURATION\tTOKENS\tERROR")
+	t.Walk(func(n *TraceNode, depth int) {
+		name := strings.Repeat("  ", depth) + oneLine(n.Title)
+		if n.Round > 0 {
+			name += fmt.Sprintf(" #%d", n.Round)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", name, n.NodeType, n.Status,
+			roundDuration(n.Start), roundDuration(n.Duration()), n.TotalTokens, oneLine(n.Error))
+	})
+	w.Flush()
+	return buf.String()
+}
+
+// Gantt renders the trace as a Mermaid Gantt chart, a section per top-level node with
+// its nested executions. Failed nodes are marked crit and running ones active.
+func (t *Trace) Gantt() string {
+	var b strings.Builder
+	b.WriteString("gantt\n")
+	fmt.Fprintf(&b, "    title %s\n", mermaidText("Workflow run "+t.WorkflowRunId))
+	b.WriteString("    dateFormat x\n")
+	b.WriteString("    axisFormat %M:%S\n")
+	id := 0
+	var task func(n *TraceNode)
+	task = func(n *TraceNode) {
+		id++
+		name := mermaidText(n.Title)
+		if n.Round > 0 {
+			name += fmt.Sprintf(" round %d", n.Round)
+		}
+		d := n.Duration()
+		if d < time.Millisecond {
+			d = time.Millisecond
+		}
+		tag := "done, "
+		switch n.Status {
+		case "failed", "exception", "stopped":
+			tag = "crit, "
+		case string(WorkflowStatusRunning):
+			tag = "active, "
+		}
+		fmt.Fprintf(&b, "    %s :%sn%d, %d, %dms\n", name, tag, id, n.Start.Milliseconds(), d.Milliseconds())
+		for _, child := range n.Children {
+			task(child)
+		}
+	}
+	for _, n := range t.Nodes {
+		fmt.Fprintf(&b, "    section %s\n", mermaidText(n.Title))
+		task(n)
+	}
+	return b.String()
+}
+
+// Flowchart renders the trace as a Mermaid flowchart following the predecessor links,
+// iterations and loops as subgraphs. The executions of a node repeated by the rounds of
+// a container are merged into one box, with their count and their total duration.
+func (t *Trace) Flowchart() string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	ids := 0
+	var failed []string
+	var scope func(nodes []*TraceNode, indent string)
+	scope = func(nodes []*TraceNode, indent string) {
+		type box struct {
+			id    string
+			first *TraceNode
+			count int
+			total time.Duration
+			bad   bool
+		}
+		var boxes []*box
+		byNode := make(map[string]*box)
+		for _, n := range nodes {
+			bx := byNode[n.NodeId]
+			if bx == nil {
+				ids++
+				bx = &box{id: fmt.Sprintf("n%d", ids), first: n}
+				byNode[n.NodeId] = bx
+				boxes = append(boxes, bx)
+			}
+			bx.count++
+			bx.total += n.Duration()
+			switch n.Status {
+			case "failed", "exception", "stopped":
+				bx.bad = true
+			}
+		}
+
+		for _, bx := range boxes {
+			n := bx.first
+			label := mermaidText(n.Title)
+			if bx.count > 1 {
+				label += fmt.Sprintf(" ×%d", bx.count)
+			}
+			if isContainer(n.NodeType) {
+				if n.Rounds > 0 {
+					label += fmt.Sprintf(" (%d rounds)", n.Rounds)
+				}
+				fmt.Fprintf(&b, "%ssubgraph %s [\"%s<br/>%s\"]\n", indent, bx.id, label, roundDuration(bx.total))
+				var children []*TraceNode
+				for _, m := range nodes {
+					if m.NodeId == n.NodeId {
+						children = append(children, m.Children...)
+					}
+				}
+				scope(children, indent+"    ")
+				fmt.Fprintf(&b, "%send\n", indent)
+			} else {
+				fmt.Fprintf(&b, "%s%s[\"%s<br/>%s\"]\n", indent, bx.id, label, roundDuration(bx.total))
+			}
+			if bx.bad {
+				failed = append(failed, bx.id)
+			}
+		}
+		for _, bx := range boxes {
+			if pred := byNode[bx.first.PredecessorNodeId]; pred != nil && pred != bx {
+				fmt.Fprintf(&b, "%s%s --> %s\n", indent, pred.id, bx.id)
+			}
+		}
+	}
+	scope(t.Nodes, "    ")
+	if len(failed) > 0 {
+		b.WriteString("    classDef failed fill:#fdd,stroke:#c00\n")
+		fmt.Fprintf(&b, "    class %s failed\n", strings.Join(failed, ","))
+	}
+	return b.String()
+}
+
+// roundDuration rounds d to the millisecond for display.
+func roundDuration(d time.Duration) time.Duration {
+	return d.Round(time.Millisecond)
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// mermaidText removes the characters delimiting the Mermaid syntax from s.
+func mermaidText(s string) string {
+	s = strings.NewReplacer(":", " ", ";", " ", "#", " ", "\"", "'", "[", "(", "]", ")").Replace(s)
+	return oneLine(s)
+}
@@ -0,0 +1,108 @@
+package workflow
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTraceRecorder(t *testing.T) {
+	events := []string{
+		`{"event":"workflow_started","task_id":"t1","workflow_run_id":"r1","data":{"id":"r1","workflow_id":"w1"}}`,
+		`{"event":"node_started","data":{"id":"e1","node_id":"start","node_type":"start","title":"Start"}}`,
+		`{"event":"node_finished","data":{"id":"e1","node_id":"start","node_type":"start","title":"Start","status":"succeeded","elapsed_time":0.01}}`,
+		`{"event":"node_started","data":{"id":"e2","node_id":"iter","node_type":"iteration","title":"Each item","predecessor_node_id":"start"}}`,
+		`{"event":"iteration_started","data":{"id":"e2","node_id":"iter","node_type":"iteration","title":"Each item"}}`,
+		`{"event":"iteration_next","data":{"id":"e2","node_id":"iter","index":0}}`,
+		`{"event":"node_started","data":{"id":"e3","node_id":"llm","node_type":"llm","title":"LLM","iteration_id":"iter"}}`,
+		`{"event":"node_retry","data":{"id":"e3","node_id":"llm"}}`,
+		`{"event":"node_finished","data":{"id":"e3","node_id":"llm","node_type":"llm","title":"LLM","iteration_id":"iter","status":"succeeded","elapsed_time":1.5,"execution_metadata":{"total_tokens":30,"total_price":"0.003","currency":"USD"}}}`,
+		`{"event":"iteration_next","data":{"id":"e2","node_id":"iter","index":1}}`,
+		`{"event":"node_started","data":{"id":"e4","node_id":"llm","node_type":"llm","title":"LLM","iteration_id":"iter"}}`,
+		`{"event":"node_finished","data":{"id":"e4","node_id":"llm","node_type":"llm","title":"LLM","iteration_id":"iter","status":"failed","error":"rate: limited","elapsed_time":0.5}}`,
+		`{"event":"iteration_completed","data":{"id":"e2","node_id":"iter","node_type":"iteration","title":"Each item","status":"failed","elapsed_time":2.1,"total_tokens":30,"steps":2}}`,
+		`{"event":"parallel_branch_started","data":{"parallel_id":"p1","parallel_start_node_id":"end"}}`,
+		`{"event":"node_started","data":{"id":"e5","node_id":"end","node_type":"end","title":"End","predecessor_node_id":"iter","parallel_id":"p1","parallel_start_node_id":"end"}}`,
+		`{"event":"node_finished","data":{"id":"e5","node_id":"end","node_type":"end","title":"End","status":"succeeded","elapsed_time":0.02}}`,
+		`{"event":"parallel_branch_finished","data":{"parallel_id":"p1","parallel_start_node_id":"end","status":"succeeded"}}`,
+		`{"event":"workflow_finished","task_id":"t1","workflow_run_id":"r1","data":{"id":"r1","status":"failed","error":"rate limited","elapsed_time":2.2,"total_tokens":30,"total_steps":5}}`,
+	}
+	rec := NewTraceRecorder()
+	clock := time.Unix(0, 0)
+	rec.now = func() time.Time {
+		clock = clock.Add(100 * time.Millisecond)
+		return clock
+	}
+	for _, data := range events {
+		e, err := DecodeEvent([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		DispatchEvent(rec, e)
+	}
+
+	trace := rec.Trace()
+	if trace.WorkflowRunId != "r1" || trace.TaskId != "t1" || trace.Status != WorkflowStatusFailed || trace.End != 1700*time.Millisecond {
+		t.Errorf("unexpected trace %s", trace)
+	}
+	if len(trace.Nodes) != 3 {
+		t.Fatalf("%d top-level nodes, want 3: %s", len(trace.Nodes), trace.MarshalIndent())
+	}
+	iter := trace.Nodes[1]
+	if iter.Id != "e2" || iter.Status != "failed" || iter.Rounds != 2 || len(iter.Children) != 2 || iter.Start != 300*time.Millisecond {
+		t.Fatalf("unexpected iteration %+v", iter)
+	}
+	first, second := iter.Children[0], iter.Children[1]
+	if first.Round != 1 || first.Retries != 1 || first.TotalTokens != 30 || first.TotalPrice != "0.003" || second.Round != 2 || second.Error != "rate: limited" {
+		t.Errorf("unexpected rounds %+v, %+v", first, second)
+	}
+	if len(trace.Branches) != 1 || trace.Branches[0].Status != "succeeded" || trace.Branches[0].End != 1600*time.Millisecond {
+		t.Errorf("unexpected branches %+v", trace.Branches)
+	}
+	if slowest := trace.Slowest(2); len(slowest) != 2 || slowest[0] != first || slowest[1] != second {
+		t.Errorf("slowest = %v", slowest)
+	}
+
+	// the copy is not updated by later events
+	rec.Add(&NodeStartedEvent{Data: NodeData{Id: "e6", NodeId: "late"}})
+	if len(trace.Nodes) != 3 {
+		t.Error("the returned trace was modified")
+	}
+
+	table := trace.Table()
+	for _, want := range []string{"Each item  iteration  failed", "  LLM #2", "1.5s", "rate: limited"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("table misses %q:\n%s", want, table)
+		}
+	}
+	gantt := trace.Gantt()
+	for _, want := range []string{"dateFormat x", "section Each item", "LLM round 1 :done, n3, 600, 1500ms", "LLM round 2 :crit, n4, "} {
+		if !strings.Contains(gantt, want) {
+			t.Errorf("gantt misses %q:\n%s", want, gantt)
+		}
+	}
+	flow := trace.Flowchart()
+	for _, want := range []string{`subgraph n2 ["Each item (2 rounds)<br/>2.1s"]`, `    n4["LLM ×2<br/>2s"]`, `n3["End<br/>20ms"]`, "n1 --> n2", "n2 --> n3", "class n4,n2 failed"} {
+		if !strings.Contains(flow, want) {
+			t.Errorf("flowchart misses %q:\n%s", want, flow)
+		}
+	}
+}
+
+func TestTraceRecorderRunStream(t *testing.T) {
+	c := NewWorkflowClient(testBaseUrl, testApiKey)
+	rec := NewTraceRecorder()
+	err := c.RunStreamWithHandler(context.Background(), &RunRequest{Inputs: map[string]interface{}{"query": "trace me"}, User: "test-user-trace"}, rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace := rec.Trace()
+	if trace.Status != WorkflowStatusSucceeded || len(trace.Nodes) != 3 {
+		t.Fatalf("unexpected trace %s", trace.MarshalIndent())
+	}
+	llm := trace.Nodes[1]
+	if llm.NodeType != "llm" || llm.PredecessorNodeId != "start" || llm.TotalTokens != 2 || llm.Status != "succeeded" {
+		t.Errorf("unexpected llm node %+v", llm)
+	}
+}
@@ -1,6 +1,7 @@
 package workflow
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -9,6 +10,8 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+
+	"github.com/taadis/dify-sdk-go/client"
 )
 
 type UploadFileRequest struct {
@@ -49,6 +52,8 @@ func (r *UploadFileResponse) String() string {
 }
 
 // UploadFileErrorResponse represents an error response from the upload API.
+//
+// Deprecated: UploadFile returns a *client.APIError instead.
 type UploadFileErrorResponse struct {
 	Status  int    `json:"status"`
 	Code    string `json:"code"`
@@ -67,35 +72,16 @@ func (c *workflowClient) UploadFile(ctx context.Context, req *UploadFileRequest)
 	}
 	defer file.Close()
 
-	pr, pw := io.Pipe()
-	writer := multipart.NewWriter(pw)
-	go func() {
-		defer pw.Close()
-		// file field
-		part, err := writer.CreateFormFile("file", filepath.Base(req.FilePath))
-		if err != nil {
-			pw.CloseWithError(err)
-			return
-		}
-		if _, err := io.Copy(part, file); err != nil {
-			pw.CloseWithError(err)
-			return
-		}
-		// user field
-		if err := writer.WriteField("user", req.User); err != nil {
-			pw.CloseWithError(err)
-			return
-		}
-		writer.Close()
-	}()
-
-	url := "/files/upload"
-	httpReq, err := c.CreateBaseRequest(ctx, http.MethodPost, url, nil)
+	var httpReq *http.Request
+	if c.RetryEnabled() {
+		// retries need a replayable body, so buffer the whole form
+		httpReq, err = c.newBufferedUploadRequest(ctx, file, req)
+	} else {
+		httpReq, err = c.newStreamingUploadRequest(ctx, file, req)
+	}
 	if err != nil {
 		return nil, err
 	}
-	httpReq.Body = io.NopCloser(pr)
-	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
 
 	resp, err := c.SendRequest(httpReq)
 	if err != nil {
@@ -104,9 +90,7 @@ func (c *workflowClient) UploadFile(ctx context.Context, req *UploadFileRequest)
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		var errBody UploadFileErrorResponse
-		_ = json.NewDecoder(resp.Body).Decode(&errBody)
-		return nil, fmt.Errorf("HTTP response error: [%v]%v", errBody.Code, errBody.Message)
+		return nil, client.NewAPIError(resp)
 	}
 
 	var rsp UploadFileResponse
@@ -115,3 +99,62 @@ func (c *workflowClient) UploadFile(ctx context.Context, req *UploadFileRequest)
 	}
 	return &rsp, nil
 }
+
+// newStreamingUploadRequest creates an upload request streaming the multipart form through a pipe.
+func (c *workflowClient) newStreamingUploadRequest(ctx context.Context, file *os.File, req *UploadFileRequest) (*http.Request, error) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeUploadForm(writer, file, req))
+	}()
+
+	httpReq, err := c.CreateBaseRequest(ctx, http.MethodPost, "/files/upload", nil)
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	httpReq.Body = io.NopCloser(pr)
+	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
+	return httpReq, nil
+}
+
+// newBufferedUploadRequest creates an upload request with the multipart form held in memory,
+// so the request can be replayed by retries.
+func (c *workflowClient) newBufferedUploadRequest(ctx context.Context, file *os.File, req *UploadFileRequest) (*http.Request, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	if err := writeUploadForm(writer, file, req); err != nil {
+		return nil, fmt.Errorf("failed to build multipart form: %w", err)
+	}
+	bs := buf.Bytes()
+
+	// a duplicated upload only leaves an unused file behind, so it is safe to retry
+	httpReq, err := c.CreateBaseRequest(client.WithIdempotent(ctx), http.MethodPost, "/files/upload", nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Body = io.NopCloser(bytes.NewReader(bs))
+	httpReq.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(bs)), nil
+	}
+	httpReq.ContentLength = int64(len(bs))
+	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
+	return httpReq, nil
+}
+
+// writeUploadForm writes the file and user fields and closes the form.
+func writeUploadForm(writer *multipart.Writer, file io.Reader, req *UploadFileRequest) error {
+	// file field
+	part, err := writer.CreateFormFile("file", filepath.Base(req.FilePath))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+	// user field
+	if err := writer.WriteField("user", req.User); err != nil {
+		return err
+	}
+	return writer.Close()
+}
@@ -1,28 +1,75 @@
 package workflow
 
 import (
+	"bytes"
 	"context"
+	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/taadis/dify-sdk-go/client"
-	"github.com/taadis/dify-sdk-go/env"
+	"github.com/taadis/dify-sdk-go/difytest"
 )
 
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
 func TestUploadFile(t *testing.T) {
 	ctx := context.Background()
 
-	filePath := "your-file-path"
-	user := "your-user"
-	if filePath == "your-file-path" || user == "your-user" {
-		t.Skip("Set a valid file path and user to run this test.")
-	}
+	filePath := writeTestFile(t, "upload.txt", "hello dify")
+	user := "test-user"
 
 	req := &UploadFileRequest{FilePath: filePath, User: user}
-	client := NewWorkflowClient(client.DifyCloud, env.GetDifyApiKey())
+	client := NewWorkflowClient(testBaseUrl, testApiKey)
 	rsp, err := client.UploadFile(ctx, req)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	t.Log(rsp.String())
+	if rsp.Name != "upload.txt" || rsp.Extension != "txt" || rsp.Size != 10 || rsp.CreatedBy != user {
+		t.Errorf("unexpected response: %s", rsp.String())
+	}
+	if content, ok := testServer.File(rsp.Id); !ok || string(content) != "hello dify" {
+		t.Errorf("uploaded content = %q, %v", content, ok)
+	}
+}
+
+func TestUploadFileRetryReplaysBody(t *testing.T) {
+	ctx := context.Background()
+
+	filePath := writeTestFile(t, "upload.txt", "hello dify")
+
+	srv := difytest.NewServer()
+	defer srv.Close()
+	srv.Inject(http.MethodPost, "/files/upload", difytest.Fault{Status: http.StatusServiceUnavailable, Times: 1})
+
+	retry := client.DefaultRetryPolicy()
+	retry.InitialBackoff = time.Millisecond
+	c := NewWorkflowClientWithConfig(&client.ClientConfig{BaseUrl: srv.BaseUrl(), ApiKey: "app-test", Retry: retry})
+	rsp, err := c.UploadFile(ctx, &UploadFileRequest{FilePath: filePath, User: "test-user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, ok := srv.File(rsp.Id); !ok || string(content) != "hello dify" {
+		t.Errorf("uploaded content = %q, %v", content, ok)
+	}
+
+	reqs := srv.RequestsTo(http.MethodPost, "/files/upload")
+	if len(reqs) != 2 {
+		t.Fatalf("requests = %d, want 2", len(reqs))
+	}
+	for i, req := range reqs {
+		if !bytes.Contains(req.Body, []byte("hello dify")) {
+			t.Errorf("attempt %d: body does not contain the file: %q", i+1, req.Body)
+		}
+	}
 }
@@ -0,0 +1,149 @@
+package workflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Variables holds the inputs or outputs of a workflow run.
+//
+// Depending on its version, Dify encodes them as a JSON object or as a string holding
+// the JSON object, both are accepted.
+type Variables map[string]interface{}
+
+// UnmarshalJSON accepts an object, a string holding an object, an empty string or null.
+func (v *Variables) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		b = bytes.TrimSpace([]byte(s))
+		if len(b) == 0 {
+			*v = nil
+			return nil
+		}
+	}
+	if bytes.Equal(b, []byte("null")) {
+		*v = nil
+		return nil
+	}
+	if len(b) == 0 || b[0] != '{' {
+		return fmt.Errorf("workflow: variables must be a JSON object, got %.20s", b)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	*v = m
+	return nil
+}
+
+// Decode decodes the variables into dst, a pointer to a struct or a map, following the
+// json tags of dst. A variable whose value does not fit its field is reported as a
+// *VariableTypeError.
+//
+//	var out struct {
+//		Summary string   `json:"summary"`
+//		Score   float64  `json:"score"`
+//		Tags    []string `json:"tags"`
+//	}
+//	if err := resp.Data.Outputs.Decode(&out); err != nil {
+//		return err
+//	}
+func (v Variables) Decode(dst interface{}) error {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(bs, dst)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &VariableTypeError{Variable: typeErr.Field, Value: typeErr.Value, Type: typeErr.Type}
+	}
+	return err
+}
+
+// VariableTypeError reports a variable that cannot be decoded into its Go field.
+type VariableTypeError struct {
+	// Variable is the name of the variable, dotted for nested fields, e.g. "result.score".
+	Variable string
+	// Value describes the JSON value, e.g. "string" or "number 1.5".
+	Value string
+	// Type is the type of the field.
+	Type reflect.Type
+}
+
+func (e *VariableTypeError) Error() string {
+	return fmt.Sprintf("workflow: cannot decode variable %q: got %s, want %s", e.Variable, e.Value, e.Type)
+}
+
+// difyFileIdentity marks the file objects embedded in variables.
+const difyFileIdentity = "__dify__file__"
+
+// File is a Dify file object found in the variables of a run.
+type File struct {
+	// Variable is the name of the variable holding the file.
+	Variable string `json:"-"`
+	Id       string `json:"id"`
+	// image, document, audio, video or custom
+	Type string `json:"type"`
+	// remote_url, local_file or tool_file
+	TransferMethod string `json:"transfer_method"`
+	RemoteUrl      string `json:"remote_url"`
+	RelatedId      string `json:"related_id"`
+	Filename       string `json:"filename"`
+	Extension      string `json:"extension"`
+	MimeType       string `json:"mime_type"`
+	Size           int64  `json:"size"`
+	// Url to download the file, it may be signed and expire.
+	Url string `json:"url"`
+}
+
+// Files returns the file objects held by the variables, including the ones nested in
+// arrays and objects, ordered by variable name.
+func (v Variables) Files() []File {
+	names := make([]string, 0, len(v))
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var ret []File
+	for _, name := range names {
+		ret = appendFiles(ret, name, v[name])
+	}
+	return ret
+}
+
+func appendFiles(files []File, variable string, value interface{}) []File {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if value["dify_model_identity"] == difyFileIdentity {
+			var f File
+			if bs, err := json.Marshal(value); err == nil && json.Unmarshal(bs, &f) == nil {
+				f.Variable = variable
+				files = append(files, f)
+			}
+			return files
+		}
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			files = appendFiles(files, variable, value[k])
+		}
+	case []interface{}:
+		for _, item := range value {
+			files = appendFiles(files, variable, item)
+		}
+	}
+	return files
+}
@@ -0,0 +1,94 @@
+package workflow
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestVariablesUnmarshalJSON(t *testing.T) {
+	for _, tt := range []struct {
+		data string
+		want int
+		err  bool
+	}{
+		{data: `{"a":1,"b":"x"}`, want: 2},
+		{data: `"{\"a\":1}"`, want: 1},
+		{data: `""`},
+		{data: `null`},
+		{data: `"null"`},
+		{data: `[1]`, err: true},
+		{data: `"not json"`, err: true},
+	} {
+		var v Variables
+		err := json.Unmarshal([]byte(tt.data), &v)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: err = %v", tt.data, err)
+			continue
+		}
+		if len(v) != tt.want {
+			t.Errorf("%s: %d variables, want %d", tt.data, len(v), tt.want)
+		}
+	}
+}
+
+func TestVariablesDecode(t *testing.T) {
+	var outputs Variables
+	if err := json.Unmarshal([]byte(`{"summary":"ok","score":0.9,"tags":["a","b"],"detail":{"count":2}}`), &outputs); err != nil {
+		t.Fatal(err)
+	}
+
+	var out struct {
+		Summary string   `json:"summary"`
+		Score   float64  `json:"score"`
+		Tags    []string `json:"tags"`
+		Detail  struct {
+			Count int `json:"count"`
+		} `json:"detail"`
+	}
+	if err := outputs.Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Summary != "ok" || out.Score != 0.9 || len(out.Tags) != 2 || out.Detail.Count != 2 {
+		t.Errorf("unexpected outputs %+v", out)
+	}
+
+	var mismatch struct {
+		Score string `json:"score"`
+	}
+	err := outputs.Decode(&mismatch)
+	typeErr, ok := err.(*VariableTypeError)
+	if !ok {
+		t.Fatalf("expected a *VariableTypeError, got %v", err)
+	}
+	if typeErr.Variable != "score" || typeErr.Value != "number" || typeErr.Type.Kind().String() != "string" {
+		t.Errorf("unexpected error %+v", typeErr)
+	}
+	t.Log(err)
+}
+
+func TestVariablesFiles(t *testing.T) {
+	var outputs Variables
+	err := json.Unmarshal([]byte(`{
+		"text": "done",
+		"image": {"dify_model_identity": "__dify__file__", "id": "f1", "type": "image", "transfer_method": "tool_file",
+			"filename": "a.png", "extension": ".png", "mime_type": "image/png", "size": 10, "url": "https://files/a.png"},
+		"docs": [
+			{"dify_model_identity": "__dify__file__", "id": "f2", "type": "document", "filename": "b.pdf"},
+			{"name": "not a file"}
+		]
+	}`), &outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := outputs.Files()
+	if len(files) != 2 {
+		t.Fatalf("files = %+v", files)
+	}
+	if files[0].Variable != "docs" || files[0].Id != "f2" || files[0].Type != "document" {
+		t.Errorf("unexpected file %+v", files[0])
+	}
+	if files[1].Variable != "image" || files[1].Url != "https://files/a.png" || files[1].Size != 10 || files[1].MimeType != "image/png" {
+		t.Errorf("unexpected file %+v", files[1])
+	}
+}
@@ -9,6 +9,14 @@ import (
 type WorkflowClient interface {
 	// Execute Workflow
 	Run(ctx context.Context, req *RunRequest) (*RunResponse, error)
+	// Execute Workflow in streaming mode, iterating over the events
+	RunStream(ctx context.Context, req *RunRequest) (*Stream, error)
+	// Execute Workflow in streaming mode, dispatching the events to a handler
+	RunStreamWithHandler(ctx context.Context, req *RunRequest, h EventHandler) error
+	// Execute a specific published version of the Workflow
+	RunById(ctx context.Context, req *RunByIdRequest) (*RunResponse, error)
+	// Execute a specific published version of the Workflow in streaming mode
+	RunByIdStream(ctx context.Context, req *RunByIdRequest) (*Stream, error)
 	// Get Workflow Run Detail
 	GetRun(ctx context.Context, req *GetRunRequest) (*GetRunResponse, error)
 	// Get Workflow Logs
@@ -19,7 +27,7 @@ type WorkflowClient interface {
 	GetParameters(ctx context.Context, req *GetParametersRequest) (*GetParametersResponse, error)
 	// Stop Workflow Task Generation
 	StopTask(ctx context.Context, req *StopTaskRequest) (*StopTaskResponse, error)
-  	// File Upload for Workflow
+	// File Upload for Workflow
 	UploadFile(ctx context.Context, req *UploadFileRequest) (*UploadFileResponse, error)
 	// Get Application WebApp Settings
 	GetSite(ctx context.Context, req *GetSiteRequest) (*GetSiteResponse, error)
@@ -34,3 +42,9 @@ func NewWorkflowClient(baseUrl string, apiKey string) WorkflowClient {
 	c.Client = client.NewClient(baseUrl, apiKey)
 	return c
 }
+
+func NewWorkflowClientWithConfig(config *client.ClientConfig) WorkflowClient {
+	c := new(workflowClient)
+	c.Client = client.NewClientWithConfig(config)
+	return c
+}
@@ -15,6 +15,8 @@ const (
 	WorkflowStatusFailed    WorkflowStatus = "failed"
 	WorkflowStatusStopped   WorkflowStatus = "stopped"
 	WorkflowStatusRunning   WorkflowStatus = "running"
+	// Some nodes failed but their errors were handled.
+	WorkflowStatusPartialSucceeded WorkflowStatus = "partial-succeeded"
 )
 
 type GetWorkflowLogsRequest struct {
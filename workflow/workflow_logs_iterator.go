@@ -0,0 +1,74 @@
+package workflow
+
+import (
+	"context"
+)
+
+// LogIterator walks the workflow logs page by page:
+//
+//	it := workflow.NewLogIterator(ctx, c, &workflow.GetWorkflowLogsRequest{Status: workflow.WorkflowStatusFailed, Limit: 100})
+//	for it.Next() {
+//		log.Println(it.Current().WorkflowRun.Id)
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+//
+// Pages are fetched lazily, the next one when the current one is consumed. Logs are
+// paged by offset, so runs created during the iteration shift the pages and may be
+// returned twice; filter with CreatedAtBefore for a stable listing.
+type LogIterator struct {
+	ctx     context.Context
+	c       WorkflowClient
+	req     GetWorkflowLogsRequest
+	page    []*WorkflowsLogItem
+	current *WorkflowsLogItem
+	last    bool
+	err     error
+}
+
+// NewLogIterator returns an iterator over the logs matching req, starting at req.Page
+// (1 by default) with req.Limit logs per page (20 by default).
+func NewLogIterator(ctx context.Context, c WorkflowClient, req *GetWorkflowLogsRequest) *LogIterator {
+	it := &LogIterator{ctx: ctx, c: c}
+	if req != nil {
+		it.req = *req
+	}
+	if it.req.Page < 1 {
+		it.req.Page = 1
+	}
+	return it
+}
+
+// Next advances to the next log, it returns false after the last one or on error.
+func (it *LogIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.last || it.err != nil {
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+		rsp, err := it.c.GetWorkflowLogs(it.ctx, &it.req)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page = rsp.Data
+		it.last = !rsp.HasMore || len(rsp.Data) == 0
+		it.req.Page++
+	}
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// Current returns the current log.
+func (it *LogIterator) Current() *WorkflowsLogItem {
+	return it.current
+}
+
+// Err returns the error that ended the iteration, nil when all the logs were read.
+func (it *LogIterator) Err() error {
+	return it.err
+}
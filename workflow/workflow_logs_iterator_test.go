@@ -0,0 +1,49 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/taadis/dify-sdk-go/difytest"
+)
+
+func TestLogIterator(t *testing.T) {
+	ctx := context.Background()
+	srv := difytest.NewServer(difytest.WithAPIKey(testApiKey))
+	defer srv.Close()
+	c := NewWorkflowClient(srv.BaseUrl(), testApiKey)
+
+	runs := map[string]bool{}
+	for i := 0; i < 5; i++ {
+		rsp, err := c.Run(ctx, &RunRequest{Inputs: map[string]interface{}{"query": "hello"}, User: "test-user"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		runs[rsp.WorkflowRunId] = true
+	}
+
+	it := NewLogIterator(ctx, c, &GetWorkflowLogsRequest{Limit: 2})
+	n := 0
+	for it.Next() {
+		id := it.Current().WorkflowRun.Id
+		if !runs[id] {
+			t.Errorf("unexpected or duplicated run %s", id)
+		}
+		delete(runs, id)
+		n++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 || len(runs) != 0 {
+		t.Errorf("%d logs, missing %v", n, runs)
+	}
+	srv.AssertRequestCount(t, "GET", "/workflows/logs", 3)
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	it = NewLogIterator(ctx, c, nil)
+	if it.Next() || it.Err() != context.Canceled {
+		t.Errorf("err = %v, want context.Canceled", it.Err())
+	}
+}
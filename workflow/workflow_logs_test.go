@@ -4,18 +4,28 @@ import (
 	"context"
 	"testing"
 	"time"
-
-	"github.com/taadis/dify-sdk-go/client"
-	"github.com/taadis/dify-sdk-go/env"
 )
 
 func TestGetWorkflowLogs(t *testing.T) {
 	ctx := context.Background()
+	client := NewWorkflowClient(testBaseUrl, testApiKey)
+	for _, query := range []string{"a test", "another test", "no match"} {
+		_, err := client.Run(ctx, &RunRequest{
+			Inputs:       map[string]interface{}{"query": query},
+			ResponseMode: "blocking",
+			User:         "test-user-logs",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
 	req := new(GetWorkflowLogsRequest)
 	req.Keyword = "test"
 	req.Page = 1
 	req.Limit = 1
-	req.Status = WorkflowStatusFailed
+	req.Status = WorkflowStatusSucceeded
+	req.CreatedByEndUserSessionId = "test-user-logs"
 
 	now := time.Now()
 	todayMidnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
@@ -24,13 +34,16 @@ func TestGetWorkflowLogs(t *testing.T) {
 	req.CreatedAtAfter = &todayMidnight
 	req.CreatedAtBefore = &tomorrowMidnight
 
-	client := NewWorkflowClient(client.DifyCloud, env.GetDifyApiKey())
 	rsp, err := client.GetWorkflowLogs(ctx, req)
 	if err != nil {
 		t.Fatalf("GetWorkflowsLogs error: %v", err)
 		// maybe error:
 		// [invalid_param]Unused components in ISO string
 	}
-	t.Log(rsp.String())
-	t.Logf("rsp.length: %d", len(rsp.Data))
+	if rsp.Total != 2 || !rsp.HasMore || len(rsp.Data) != 1 {
+		t.Errorf("unexpected logs: %s", rsp.String())
+	}
+	if len(rsp.Data) == 1 && rsp.Data[0].WorkflowRun.Status != WorkflowStatusSucceeded {
+		t.Errorf("status = %s, want %s", rsp.Data[0].WorkflowRun.Status, WorkflowStatusSucceeded)
+	}
 }